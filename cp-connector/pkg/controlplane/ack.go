@@ -0,0 +1,203 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// RedeliveryDelayFn computes the delay after which an event is redelivered, based on the number of times
+// the event was delivered so far
+type RedeliveryDelayFn func(deliveryCount uint64) time.Duration
+
+// LinearRedeliveryDelay returns a RedeliveryDelayFn whose delay grows by step with every delivery, up to max.
+// If the delivery count is unknown, the event is treated as being delivered for the first time
+func LinearRedeliveryDelay(step time.Duration, max time.Duration) RedeliveryDelayFn {
+	return func(deliveryCount uint64) time.Duration {
+		if step <= 0 {
+			return 0
+		}
+		if deliveryCount == 0 {
+			deliveryCount = 1
+		}
+		if deliveryCount > uint64(max/step) {
+			return max
+		}
+		return time.Duration(deliveryCount) * step
+	}
+}
+
+// acknowledge returns the handling result to the sender of the event, if it waits for it, and settles the event
+func (cp *ControlPlane) acknowledge(ctx context.Context, eventUpdate types.EventUpdate, handlingErr error) {
+	cp.respond(ctx, eventUpdate, handlingErr)
+	cp.settle(ctx, eventUpdate, handlingErr)
+}
+
+// settle tells the broker whether the event was handled. Events failing to be handled are redelivered
+// after the delay computed for their delivery count. Events not being forwarded to the integration are acknowledged
+func (cp *ControlPlane) settle(ctx context.Context, eventUpdate types.EventUpdate, handlingErr error) {
+	log := cp.eventLogger(context.WithValue(ctx, types.CorrelationIDKey, correlationID(eventUpdate.KeptnEvent)))
+	if eventUpdate.Acknowledger == nil {
+		if errors.Is(handlingErr, errCancelledByReload) {
+			log.Warnf("Event %s was cancelled by a configuration reload but cannot be redelivered", eventUpdate.KeptnEvent.ID)
+		}
+		if errors.Is(handlingErr, errShedDuringStorm) {
+			log.Warnf("Event %s was rejected during an event storm but cannot be redelivered", eventUpdate.KeptnEvent.ID)
+		}
+		return
+	}
+	if handlingErr == nil {
+		cp.metrics.observeAcknowledgement(eventUpdate.MetaData.Subject, ackOutcomeAck)
+		if err := eventUpdate.Acknowledger.Ack(); err != nil {
+			log.Warnf("Could not acknowledge event %s: %v", eventUpdate.KeptnEvent.ID, err)
+		}
+		return
+	}
+	var delay time.Duration
+	switch {
+	// events cancelled by a reload did not fail, they are redelivered to be handled under the new configuration.
+	// Abandoned events did not fail either, they are redelivered to be handled by another replica or registration
+	case errors.Is(handlingErr, errCancelledByReload), errors.Is(handlingErr, errEventAbandoned):
+	case errors.Is(handlingErr, errShedDuringStorm):
+		delay = cp.stormRedeliveryDelay
+	case cp.isDeadLetter(eventUpdate):
+		log.Warnf("Giving up on event %s after %d deliveries: %v", eventUpdate.KeptnEvent.ID, eventUpdate.MetaData.DeliveryCount, handlingErr)
+		cp.metrics.observeAcknowledgement(eventUpdate.MetaData.Subject, ackOutcomeDeadLetter)
+		cp.deadLetter(eventUpdate, handlingErr)
+		if err := eventUpdate.Acknowledger.Ack(); err != nil {
+			log.Warnf("Could not acknowledge event %s: %v", eventUpdate.KeptnEvent.ID, err)
+		}
+		return
+	default:
+		delay = cp.redeliveryDelayFn(eventUpdate.MetaData.DeliveryCount)
+	}
+	log.Debugf("Requesting redelivery of event %s in %s", eventUpdate.KeptnEvent.ID, delay)
+	if delay > 0 {
+		cp.metrics.observeAcknowledgement(eventUpdate.MetaData.Subject, ackOutcomeNackDelayed)
+	} else {
+		cp.metrics.observeAcknowledgement(eventUpdate.MetaData.Subject, ackOutcomeNack)
+	}
+	if err := eventUpdate.Acknowledger.Nack(delay); err != nil {
+		log.Warnf("Could not request redelivery of event %s: %v", eventUpdate.KeptnEvent.ID, err)
+	}
+}
+
+// WithMaxDeliveries limits the number of times an event whose handling failed is delivered. Once an event failed
+// to be handled on its last delivery, it is acknowledged instead of being redelivered, i.e. it is dead-lettered
+// (see WithDeadLetterHandler).
+// It is only used for event sources supporting acknowledgements and reporting the delivery count of the events.
+// A limit <= 0 disables the limit, which is the default
+func WithMaxDeliveries(max int) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.maxDeliveries = max
+	}
+}
+
+// DeadLetterFn receives an event that is dead-lettered after failing to be handled by the integration for the
+// given subscription. The subscription is empty if the event was not handled for a single subscription, e.g. as part
+// of a batch
+type DeadLetterFn func(eventUpdate types.EventUpdate, subscription models.EventSubscription, handlingErr error)
+
+// WithDeadLetterHandler sets a function receiving the events that are dead-lettered once they reached the maximum
+// number of deliveries configured via WithMaxDeliveries, e.g. to publish them to a dead-letter subject chosen by
+// subscription. If the event failed for multiple subscriptions, the function is called once per subscription.
+// The function is called before the event is acknowledged
+func WithDeadLetterHandler(fn DeadLetterFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.deadLetterFn = fn
+	}
+}
+
+// subscriptionFailure is the handling result of an event the integration failed to handle for the given subscriptions
+type subscriptionFailure struct {
+	subscriptions []models.EventSubscription
+	err           error
+}
+
+func (f *subscriptionFailure) Error() string {
+	return f.err.Error()
+}
+
+func (f *subscriptionFailure) Unwrap() error {
+	return f.err
+}
+
+// deadLetter passes the dead-lettered event to the DeadLetterFn once for every subscription it failed for
+func (cp *ControlPlane) deadLetter(eventUpdate types.EventUpdate, handlingErr error) {
+	if cp.deadLetterFn == nil {
+		return
+	}
+	failure := &subscriptionFailure{}
+	if !errors.As(handlingErr, &failure) || len(failure.subscriptions) == 0 {
+		cp.deadLetterFn(eventUpdate, models.EventSubscription{}, handlingErr)
+		return
+	}
+	for _, subscription := range failure.subscriptions {
+		cp.deadLetterFn(eventUpdate, subscription, failure.err)
+	}
+}
+
+// isDeadLetter checks whether the failed event reached the configured maximum number of deliveries
+func (cp *ControlPlane) isDeadLetter(eventUpdate types.EventUpdate) bool {
+	return cp.maxDeliveries > 0 && eventUpdate.MetaData.DeliveryCount >= uint64(cp.maxDeliveries)
+}
+
+// errNackedByAckPolicy is the handling result of successfully handled events the AckPolicyFn decided to nack
+var errNackedByAckPolicy = errors.New("redelivery requested by ack policy")
+
+// AckDecision tells the ControlPlane how to acknowledge a handled event
+type AckDecision int
+
+const (
+	// AckDecisionDefault acknowledges successfully handled events and requests the redelivery of failed events
+	AckDecisionDefault AckDecision = iota
+	// AckDecisionAck acknowledges the event, regardless of the handling result
+	AckDecisionAck
+	// AckDecisionNack requests the redelivery of the event, regardless of the handling result
+	AckDecisionNack
+	// AckDecisionNone neither acknowledges the event nor requests its redelivery, e.g. because the broker
+	// already redelivered the event after its acknowledgement timeout expired. Resources the event source holds for
+	// the event until it is acknowledged, e.g. a prefetch slot, are released (see types.Releaser)
+	AckDecisionNone
+)
+
+// AckPolicyFn decides how to acknowledge an event, given the result of its handling and the time the handling took
+type AckPolicyFn func(eventUpdate types.EventUpdate, handlingErr error, duration time.Duration) AckDecision
+
+// WithAckPolicy sets a function deciding how events forwarded one by one are acknowledged once they were handled,
+// e.g. to not acknowledge events whose handling exceeded the acknowledgement timeout of the broker.
+// Events that are delivered as a batch or are not handled at all are acknowledged as usual
+func WithAckPolicy(policy AckPolicyFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.ackPolicy = policy
+	}
+}
+
+// acknowledgeHandled acknowledges a handled event according to the configured AckPolicyFn
+func (cp *ControlPlane) acknowledgeHandled(ctx context.Context, eventUpdate types.EventUpdate, handlingErr error, duration time.Duration) {
+	if cp.ackPolicy == nil {
+		cp.acknowledge(ctx, eventUpdate, handlingErr)
+		return
+	}
+	// the sender of the event receives the actual handling result, regardless of the decision of the ack policy
+	cp.respond(ctx, eventUpdate, handlingErr)
+	switch cp.ackPolicy(eventUpdate, handlingErr, duration) {
+	case AckDecisionAck:
+		cp.settle(ctx, eventUpdate, nil)
+	case AckDecisionNack:
+		if handlingErr == nil {
+			handlingErr = errNackedByAckPolicy
+		}
+		cp.settle(ctx, eventUpdate, handlingErr)
+	case AckDecisionNone:
+		cp.eventLogger(ctx).Debugf("Leaving event %s unacknowledged as decided by the ack policy", eventUpdate.KeptnEvent.ID)
+		if releaser, ok := eventUpdate.Acknowledger.(types.Releaser); ok {
+			releaser.Release()
+		}
+	default:
+		cp.settle(ctx, eventUpdate, handlingErr)
+	}
+}
@@ -0,0 +1,383 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAcknowledger struct {
+	mtx    sync.Mutex
+	acked  int
+	nacked []time.Duration
+}
+
+func (f *fakeAcknowledger) Ack() error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.acked++
+	return nil
+}
+
+func (f *fakeAcknowledger) Nack(delay time.Duration) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.nacked = append(f.nacked, delay)
+	return nil
+}
+
+func (f *fakeAcknowledger) outcomes() (int, []time.Duration) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.acked, append([]time.Duration{}, f.nacked...)
+}
+
+func TestControlPlaneNackWithIncreasingDelay(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithRedeliveryDelay(LinearRedeliveryDelay(time.Second, 3*time.Second)))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			if ce.ID == "failing" {
+				return errors.New("handling failed")
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	acknowledger := &fakeAcknowledger{}
+	for delivery := uint64(1); delivery <= 4; delivery++ {
+		update := eventUpdate("failing", "sh.keptn.event.echo.triggered")
+		update.MetaData.DeliveryCount = delivery
+		update.Acknowledger = acknowledger
+		eventChan <- update
+	}
+	succeeding := eventUpdate("succeeding", "sh.keptn.event.echo.triggered")
+	succeeding.Acknowledger = acknowledger
+	eventChan <- succeeding
+
+	require.Eventually(t, func() bool {
+		acked, _ := acknowledger.outcomes()
+		return acked == 1
+	}, time.Second, 10*time.Millisecond)
+	_, nacked := acknowledger.outcomes()
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}, nacked)
+}
+
+func TestControlPlaneAcknowledgementMetrics(t *testing.T) {
+	const subject = "sh.keptn.event.echo.triggered"
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithMetrics(prometheus.NewRegistry()), WithMaxDeliveries(3),
+		WithRedeliveryDelay(func(deliveryCount uint64) time.Duration {
+			if deliveryCount == 1 {
+				return 0
+			}
+			return time.Second
+		}))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			if ce.ID == "failing" {
+				return errors.New("handling failed")
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: subject}}
+
+	acknowledger := &fakeAcknowledger{}
+	for delivery := uint64(1); delivery <= 3; delivery++ {
+		update := eventUpdate("failing", subject)
+		update.MetaData.DeliveryCount = delivery
+		update.Acknowledger = acknowledger
+		eventChan <- update
+	}
+	succeeding := eventUpdate("succeeding", subject)
+	succeeding.Acknowledger = acknowledger
+	eventChan <- succeeding
+
+	// the dead-lettered event is acknowledged, so it is not redelivered again
+	require.Eventually(t, func() bool {
+		acked, _ := acknowledger.outcomes()
+		return acked == 2
+	}, time.Second, 10*time.Millisecond)
+	_, nacked := acknowledger.outcomes()
+	require.Equal(t, []time.Duration{0, time.Second}, nacked)
+
+	counter := func(outcome string) float64 {
+		return testutil.ToFloat64(controlPlane.metrics.acknowledgements.WithLabelValues(subject, outcome))
+	}
+	require.Equal(t, float64(1), counter(ackOutcomeAck))
+	require.Equal(t, float64(1), counter(ackOutcomeNack))
+	require.Equal(t, float64(1), counter(ackOutcomeNackDelayed))
+	require.Equal(t, float64(1), counter(ackOutcomeDeadLetter))
+}
+
+func TestControlPlaneDeadLetterRoutingBySubscription(t *testing.T) {
+	sources := newFakeSources()
+	var mtx sync.Mutex
+	deadLetters := map[string][]string{}
+	destinations := map[string]string{"sub-deployment": "dead-letters.deployment", "sub-test": "dead-letters.test"}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithMaxDeliveries(2),
+		WithDeadLetterHandler(func(eventUpdate types.EventUpdate, subscription models.EventSubscription, handlingErr error) {
+			require.EqualError(t, handlingErr, "handling failed")
+			mtx.Lock()
+			defer mtx.Unlock()
+			destination := destinations[subscription.ID]
+			deadLetters[destination] = append(deadLetters[destination], eventUpdate.KeptnEvent.ID)
+		}))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			return errors.New("handling failed")
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{
+		{ID: "sub-deployment", Event: "sh.keptn.event.deployment.triggered"},
+		{ID: "sub-test", Event: "sh.keptn.event.test.triggered"},
+	}
+
+	acknowledger := &fakeAcknowledger{}
+	for delivery := uint64(1); delivery <= 2; delivery++ {
+		for _, subject := range []string{"sh.keptn.event.deployment.triggered", "sh.keptn.event.test.triggered"} {
+			update := eventUpdate(fmt.Sprintf("%s-%d", subject, delivery), subject)
+			update.MetaData.DeliveryCount = delivery
+			update.Acknowledger = acknowledger
+			eventChan <- update
+		}
+	}
+
+	// only the events failing on their last delivery are dead-lettered
+	require.Eventually(t, func() bool {
+		acked, _ := acknowledger.outcomes()
+		return acked == 2
+	}, time.Second, 10*time.Millisecond)
+	_, nacked := acknowledger.outcomes()
+	require.Len(t, nacked, 2)
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Equal(t, map[string][]string{
+		"dead-letters.deployment": {"sh.keptn.event.deployment.triggered-2"},
+		"dead-letters.test":       {"sh.keptn.event.test.triggered-2"},
+	}, deadLetters)
+}
+
+func TestLinearRedeliveryDelay(t *testing.T) {
+	delay := LinearRedeliveryDelay(time.Second, 10*time.Second)
+	require.Equal(t, time.Second, delay(0))
+	require.Equal(t, time.Second, delay(1))
+	require.Equal(t, 5*time.Second, delay(5))
+	require.Equal(t, 10*time.Second, delay(10))
+	require.Equal(t, 10*time.Second, delay(1000))
+}
+
+func TestControlPlaneAcknowledgesEventsOfBatchIndividually(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithRedeliveryDelay(LinearRedeliveryDelay(time.Second, time.Minute)))
+	var mtx sync.Mutex
+	var handled []string
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			handled = append(handled, ce.ID)
+			if ce.ID == "event-2" {
+				return errors.New("handling failed")
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	acknowledgers := []*fakeAcknowledger{{}, {}, {}}
+	var batch []types.EventUpdate
+	for i, acknowledger := range acknowledgers {
+		update := eventUpdate(fmt.Sprintf("event-%d", i+1), "sh.keptn.event.echo.triggered")
+		update.Acknowledger = acknowledger
+		batch = append(batch, update)
+	}
+	eventChan <- types.EventUpdate{Batch: batch}
+
+	require.Eventually(t, func() bool {
+		_, nacked := acknowledgers[1].outcomes()
+		acked, _ := acknowledgers[2].outcomes()
+		return len(nacked) == 1 && acked == 1
+	}, time.Second, 10*time.Millisecond)
+	acked, nacked := acknowledgers[0].outcomes()
+	require.Equal(t, 1, acked)
+	require.Empty(t, nacked)
+	acked, _ = acknowledgers[1].outcomes()
+	require.Equal(t, 0, acked)
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Equal(t, []string{"event-1", "event-2", "event-3"}, handled)
+}
+
+func TestControlPlaneAckPolicy(t *testing.T) {
+	sources := newFakeSources()
+	mockClock := clock.NewMock()
+	var durations []time.Duration
+	controlPlane := New(sources.ssm, sources.esm, nil, WithClock(mockClock), WithAckPolicy(func(update types.EventUpdate, handlingErr error, duration time.Duration) AckDecision {
+		durations = append(durations, duration)
+		if handlingErr == nil && duration > time.Second {
+			// the broker already redelivered the event
+			return AckDecisionNone
+		}
+		return AckDecisionDefault
+	}))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			if ce.ID == "slow" {
+				mockClock.Add(2 * time.Second)
+			} else {
+				mockClock.Add(100 * time.Millisecond)
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	slow, fast := &fakeAcknowledger{}, &fakeAcknowledger{}
+	slowUpdate := eventUpdate("slow", "sh.keptn.event.echo.triggered")
+	slowUpdate.Acknowledger = slow
+	eventChan <- slowUpdate
+	fastUpdate := eventUpdate("fast", "sh.keptn.event.echo.triggered")
+	fastUpdate.Acknowledger = fast
+	eventChan <- fastUpdate
+
+	require.Eventually(t, func() bool {
+		acked, _ := fast.outcomes()
+		return acked == 1
+	}, time.Second, 10*time.Millisecond)
+	acked, nacked := slow.outcomes()
+	require.Equal(t, 0, acked)
+	require.Empty(t, nacked)
+	require.Equal(t, []time.Duration{2 * time.Second, 100 * time.Millisecond}, durations)
+}
+
+// prefetchAcknowledger models the acknowledger of an event source limiting the events passed on before they are
+// acknowledged, like eventsource.WithPrefetch does
+type prefetchAcknowledger struct {
+	fakeAcknowledger
+	slots    chan struct{}
+	released int32
+}
+
+func (p *prefetchAcknowledger) Release() {
+	atomic.AddInt32(&p.released, 1)
+	<-p.slots
+}
+
+func TestControlPlaneAckPolicyNoneReleasesPrefetchSlot(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithAckPolicy(func(update types.EventUpdate, handlingErr error, duration time.Duration) AckDecision {
+		return AckDecisionNone
+	}))
+	handled := make(chan string, 3)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			handled <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	// a single prefetch slot, so every further event is only passed on once the slot of the previous one was freed
+	slots := make(chan struct{}, 1)
+	acknowledgers := make([]*prefetchAcknowledger, 3)
+	go func() {
+		for i := range acknowledgers {
+			slots <- struct{}{}
+			acknowledgers[i] = &prefetchAcknowledger{slots: slots}
+			update := eventUpdate(fmt.Sprintf("event-%d", i), "sh.keptn.event.echo.triggered")
+			update.Acknowledger = acknowledgers[i]
+			eventChan <- update
+		}
+	}()
+	for i := range acknowledgers {
+		select {
+		case id := <-handled:
+			require.Equal(t, fmt.Sprintf("event-%d", i), id)
+		case <-time.After(time.Second):
+			require.FailNow(t, "prefetch slot of an unacknowledged event was not released")
+		}
+	}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&acknowledgers[2].released) == 1 }, time.Second, 10*time.Millisecond)
+	for _, a := range acknowledgers {
+		acked, nacked := a.outcomes()
+		require.Equal(t, 0, acked)
+		require.Empty(t, nacked)
+		require.Equal(t, int32(1), atomic.LoadInt32(&a.released))
+	}
+}
+
+func TestControlPlaneAckPolicyNacksHandledEvent(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil,
+		WithRedeliveryDelay(func(deliveryCount uint64) time.Duration { return 5 * time.Second }),
+		WithAckPolicy(func(update types.EventUpdate, handlingErr error, duration time.Duration) AckDecision {
+			return AckDecisionNack
+		}),
+	)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn:          func(ctx context.Context, ce models.KeptnContextExtendedCE) error { return nil },
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	acknowledger := &fakeAcknowledger{}
+	update := eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	update.Acknowledger = acknowledger
+	eventChan <- update
+
+	require.Eventually(t, func() bool {
+		_, nacked := acknowledger.outcomes()
+		return len(nacked) == 1
+	}, time.Second, 10*time.Millisecond)
+	acked, nacked := acknowledger.outcomes()
+	require.Equal(t, 0, acked)
+	require.Equal(t, []time.Duration{5 * time.Second}, nacked)
+}
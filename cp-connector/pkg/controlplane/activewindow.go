@@ -0,0 +1,71 @@
+package controlplane
+
+import (
+	"time"
+)
+
+// TimeWindow is a daily recurring period of time, e.g. the business hours
+type TimeWindow struct {
+	// Start and End are the offsets from midnight the window starts and ends at, e.g. 9*time.Hour.
+	// If End is before Start, the window spans midnight
+	Start time.Duration
+	End   time.Duration
+	// Weekdays are the days the window starts on. If empty, the window starts on every day
+	Weekdays []time.Weekday
+	// Location is the time zone the window is defined in. If nil, UTC is used
+	Location *time.Location
+}
+
+// Contains checks whether the given time lies within the window
+func (w TimeWindow) Contains(t time.Time) bool {
+	location := w.Location
+	if location == nil {
+		location = time.UTC
+	}
+	t = t.In(location)
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End && w.startsOn(t.Weekday())
+	}
+	if offset >= w.Start {
+		return w.startsOn(t.Weekday())
+	}
+	// the window started on the previous day
+	return offset < w.End && w.startsOn((t.Weekday()+6)%7)
+}
+
+func (w TimeWindow) startsOn(day time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, d := range w.Weekdays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// WithActiveWindows configures the ControlPlane to forward events to the integration only if they are received
+// within one of the given windows, e.g. during business hours. Events received outside the windows are dropped
+// and acknowledged. The current time is taken from the clock of the ControlPlane
+func WithActiveWindows(windows []TimeWindow) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.activeWindows = windows
+	}
+}
+
+// isActive checks whether the current time lies within one of the active windows, if any are configured
+func (cp *ControlPlane) isActive() bool {
+	if len(cp.activeWindows) == 0 {
+		return true
+	}
+	now := cp.clock.Now()
+	for _, w := range cp.activeWindows {
+		if w.Contains(now) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneActiveWindows(t *testing.T) {
+	sources := newFakeSources()
+	// the mock clock starts at Thursday, 1970-01-01 00:00 UTC
+	mockClock := clock.NewMock()
+	businessHours := TimeWindow{Start: 9 * time.Hour, End: 17 * time.Hour, Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithClock(mockClock), WithActiveWindows([]TimeWindow{businessHours}))
+	handled := make(chan string, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			handled <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	acknowledger := &fakeAcknowledger{}
+	outside := eventUpdate("outside", "sh.keptn.event.echo.triggered")
+	outside.Acknowledger = acknowledger
+	eventChan <- outside
+	require.Eventually(t, func() bool {
+		acked, _ := acknowledger.outcomes()
+		return acked == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Empty(t, handled)
+
+	mockClock.Add(10 * time.Hour)
+	eventChan <- eventUpdate("inside", "sh.keptn.event.echo.triggered")
+	require.Equal(t, "inside", <-handled)
+}
+
+func TestTimeWindowContains(t *testing.T) {
+	vienna, err := time.LoadLocation("Europe/Vienna")
+	require.NoError(t, err)
+	// Monday, 2022-06-13
+	monday := func(hour int, minute int) time.Time { return time.Date(2022, 6, 13, hour, minute, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name   string
+		window TimeWindow
+		time   time.Time
+		want   bool
+	}{
+		{name: "within window", window: TimeWindow{Start: 9 * time.Hour, End: 17 * time.Hour}, time: monday(12, 0), want: true},
+		{name: "at start", window: TimeWindow{Start: 9 * time.Hour, End: 17 * time.Hour}, time: monday(9, 0), want: true},
+		{name: "at end", window: TimeWindow{Start: 9 * time.Hour, End: 17 * time.Hour}, time: monday(17, 0), want: false},
+		{name: "other weekday", window: TimeWindow{Start: 9 * time.Hour, End: 17 * time.Hour, Weekdays: []time.Weekday{time.Tuesday}}, time: monday(12, 0), want: false},
+		// 16:30 UTC is 18:30 in Vienna during summer time
+		{name: "time zone", window: TimeWindow{Start: 9 * time.Hour, End: 17 * time.Hour, Location: vienna}, time: monday(16, 30), want: false},
+		{name: "time zone within window", window: TimeWindow{Start: 9 * time.Hour, End: 17 * time.Hour, Location: vienna}, time: monday(7, 30), want: true},
+		{name: "spanning midnight before midnight", window: TimeWindow{Start: 22 * time.Hour, End: 2 * time.Hour, Weekdays: []time.Weekday{time.Monday}}, time: monday(23, 0), want: true},
+		{name: "spanning midnight after midnight", window: TimeWindow{Start: 22 * time.Hour, End: 2 * time.Hour, Weekdays: []time.Weekday{time.Sunday}}, time: monday(1, 0), want: true},
+		{name: "spanning midnight started on other day", window: TimeWindow{Start: 22 * time.Hour, End: 2 * time.Hour, Weekdays: []time.Weekday{time.Monday}}, time: monday(1, 0), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.window.Contains(tt.time))
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// auditBufferSize is the number of audit records buffered for the audit sink. Further records are dropped
+// until the sink caught up
+const auditBufferSize = 100
+
+// AuditOutcome describes the result of the handling of a forwarded event
+type AuditOutcome int
+
+const (
+	// AuditOutcomeSucceeded is reported for events the integration handled successfully
+	AuditOutcomeSucceeded AuditOutcome = iota
+	// AuditOutcomeFailed is reported for events the integration failed to handle
+	AuditOutcomeFailed
+	// AuditOutcomeFatal is reported for events the integration failed to handle with ErrEventHandleFatal
+	AuditOutcomeFatal
+)
+
+func (o AuditOutcome) String() string {
+	switch o {
+	case AuditOutcomeSucceeded:
+		return "succeeded"
+	case AuditOutcomeFailed:
+		return "failed"
+	case AuditOutcomeFatal:
+		return "fatal"
+	default:
+		return "unknown"
+	}
+}
+
+// AuditRecord describes an event forwarded to the integration and the outcome of its handling
+type AuditRecord struct {
+	// Timestamp is the time the event was forwarded to the integration
+	Timestamp      time.Time
+	EventID        string
+	Subject        string
+	KeptnContext   string
+	SubscriptionID string
+	Outcome        AuditOutcome
+	// Err is the error the handling failed with, it is nil if the event was handled successfully
+	Err error
+	// Duration is the time the integration took to handle the event, including retries
+	Duration time.Duration
+}
+
+// AuditSinkFn receives an AuditRecord for every event forwarded to the integration
+type AuditSinkFn func(AuditRecord)
+
+// WithAuditSink sets a function receiving an AuditRecord for every event forwarded to the integration, e.g. to
+// keep an audit trail of the processed events. The sink is called on a separate goroutine, so a slow sink does
+// not delay the handling of events. If the sink falls behind by more than 100 records, further records are dropped
+func WithAuditSink(sink AuditSinkFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.auditSink = sink
+	}
+}
+
+// runAuditSink passes the audit records to the audit sink until the context is cancelled.
+// The returned channel is closed as soon as the sink received the last buffered record
+func (cp *ControlPlane) runAuditSink(ctx context.Context) <-chan struct{} {
+	records := make(chan AuditRecord, auditBufferSize)
+	cp.auditRecords = records
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case record := <-records:
+				cp.auditSink(record)
+			case <-ctx.Done():
+				// pass on the records of the events handled before the shutdown
+				for {
+					select {
+					case record := <-records:
+						cp.auditSink(record)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+	return done
+}
+
+// audit queues the audit record of the given handled event, unless no audit sink is configured
+func (cp *ControlPlane) audit(eventUpdate types.EventUpdate, subscriptionID string, start time.Time, err error) {
+	if cp.auditRecords == nil {
+		return
+	}
+	record := AuditRecord{
+		Timestamp:      start,
+		EventID:        eventUpdate.KeptnEvent.ID,
+		Subject:        eventUpdate.MetaData.Subject,
+		KeptnContext:   eventUpdate.KeptnEvent.Shkeptncontext,
+		SubscriptionID: subscriptionID,
+		Outcome:        AuditOutcomeSucceeded,
+		Err:            err,
+		Duration:       cp.clock.Since(start),
+	}
+	if errors.Is(err, ErrEventHandleFatal) {
+		record.Outcome = AuditOutcomeFatal
+	} else if err != nil {
+		record.Outcome = AuditOutcomeFailed
+	}
+	select {
+	case cp.auditRecords <- record:
+	default:
+		cp.logger.Warnf("Dropping audit record of event %s: audit sink is not keeping up", record.EventID)
+	}
+}
@@ -0,0 +1,95 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneAuditSink(t *testing.T) {
+	sources := newFakeSources()
+	mockClock := clock.NewMock()
+	records := make(chan AuditRecord, 2)
+	controlPlane := New(sources.ssm, sources.esm, nil, WithClock(mockClock), WithAuditSink(func(record AuditRecord) {
+		records <- record
+	}))
+	handlingErr := errors.New("handling failed")
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			mockClock.Add(2 * time.Second)
+			if ce.ID == "failing" {
+				return handlingErr
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	update := eventUpdate("succeeding", "sh.keptn.event.echo.triggered")
+	update.KeptnEvent.Shkeptncontext = "keptn-context"
+	eventChan <- update
+	record := <-records
+	require.Equal(t, AuditRecord{
+		Timestamp:      time.Unix(0, 0),
+		EventID:        "succeeding",
+		Subject:        "sh.keptn.event.echo.triggered",
+		KeptnContext:   "keptn-context",
+		SubscriptionID: "sub-1",
+		Outcome:        AuditOutcomeSucceeded,
+		Duration:       2 * time.Second,
+	}, record)
+
+	eventChan <- eventUpdate("failing", "sh.keptn.event.echo.triggered")
+	record = <-records
+	require.Equal(t, "failing", record.EventID)
+	require.Equal(t, time.Unix(2, 0), record.Timestamp)
+	require.Equal(t, AuditOutcomeFailed, record.Outcome)
+	require.ErrorIs(t, record.Err, handlingErr)
+}
+
+func TestControlPlaneAuditSinkDoesNotBlock(t *testing.T) {
+	sources := newFakeSources()
+	release := make(chan struct{})
+	controlPlane := New(sources.ssm, sources.esm, nil, WithAuditSink(func(record AuditRecord) {
+		<-release
+	}))
+	handled := make(chan string)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			handled <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	defer close(release)
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	// more events than audit records are buffered are handled while the sink is blocked
+	for i := 0; i < 2*auditBufferSize; i++ {
+		id := fmt.Sprintf("event-%d", i)
+		eventChan <- eventUpdate(id, "sh.keptn.event.echo.triggered")
+		require.Equal(t, id, <-handled)
+	}
+}
+
+func TestAuditOutcomeString(t *testing.T) {
+	require.Equal(t, "succeeded", AuditOutcomeSucceeded.String())
+	require.Equal(t, "failed", AuditOutcomeFailed.String())
+	require.Equal(t, "fatal", AuditOutcomeFatal.String())
+}
@@ -0,0 +1,130 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// errEventBatched is the handling result of events that were added to the current batch.
+// They are acknowledged once the batch was passed to the integration
+var errEventBatched = errors.New("event was added to batch")
+
+// errBatchAbandoned is the handling result of batched events that were not passed to the integration
+// before the registration stopped
+var errBatchAbandoned = errors.New("batch was abandoned before being handled")
+
+// BatchIntegration can be implemented by integrations to receive the matched events in batches,
+// e.g. to write them to a database at once. Batches are only used if enabled via WithBatching
+type BatchIntegration interface {
+	Integration
+	// OnEvents is called with a batch of matched events in the order they were received.
+	// If an error is returned, all events of the batch are redelivered, if supported by the event source
+	OnEvents(context.Context, []models.KeptnContextExtendedCE) error
+}
+
+// WithBatching configures the ControlPlane to pass the matched events to integrations implementing
+// BatchIntegration in batches of up to maxSize events. A batch is passed to the integration as soon as it is full
+// or the given window expired since its first event was received. Events requiring strict ordering
+// (see WithPerSubscriptionOrdering) are not batched
+func WithBatching(maxSize int, window time.Duration) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.batchSize = maxSize
+		ns.batchWindow = window
+	}
+}
+
+// eventBatch collects the matched events until they are passed to the integration.
+// It is only accessed by the goroutine handling the received events
+type eventBatch struct {
+	clock   clock.Clock
+	maxSize int
+	window  time.Duration
+	// updates holds the received events to acknowledge once the batch was handled
+	updates []types.EventUpdate
+	// events holds the prepared events passed to the integration, one per matched subscription
+	events []models.KeptnContextExtendedCE
+	timer  *clock.Timer
+}
+
+func newEventBatch(clock clock.Clock, maxSize int, window time.Duration) *eventBatch {
+	return &eventBatch{clock: clock, maxSize: maxSize, window: window}
+}
+
+// add adds the events prepared for the matched subscriptions of the received event to the batch.
+// The first event of a batch starts its window
+func (b *eventBatch) add(update types.EventUpdate, events []models.KeptnContextExtendedCE) {
+	if len(b.updates) == 0 && b.window > 0 {
+		b.timer = b.clock.Timer(b.window)
+	}
+	b.updates = append(b.updates, update)
+	b.events = append(b.events, events...)
+}
+
+func (b *eventBatch) full() bool {
+	return b != nil && len(b.events) >= b.maxSize
+}
+
+// expired returns a channel receiving a value once the window of the current batch expired
+func (b *eventBatch) expired() <-chan time.Time {
+	if b == nil || b.timer == nil {
+		return nil
+	}
+	return b.timer.C
+}
+
+// take removes all events from the batch
+func (b *eventBatch) take() ([]types.EventUpdate, []models.KeptnContextExtendedCE) {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	updates, events := b.updates, b.events
+	b.updates, b.events = nil, nil
+	return updates, events
+}
+
+// flushBatch passes the batched events to the integration and acknowledges all of them based on the result.
+// The events are only marked as seen if the batch was handled successfully
+func (cp *ControlPlane) flushBatch(ctx context.Context, integration BatchIntegration) error {
+	cp.reload.dispatchMtx.RLock()
+	defer cp.reload.dispatchMtx.RUnlock()
+	updates, events := cp.batch.take()
+	if len(events) == 0 {
+		return nil
+	}
+	cp.logger.Infof("Forwarding batch of %d events", len(events))
+	err := cp.classifyFatal(integration.OnEvents(context.WithValue(ctx, types.EventSenderKey, cp.getSender(cp.eventSender)), events))
+	if errors.Is(err, ErrEventHandleFatal) {
+		cp.logger.Errorf("Fatal error during handling of batch: %v", err)
+	} else if err != nil {
+		cp.logger.Warnf("Error during handling of batch: %v", err)
+	}
+	for _, update := range updates {
+		cp.acknowledge(ctx, update, err)
+	}
+	if err == nil {
+		for _, update := range updates {
+			cp.markSeen(cp.logger, update.KeptnEvent.ID)
+		}
+		for range events {
+			cp.eventLimit.observeHandled()
+		}
+	}
+	return err
+}
+
+// abandonBatch requests the redelivery of the batched events that were not passed to the integration
+func (cp *ControlPlane) abandonBatch(ctx context.Context) {
+	if cp.batch == nil {
+		return
+	}
+	updates, _ := cp.batch.take()
+	for _, update := range updates {
+		cp.acknowledge(ctx, update, errBatchAbandoned)
+	}
+}
@@ -0,0 +1,176 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+type batchIntegration struct {
+	ExampleIntegration
+	OnEventsFn func(ctx context.Context, events []models.KeptnContextExtendedCE) error
+}
+
+func (b batchIntegration) OnEvents(ctx context.Context, events []models.KeptnContextExtendedCE) error {
+	return b.OnEventsFn(ctx, events)
+}
+
+func eventIDs(events []models.KeptnContextExtendedCE) []string {
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestControlPlaneBatching(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithBatching(3, 100*time.Millisecond))
+	var mtx sync.Mutex
+	var batches [][]string
+	integration := batchIntegration{
+		ExampleIntegration: ExampleIntegration{
+			RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+			OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+				require.FailNow(t, "unexpected call of OnEvent")
+				return nil
+			},
+		},
+		OnEventsFn: func(ctx context.Context, events []models.KeptnContextExtendedCE) error {
+			require.NotNil(t, ctx.Value(types.EventSenderKey))
+			mtx.Lock()
+			defer mtx.Unlock()
+			batches = append(batches, eventIDs(events))
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	acknowledgers := make([]*fakeAcknowledger, 5)
+	for i := range acknowledgers {
+		acknowledgers[i] = &fakeAcknowledger{}
+		update := eventUpdate(fmt.Sprintf("event-%d", i), "sh.keptn.event.echo.triggered")
+		update.Acknowledger = acknowledgers[i]
+		eventChan <- update
+	}
+
+	// the first batch is full, the second one is forwarded after the window expired
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(batches) == 2
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, [][]string{{"event-0", "event-1", "event-2"}, {"event-3", "event-4"}}, batches)
+	for _, a := range acknowledgers {
+		acked, nacked := a.outcomes()
+		require.Equal(t, 1, acked)
+		require.Empty(t, nacked)
+	}
+}
+
+func TestControlPlaneBatchErrorNacksWholeBatch(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithBatching(3, time.Minute), WithRedeliveryDelay(LinearRedeliveryDelay(time.Second, time.Minute)))
+	integration := batchIntegration{
+		ExampleIntegration: ExampleIntegration{
+			RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		},
+		OnEventsFn: func(ctx context.Context, events []models.KeptnContextExtendedCE) error {
+			return errors.New("database unavailable")
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	acknowledgers := make([]*fakeAcknowledger, 3)
+	for i := range acknowledgers {
+		acknowledgers[i] = &fakeAcknowledger{}
+		update := eventUpdate(fmt.Sprintf("event-%d", i), "sh.keptn.event.echo.triggered")
+		update.Acknowledger = acknowledgers[i]
+		eventChan <- update
+	}
+
+	for _, a := range acknowledgers {
+		require.Eventually(t, func() bool {
+			_, nacked := a.outcomes()
+			return len(nacked) == 1
+		}, time.Second, 10*time.Millisecond)
+		acked, _ := a.outcomes()
+		require.Equal(t, 0, acked)
+	}
+}
+
+func TestControlPlaneBatchAbandonedOnShutdown(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithBatching(10, time.Minute))
+	integration := batchIntegration{
+		ExampleIntegration: ExampleIntegration{
+			RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		},
+		OnEventsFn: func(ctx context.Context, events []models.KeptnContextExtendedCE) error {
+			require.FailNow(t, "unexpected call of OnEvents")
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	registerErr := make(chan error)
+	go func() { registerErr <- controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	acknowledger := &fakeAcknowledger{}
+	update := eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	update.Acknowledger = acknowledger
+	eventChan <- update
+
+	cancel()
+	require.NoError(t, <-registerErr)
+	_, nacked := acknowledger.outcomes()
+	require.Len(t, nacked, 1)
+}
+
+func TestControlPlaneBatchWindowWithMockClock(t *testing.T) {
+	sources := newFakeSources()
+	mockClock := clock.NewMock()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithClock(mockClock), WithBatching(10, time.Minute))
+	batches := make(chan []string, 1)
+	integration := batchIntegration{
+		ExampleIntegration: ExampleIntegration{
+			RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		},
+		OnEventsFn: func(ctx context.Context, events []models.KeptnContextExtendedCE) error {
+			batches <- eventIDs(events)
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	// the window starts with the first event of the batch. Each event is only received
+	// once the previous one was batched
+	eventChan <- eventUpdate("event-0", "sh.keptn.event.echo.triggered")
+	eventChan <- eventUpdate("event-1", "sh.keptn.event.echo.triggered")
+	mockClock.Add(59 * time.Second)
+	eventChan <- eventUpdate("unmatched", "sh.keptn.event.other.triggered")
+	require.Empty(t, batches)
+
+	mockClock.Add(time.Second)
+	require.Equal(t, []string{"event-0", "event-1"}, <-batches)
+}
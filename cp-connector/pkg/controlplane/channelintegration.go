@@ -0,0 +1,85 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+var _ Integration = (*ChannelIntegration)(nil)
+
+// ErrEventDropped is returned by ChannelIntegration.OnEvent if the event could not be passed to the channel
+var ErrEventDropped = errors.New("event dropped")
+
+// ChannelIntegration is an Integration that passes every received event to a channel.
+// By default, OnEvent blocks until the event is received from the channel
+type ChannelIntegration struct {
+	registration types.RegistrationData
+	events       chan<- models.KeptnContextExtendedCE
+	sendTimeout  time.Duration
+	dropOnFull   bool
+}
+
+// WithSendTimeout configures the ChannelIntegration to drop an event if it could not be passed to the channel
+// within the given timeout
+func WithSendTimeout(timeout time.Duration) func(integration *ChannelIntegration) {
+	return func(integration *ChannelIntegration) {
+		integration.sendTimeout = timeout
+	}
+}
+
+// WithDropOnFull configures the ChannelIntegration to drop an event immediately if the channel is not able to receive it
+func WithDropOnFull() func(integration *ChannelIntegration) {
+	return func(integration *ChannelIntegration) {
+		integration.dropOnFull = true
+	}
+}
+
+// NewChannelIntegration creates a new ChannelIntegration passing events to the given channel
+// and using the given registration data
+func NewChannelIntegration(registrationData types.RegistrationData, events chan<- models.KeptnContextExtendedCE, options ...func(integration *ChannelIntegration)) *ChannelIntegration {
+	ci := &ChannelIntegration{
+		registration: registrationData,
+		events:       events,
+	}
+	for _, o := range options {
+		o(ci)
+	}
+	return ci
+}
+
+// OnEvent passes the event to the channel. If the event is dropped, an error wrapping ErrEventDropped is returned.
+// If the given context is cancelled before the event is passed to the channel, the error of the context is returned
+func (c *ChannelIntegration) OnEvent(ctx context.Context, event models.KeptnContextExtendedCE) error {
+	if c.dropOnFull {
+		select {
+		case c.events <- event:
+			return nil
+		default:
+			return fmt.Errorf("channel is full: %w", ErrEventDropped)
+		}
+	}
+	var timeout <-chan time.Time
+	if c.sendTimeout > 0 {
+		timer := time.NewTimer(c.sendTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case c.events <- event:
+		return nil
+	case <-timeout:
+		return fmt.Errorf("channel did not receive event within %s: %w", c.sendTimeout, ErrEventDropped)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RegistrationData returns the registration data the ChannelIntegration was created with
+func (c *ChannelIntegration) RegistrationData() types.RegistrationData {
+	return c.registration
+}
@@ -0,0 +1,71 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelIntegrationDelivery(t *testing.T) {
+	events := make(chan models.KeptnContextExtendedCE)
+	integration := NewChannelIntegration(types.RegistrationData{Name: "channel"}, events)
+	require.Equal(t, "channel", integration.RegistrationData().Name)
+
+	go func() {
+		require.Nil(t, integration.OnEvent(context.TODO(), models.KeptnContextExtendedCE{ID: "event-id"}))
+	}()
+	select {
+	case event := <-events:
+		require.Equal(t, "event-id", event.ID)
+	case <-time.After(time.Second):
+		require.FailNow(t, "did not receive event")
+	}
+}
+
+func TestChannelIntegrationThroughControlPlane(t *testing.T) {
+	sources := newFakeSources()
+	events := make(chan models.KeptnContextExtendedCE)
+	controlPlane := New(sources.ssm, sources.esm, nil)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, NewChannelIntegration(types.RegistrationData{}, events)) }()
+	eventChan, subsChan := sources.channels(t)
+
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- types.EventUpdate{
+		KeptnEvent: models.KeptnContextExtendedCE{ID: "event-id", Type: strutils.Stringp("sh.keptn.event.echo.triggered")},
+		MetaData:   types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+	}
+	require.Equal(t, "event-id", (<-events).ID)
+}
+
+func TestChannelIntegrationDropOnFull(t *testing.T) {
+	events := make(chan models.KeptnContextExtendedCE, 1)
+	integration := NewChannelIntegration(types.RegistrationData{}, events, WithDropOnFull())
+
+	require.Nil(t, integration.OnEvent(context.TODO(), models.KeptnContextExtendedCE{ID: "first"}))
+	err := integration.OnEvent(context.TODO(), models.KeptnContextExtendedCE{ID: "second"})
+	require.ErrorIs(t, err, ErrEventDropped)
+	require.NotErrorIs(t, err, ErrEventHandleFatal)
+	require.Equal(t, "first", (<-events).ID)
+	require.Empty(t, events)
+}
+
+func TestChannelIntegrationSendTimeout(t *testing.T) {
+	events := make(chan models.KeptnContextExtendedCE)
+	integration := NewChannelIntegration(types.RegistrationData{}, events, WithSendTimeout(10*time.Millisecond))
+	require.ErrorIs(t, integration.OnEvent(context.TODO(), models.KeptnContextExtendedCE{ID: "event-id"}), ErrEventDropped)
+}
+
+func TestChannelIntegrationContextCancelled(t *testing.T) {
+	events := make(chan models.KeptnContextExtendedCE)
+	integration := NewChannelIntegration(types.RegistrationData{}, events)
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	require.ErrorIs(t, integration.OnEvent(ctx, models.KeptnContextExtendedCE{ID: "event-id"}), context.Canceled)
+}
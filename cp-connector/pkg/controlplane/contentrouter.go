@@ -0,0 +1,90 @@
+package controlplane
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+// KeyExtractorFn determines the routing key of an event, e.g. based on a field of the event data.
+// Returning an error marks the event as unrouteable
+type KeyExtractorFn func(event models.KeptnContextExtendedCE) (string, error)
+
+// ContentRouter dispatches events to handlers based on a key extracted from the event, e.g. an action name
+// contained in the event data. The ContentRouter is meant to be registered as handler of a Mux route:
+//
+//	router := NewContentRouter(DataFieldKey("action", "name"), defaultHandler)
+//	router.Route("restart", restartHandler)
+//	mux.Handle("sh.keptn.event.action.triggered", router.Handle)
+type ContentRouter struct {
+	mtx            sync.RWMutex
+	keyFn          KeyExtractorFn
+	routes         map[string]HandlerFunc
+	defaultHandler HandlerFunc
+}
+
+// NewContentRouter creates a new ContentRouter using the given key extractor.
+// Events that cannot be routed are passed to the default handler, which may be nil
+func NewContentRouter(keyFn KeyExtractorFn, defaultHandler HandlerFunc) *ContentRouter {
+	return &ContentRouter{
+		keyFn:          keyFn,
+		routes:         map[string]HandlerFunc{},
+		defaultHandler: defaultHandler,
+	}
+}
+
+// Route registers a handler for events with the given key, replacing any handler registered for the same key
+func (r *ContentRouter) Route(key string, handler HandlerFunc) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.routes[key] = handler
+}
+
+// Handle dispatches the event to the handler registered for its key. If the key cannot be extracted or
+// no handler is registered for it, the event is passed to the default handler. Without a default handler,
+// an error wrapping ErrNoMatchingRoute is returned
+func (r *ContentRouter) Handle(ctx context.Context, event models.KeptnContextExtendedCE) error {
+	key, err := r.keyFn(event)
+	r.mtx.RLock()
+	handler, ok := r.routes[key]
+	if err != nil || !ok {
+		handler = r.defaultHandler
+	}
+	r.mtx.RUnlock()
+	if handler == nil {
+		if err != nil {
+			return fmt.Errorf("unable to determine route of event %s: %v: %w", event.ID, err, ErrNoMatchingRoute)
+		}
+		return fmt.Errorf("unable to handle event %s with key %s: %w", event.ID, key, ErrNoMatchingRoute)
+	}
+	return handler(ctx, event)
+}
+
+// DataFieldKey returns a KeyExtractorFn using the string value found at the given path within the event data
+// as key. E.g. DataFieldKey("action", "action") uses the action name of an action.triggered event
+func DataFieldKey(path ...string) KeyExtractorFn {
+	return func(event models.KeptnContextExtendedCE) (string, error) {
+		var value interface{}
+		if err := keptnv2.EventDataAs(event, &value); err != nil {
+			return "", fmt.Errorf("could not decode event data: %w", err)
+		}
+		for _, field := range path {
+			fields, ok := value.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("event data does not contain field %s", strings.Join(path, "."))
+			}
+			if value, ok = fields[field]; !ok {
+				return "", fmt.Errorf("event data does not contain field %s", strings.Join(path, "."))
+			}
+		}
+		key, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("field %s of event data is not a string", strings.Join(path, "."))
+		}
+		return key, nil
+	}
+}
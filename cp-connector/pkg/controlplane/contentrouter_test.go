@@ -0,0 +1,72 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func actionEvent(data interface{}) models.KeptnContextExtendedCE {
+	return models.KeptnContextExtendedCE{ID: "event-id", Type: strutils.Stringp("sh.keptn.event.action.triggered"), Data: data}
+}
+
+func TestContentRouterRoutesByDataField(t *testing.T) {
+	var called []string
+	router := NewContentRouter(DataFieldKey("action", "action"), func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+		called = append(called, "default")
+		return nil
+	})
+	router.Route("restart", func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+		called = append(called, "restart")
+		return nil
+	})
+	router.Route("scale", func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+		called = append(called, "scale")
+		return nil
+	})
+	mux := NewMux(types.RegistrationData{})
+	mux.Handle("sh.keptn.event.action.triggered", router.Handle)
+
+	require.Nil(t, mux.OnEvent(context.TODO(), actionEvent(map[string]interface{}{"action": map[string]interface{}{"action": "scale"}})))
+	require.Nil(t, mux.OnEvent(context.TODO(), actionEvent(map[string]interface{}{"action": map[string]interface{}{"action": "restart"}})))
+	require.Equal(t, []string{"scale", "restart"}, called)
+}
+
+func TestContentRouterDefaultHandler(t *testing.T) {
+	var called []string
+	router := NewContentRouter(DataFieldKey("action", "action"), func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+		called = append(called, "default")
+		return nil
+	})
+	router.Route("restart", func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+		require.FailNow(t, "unexpected call of route handler")
+		return nil
+	})
+
+	// unknown key
+	require.Nil(t, router.Handle(context.TODO(), actionEvent(map[string]interface{}{"action": map[string]interface{}{"action": "unknown"}})))
+	// missing field
+	require.Nil(t, router.Handle(context.TODO(), actionEvent(map[string]interface{}{"project": "my-project"})))
+	// field is not a string
+	require.Nil(t, router.Handle(context.TODO(), actionEvent(map[string]interface{}{"action": map[string]interface{}{"action": 1}})))
+	require.Equal(t, []string{"default", "default", "default"}, called)
+}
+
+func TestContentRouterWithoutDefaultHandler(t *testing.T) {
+	router := NewContentRouter(func(event models.KeptnContextExtendedCE) (string, error) {
+		if event.ID == "" {
+			return "", errors.New("no id")
+		}
+		return event.ID, nil
+	}, nil)
+	router.Route("known", func(ctx context.Context, ce models.KeptnContextExtendedCE) error { return nil })
+
+	require.Nil(t, router.Handle(context.TODO(), models.KeptnContextExtendedCE{ID: "known"}))
+	require.ErrorIs(t, router.Handle(context.TODO(), models.KeptnContextExtendedCE{ID: "unknown"}), ErrNoMatchingRoute)
+	require.ErrorIs(t, router.Handle(context.TODO(), models.KeptnContextExtendedCE{}), ErrNoMatchingRoute)
+}
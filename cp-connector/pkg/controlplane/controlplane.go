@@ -2,16 +2,28 @@ package controlplane
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/benbjohnson/clock"
+	"github.com/google/uuid"
 	"github.com/keptn/go-utils/pkg/api/models"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
 	"github.com/keptn/keptn/cp-connector/pkg/eventmatcher"
 	"github.com/keptn/keptn/cp-connector/pkg/eventsource"
 	"github.com/keptn/keptn/cp-connector/pkg/logforwarder"
 	"github.com/keptn/keptn/cp-connector/pkg/logger"
 	"github.com/keptn/keptn/cp-connector/pkg/subscriptionsource"
 	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
 type EventSender = types.EventSender
@@ -22,24 +34,174 @@ const tmpDataDistributorKey = "distributor"
 
 var ErrEventHandleFatal = errors.New("fatal event handling error")
 
+// errEventAbandoned is the handling result of received events that were not handled, because the handling of
+// events stopped before, e.g. after a fatal error
+var errEventAbandoned = errors.New("event was abandoned before being handled")
+
+// ErrMissingSender is returned by Register if the event source does not provide a sender
+// and the ControlPlane is configured to fail on a missing sender
+var ErrMissingSender = errors.New("event source does not provide a sender")
+
+// MissingSenderBehavior defines how the ControlPlane reacts to an event source not providing a sender
+type MissingSenderBehavior int
+
+const (
+	// FailOnMissingSender lets Register fail with ErrMissingSender
+	FailOnMissingSender MissingSenderBehavior = iota
+	// NoOpOnMissingSender passes a sender to the integration that drops and logs all events sent
+	NoOpOnMissingSender
+)
+
+// EventTransformerFn is used to modify an event before it is passed to the integration
+type EventTransformerFn func(models.KeptnContextExtendedCE) (models.KeptnContextExtendedCE, error)
+
+// SubscriptionDataFn creates the data added as temporary data to every event forwarded for the given subscription.
+// The returned value must be serializable to JSON
+type SubscriptionDataFn func(models.EventSubscription) interface{}
+
+// MissingContextPolicy defines how the ControlPlane handles events without a keptn context
+type MissingContextPolicy int
+
+const (
+	// ForwardMissingContext forwards events without a keptn context unchanged
+	ForwardMissingContext MissingContextPolicy = iota
+	// DropMissingContext drops events without a keptn context
+	DropMissingContext
+	// SynthesizeMissingContext assigns a newly generated keptn context to events without a keptn context
+	SynthesizeMissingContext
+)
+
+// ContextDecoratorFn can enrich the context passed to the integration with request-scoped values
+type ContextDecoratorFn func(context.Context, models.KeptnContextExtendedCE) context.Context
+
+// SenderSelectorFn selects the sender used for responses to the given event, e.g. the sender of the control plane
+// the event originates from
+type SenderSelectorFn func(models.KeptnContextExtendedCE) types.EventSender
+
+// SubjectMapperFn derives the subject the event source subscribes to for the given subscription
+type SubjectMapperFn func(models.EventSubscription) string
+
+// SubjectNormalizerFn maps a subject to its normalized form, which is used to compare subjects
+type SubjectNormalizerFn func(subject string) string
+
 // Integration represents a Keptn Service that wants to receive events from the Keptn Control plane
 type Integration interface {
-	// OnEvent is called when a new event was received
+	// OnEvent is called when a new event was received. Each call receives its own context derived for the event,
+	// so values added to it (e.g. by a ContextDecoratorFn) are not visible to the handling of other events,
+	// also if events are handled concurrently. The sender for responses can be read from it via types.SenderFromContext
 	OnEvent(context.Context, models.KeptnContextExtendedCE) error
 
 	// RegistrationData is called to get the initial registration data
 	RegistrationData() types.RegistrationData
 }
 
+// Option configures the behavior of a ControlPlane, see the With... functions of this package.
+// It is an alias, so the options can be used wherever a func(plane *ControlPlane) is expected
+type Option = func(plane *ControlPlane)
+
 // ControlPlane can be used to connect to the Keptn Control Plane
 type ControlPlane struct {
 	subscriptionSource   subscriptionsource.SubscriptionSource
 	eventSource          eventsource.EventSource
 	currentSubscriptions []models.EventSubscription
+	subscriptionsMtx     sync.RWMutex
 	logger               logger.Logger
 	registered           bool
-	integrationID        string
-	logForwarder         logforwarder.LogForwarder
+	// registeredCh is closed as soon as the integration is registered and replaced once the registration stopped
+	registeredCh        chan struct{}
+	registeredMtx       sync.Mutex
+	integrationID       string
+	integrationName     string
+	logForwarder        logforwarder.LogForwarder
+	autoStarted         bool
+	eventTransformer    EventTransformerFn
+	metrics             *metrics
+	metricsRegisterer   prometheus.Registerer
+	selfCheckInterval   time.Duration
+	receiveBuffer       int
+	autoFinishedOnError bool
+	subscriptionErrorFn func(error)
+	ignoreSelfEvents    bool
+	eventPriorities     map[string]int
+	activationTimeout   time.Duration
+	samplingRatios      map[string]float64
+	subscriptionDataFn  SubscriptionDataFn
+	connectionStateFn   func(types.ConnectionState)
+	// registrationID is the integration ID of the registration that has not been removed via Deregister yet
+	registrationID    string
+	registrationMtx   sync.Mutex
+	idempotencyStore  IdempotencyStore
+	subjectMapper     SubjectMapperFn
+	subjectNormalizer SubjectNormalizerFn
+	redeliveryDelayFn RedeliveryDelayFn
+	maxDeliveries     int
+	deadLetterFn      DeadLetterFn
+	// subscriptionDebounce is the interval without further subscription updates after which the latest update is applied
+	subscriptionDebounce time.Duration
+	orderedSubjects      map[string]struct{}
+	orderedQueues        *orderedQueues
+	missingSender        MissingSenderBehavior
+	contextDecorator     ContextDecoratorFn
+	maxEventSize         int
+	missingContext       MissingContextPolicy
+	errorEventOnFailure  bool
+	errorEventSubject    string
+	resultEventSubject   string
+	readinessCheck       ReadinessCheckFn
+	integrationVersion   string
+	reload               reloadCoordinator
+	redeliverOnReload    bool
+	pauseOnUpdate        bool
+	dropReasonFn         DropReasonHandlerFn
+	maxEventsHandled     int
+	eventLimit           *eventLimit
+	typePatternMatching  bool
+	receiveErrorFn       func(error)
+	batchSize            int
+	batchWindow          time.Duration
+	batch                *eventBatch
+	warmupBufferSize     int
+	warmup               *warmup
+	clock                clock.Clock
+	mutedMtx             sync.RWMutex
+	muted                map[string]struct{}
+	livenessInterval     time.Duration
+	livenessEventFn      LivenessEventFn
+	senderSelector       SenderSelectorFn
+	subscriptionDiffFn   SubscriptionDiffHandlerFn
+	handlerAttempts      int
+	handlerRetryBackoff  RetryBackoffFn
+	auditSink            AuditSinkFn
+	auditRecords         chan AuditRecord
+	handlerTimeout       time.Duration
+	subscriptionTimeouts map[string]time.Duration
+	stormRate            float64
+	stormWindow          time.Duration
+	stormShedding        bool
+	stormRedeliveryDelay time.Duration
+	storm                *stormDetector
+	idPersistencePath    string
+	eventExtensions      map[string]interface{}
+	ackPolicy            AckPolicyFn
+	activeWindows        []TimeWindow
+	stackTraceOnTimeout  bool
+	stats                *stats
+	unmatchedBufferSize  int
+	unmatchedTTL         time.Duration
+	unmatched            *unmatchedEvents
+	fatalClassifier      FatalClassifierFn
+	taskTimeout          time.Duration
+	stuckTaskFn          StuckTaskFn
+	tasks                *taskTracker
+	filterExpression     *eventmatcher.FilterExpression
+	filterExpressionErr  error
+	dryRunSender         types.EventSender
+	maxConcurrentSends   int
+	subscriptionTagging  bool
+	// sendSlots holds a token for every running send, if the number of concurrent sends is limited
+	sendSlots chan struct{}
+	// eventSender is the sender of the event source, determined during registration
+	eventSender types.EventSender
 }
 
 // WithLogger sets the logger to use
@@ -49,101 +211,1055 @@ func WithLogger(logger logger.Logger) func(plane *ControlPlane) {
 	}
 }
 
+// WithAutoStarted configures the ControlPlane to automatically send a .started event
+// for every received .triggered event before passing it to the integration
+func WithAutoStarted(autoStarted bool) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.autoStarted = autoStarted
+	}
+}
+
+// WithEventTransformer sets a function that is applied to every matched event before it is passed to the integration.
+// If the transformer returns an error, the event is dropped
+func WithEventTransformer(transformer EventTransformerFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.eventTransformer = transformer
+	}
+}
+
+// WithMetrics registers the prometheus metrics of the ControlPlane at the given registerer.
+// Metrics that cannot be registered are logged and not exported
+func WithMetrics(registerer prometheus.Registerer) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.metricsRegisterer = registerer
+	}
+}
+
+// WithSelfCheck enables a periodic self check which logs the number of resources
+// owned by the event source and subscription source (see Health)
+func WithSelfCheck(interval time.Duration) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.selfCheckInterval = interval
+	}
+}
+
+// WithReceiveBuffer sets the number of received events that are buffered while the integration is busy handling an event.
+// If the buffer is full, the event source is blocked until the next event is taken from the buffer.
+// By default, no events are buffered
+func WithReceiveBuffer(size int) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.receiveBuffer = size
+	}
+}
+
+// WithAutoFinishedOnError configures the ControlPlane to automatically send a .finished event with
+// status errored if the integration panics or returns a non-fatal error while handling a .triggered event.
+// The message of the .finished event contains the returned error or the recovered panic
+func WithAutoFinishedOnError(autoFinished bool) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.autoFinishedOnError = autoFinished
+	}
+}
+
+// WithSubscriptionErrorHandler sets a function that is called for every error reported by the subscription source,
+// e.g. if the subscriptions could not be fetched from the Keptn API
+func WithSubscriptionErrorHandler(handler func(error)) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.subscriptionErrorFn = handler
+	}
+}
+
+// WithIgnoreSelfEvents configures the ControlPlane to drop received events that were produced by the integration itself,
+// i.e. events whose source is the integration ID assigned during registration or the name of the integration
+// (which is used as source of the events sent by the ControlPlane)
+func WithIgnoreSelfEvents(ignore bool) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.ignoreSelfEvents = ignore
+	}
+}
+
+// WithEventPriority assigns priorities to event subjects or subject patterns (see Mux).
+// Received events waiting to be handled are passed to the integration in order of their priority, higher priorities first.
+// Events of subjects without priority have priority 0. To bound starvation, an event is handled regardless of its
+// priority once it was overtaken by 10 events. The number of waiting events is limited by WithReceiveBuffer and
+// defaults to 100
+func WithEventPriority(priorities map[string]int) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.eventPriorities = priorities
+	}
+}
+
+// WithSubscriptionActivationTimeout sets the time the subscriptions requested in the registration data of the integration
+// have to become active. If a requested subject is not part of any subscription update received within the timeout,
+// a warning listing the missing subjects is logged. A timeout of 0 disables the check. Defaults to one minute
+func WithSubscriptionActivationTimeout(timeout time.Duration) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.activationTimeout = timeout
+	}
+}
+
+// WithEventSampling configures the ControlPlane to only forward a sample of the events of the given subjects.
+// The ratio of a subject (between 0 and 1) is the fraction of its events being forwarded to the integration,
+// the remaining events are dropped. Whether an event is part of the sample is determined by its ID, so
+// redeliveries of an event are either always forwarded or always dropped
+func WithEventSampling(ratios map[string]float64) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.samplingRatios = ratios
+	}
+}
+
+// WithSubscriptionData sets the function creating the temporary data that is added to the events forwarded to the integration.
+// By default, types.AdditionalSubscriptionData containing the ID of the matched subscription is added
+func WithSubscriptionData(fn SubscriptionDataFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.subscriptionDataFn = fn
+	}
+}
+
+// WithConnectionStateHandler sets a function that is called whenever the event source
+// lost or re-established its connection to the message broker
+func WithConnectionStateHandler(fn func(types.ConnectionState)) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.connectionStateFn = fn
+	}
+}
+
+// WithIdempotencyStore sets the IdempotencyStore that is consulted before forwarding an event to the integration.
+// Events whose ID was seen before are dropped, all other events are marked as seen once they were handled successfully,
+// so the redelivery of a failed event is forwarded again
+func WithIdempotencyStore(store IdempotencyStore) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.idempotencyStore = store
+	}
+}
+
+// WithSubjectMapper sets the function deriving the subjects passed to the event source from the subscriptions,
+// e.g. to subscribe to namespaced or prefixed subjects. By default, the event type of the subscription is used.
+// Events received on a mapped subject are matched against the subscription the subject was derived from
+func WithSubjectMapper(mapper SubjectMapperFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.subjectMapper = mapper
+	}
+}
+
+// WithSubjectNormalizer sets a function normalizing both the subject an event was received on and the subjects of
+// the subscriptions before they are compared, e.g. to match events of Keptn versions using slightly different subjects
+// for the same event. The event source still subscribes to the subjects of the subscriptions (see WithSubjectMapper).
+// By default, subjects are compared as they are
+func WithSubjectNormalizer(normalizer SubjectNormalizerFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.subjectNormalizer = normalizer
+	}
+}
+
+// WithSubscriptionDebounce coalesces bursts of subscription updates. An update is applied only once no further
+// update was received for the given interval, in which case only the latest update is applied
+func WithSubscriptionDebounce(interval time.Duration) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.subscriptionDebounce = interval
+	}
+}
+
+// WithRedeliveryDelay sets the function computing the delay after which an event whose handling failed
+// is redelivered by the broker. It is only used for event sources supporting acknowledgements.
+// By default, LinearRedeliveryDelay(time.Second, time.Minute) is used
+func WithRedeliveryDelay(fn RedeliveryDelayFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.redeliveryDelayFn = fn
+	}
+}
+
+// WithPerSubscriptionOrdering enables strict ordering for the subscriptions to the given subjects.
+// Each of these subscriptions gets its own FIFO queue processed by a single dedicated worker, while events of
+// other subscriptions are handled independently. Events forwarded by a worker are acknowledged as soon as they
+// were queued, so if their handling is cancelled by a reload (see WithRedeliveryOnReload), they are forwarded again
+// once the reload finished. Fatal errors returned by the integration for them stop the ControlPlane
+func WithPerSubscriptionOrdering(subjects ...string) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.orderedSubjects = map[string]struct{}{}
+		for _, subject := range subjects {
+			ns.orderedSubjects[subject] = struct{}{}
+		}
+	}
+}
+
+// WithMissingSenderBehavior configures how the ControlPlane reacts if the event source does not provide a sender.
+// By default, Register fails
+func WithMissingSenderBehavior(behavior MissingSenderBehavior) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.missingSender = behavior
+	}
+}
+
+// WithContextDecorator sets a function that can enrich the context passed to the integration, e.g. with the tenant
+// of the event. The decorator is called just before the event is passed to OnEvent, after the sender was added to
+// the context and the subscription data was added to the event. The decorator is called once per forwarded event
+// with a context derived for that event only, so the values it adds do not leak into the handling of other events.
+// Values that are shared between events, e.g. pointers kept by the decorator, still need to be safe for concurrent use
+func WithContextDecorator(decorator ContextDecoratorFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.contextDecorator = decorator
+	}
+}
+
+// WithSenderSelector sets a function selecting the sender that is added to the context passed to the integration
+// and used for the .started, .finished or error events sent on behalf of the integration.
+// If the function returns nil, or if events are delivered as a batch, the sender of the event source is used
+func WithSenderSelector(selector SenderSelectorFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.senderSelector = selector
+	}
+}
+
+// WithMaxEventSize configures the ControlPlane to drop events whose raw payload exceeds the given number of bytes.
+// Dropped events are logged and acknowledged. If the event source does not provide the size of the raw payload,
+// the size of the serialized event is used
+func WithMaxEventSize(bytes int) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.maxEventSize = bytes
+	}
+}
+
+// WithMissingContextPolicy configures how events without a keptn context are handled.
+// By default, they are forwarded unchanged
+func WithMissingContextPolicy(policy MissingContextPolicy) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.missingContext = policy
+	}
+}
+
+// WithErrorEventOnFailure configures the ControlPlane to publish an error event if the integration
+// fails to handle an event. By default, the error event is of type sh.keptn.log.error
+func WithErrorEventOnFailure(enabled bool) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.errorEventOnFailure = enabled
+	}
+}
+
+// WithErrorEventSubject sets the type of the error events published if the integration fails to handle an event,
+// e.g. to route them to an alerting pipeline. Error events are only published if enabled via WithErrorEventOnFailure
+func WithErrorEventSubject(subject string) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.errorEventSubject = subject
+	}
+}
+
+// WithReadinessCheck configures a check that Register polls until it succeeds before the integration is registered
+// and starts receiving events, e.g. to wait for a database the integration depends on
+func WithReadinessCheck(check ReadinessCheckFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.readinessCheck = check
+	}
+}
+
+// WithVersion sets the version of the integration that is sent to Keptn when registering, overriding the
+// version contained in the registration data of the integration. As the registration has no dedicated field
+// for the git commit, a non-empty commit is appended as build metadata, e.g. "1.2.0+3f2a9c1"
+func WithVersion(version, gitCommit string) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.integrationVersion = version
+		if gitCommit != "" {
+			ns.integrationVersion = fmt.Sprintf("%s+%s", version, gitCommit)
+		}
+	}
+}
+
+// WithTypePatternMatching configures the ControlPlane to additionally match the type of received events against
+// the events of the subscriptions, treating them as NATS style patterns, where "*" matches exactly one token and
+// ">" matches one or more trailing tokens. This allows subscriptions like "sh.keptn.event.*.deployment.triggered".
+// By default, only the subject the event was received on is compared to the events of the subscriptions
+func WithTypePatternMatching() func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.typePatternMatching = true
+	}
+}
+
+// WithReceiveErrorHandler sets a function that is called whenever the event source fails to receive an event,
+// e.g. because of a malformed message. The ControlPlane continues to process further events
+func WithReceiveErrorHandler(handler func(error)) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.receiveErrorFn = handler
+	}
+}
+
+// WithClock sets the clock used for all time dependent behavior of the ControlPlane, e.g. to use a fake clock in tests
+func WithClock(clock clock.Clock) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.clock = clock
+	}
+}
+
 // New creates a new ControlPlane
 // It is using a SubscriptionSource source to get information about current uniform subscriptions
 // as well as an EventSource to actually receive events from Keptn
-// and a LogForwarder to forward error logs
-func New(subscriptionSource subscriptionsource.SubscriptionSource, eventSource eventsource.EventSource, logForwarder logforwarder.LogForwarder, opts ...func(plane *ControlPlane)) *ControlPlane {
+// and a LogForwarder to forward error logs.
+// Optional behavior is configured via the given options
+func New(subscriptionSource subscriptionsource.SubscriptionSource, eventSource eventsource.EventSource, logForwarder logforwarder.LogForwarder, opts ...Option) *ControlPlane {
 	cp := &ControlPlane{
 		subscriptionSource:   subscriptionSource,
 		eventSource:          eventSource,
 		currentSubscriptions: []models.EventSubscription{},
-		logger:               logger.NewDefaultLogger(),
+		logger:               logger.NewLevelLogger(logger.NewDefaultLogger(), logger.DebugLevel),
 		logForwarder:         logForwarder,
 		registered:           false,
+		registeredCh:         make(chan struct{}),
+		activationTimeout:    time.Minute,
+		redeliveryDelayFn:    LinearRedeliveryDelay(time.Second, time.Minute),
+		clock:                clock.New(),
+		stats:                &stats{},
+		subscriptionTagging:  true,
 	}
 	for _, o := range opts {
 		o(cp)
 	}
+	// the metrics are created after all options were applied, so registration errors are logged with the configured logger
+	if cp.metricsRegisterer != nil {
+		cp.metrics = newMetrics(cp.metricsRegisterer, cp.logger)
+	}
+	if cp.taskTimeout > 0 {
+		cp.tasks = newTaskTracker(cp.clock, cp.taskTimeout, cp.stuckTaskFn)
+	}
+	if cp.maxConcurrentSends > 0 {
+		cp.sendSlots = make(chan struct{}, cp.maxConcurrentSends)
+	}
 	return cp
 }
 
-// Register is initially used to register the Keptn integration to the Control Plane
+// Register is initially used to register the Keptn integration to the Control Plane.
+// If the given context is already cancelled, the integration is not registered and an error is returned.
+// Register blocks until the given context is cancelled or handling an event failed fatally
 func (cp *ControlPlane) Register(ctx context.Context, integration Integration) error {
-	eventUpdates := make(chan types.EventUpdate)
+	result, err := cp.Start(ctx, integration)
+	if err != nil {
+		return err
+	}
+	return <-result
+}
+
+// Start registers the Keptn integration to the Control Plane like Register, but returns as soon as the integration
+// is registered and the ControlPlane started to forward events to it. Errors preventing the registration are
+// returned immediately. Once the ControlPlane stopped, e.g. because the given context was cancelled, the result
+// Register would have returned is sent on the returned channel
+func (cp *ControlPlane) Start(ctx context.Context, integration Integration) (<-chan error, error) {
+	registered := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		result <- cp.register(ctx, integration, func() { close(registered) })
+	}()
+	select {
+	case <-registered:
+		return result, nil
+	case err := <-result:
+		select {
+		case <-registered:
+			// the ControlPlane stopped right after the registration
+			result <- err
+			return result, nil
+		default:
+			return nil, err
+		}
+	}
+}
+
+// register registers the integration and forwards the received events to it until the ControlPlane stops.
+// The given function is called once the integration is registered
+func (cp *ControlPlane) register(ctx context.Context, integration Integration, registered func()) error {
+	if cp.filterExpressionErr != nil {
+		return fmt.Errorf("could not register integration: %w", cp.filterExpressionErr)
+	}
+	// do not create a registration that would be abandoned immediately
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("could not register integration: %w", err)
+	}
+
+	var eventUpdates chan types.EventUpdate
+	if len(cp.eventPriorities) > 0 {
+		// received events are buffered by the prioritization
+		eventUpdates = make(chan types.EventUpdate)
+	} else {
+		eventUpdates = make(chan types.EventUpdate, cp.receiveBuffer)
+	}
 	subscriptionUpdates := make(chan []models.EventSubscription)
+	subscriptionErrors := make(chan error)
+	connectionStates := make(chan types.ConnectionState)
+	receiveErrors := make(chan error)
+
+	if err := cp.waitUntilReady(ctx); err != nil {
+		return fmt.Errorf("could not register integration: %w", err)
+	}
 
 	var err error
 	registrationData := integration.RegistrationData()
+	if cp.integrationVersion != "" {
+		registrationData.MetaData.IntegrationVersion = cp.integrationVersion
+	}
 	cp.logger.Debugf("Registering integration %s", integration.RegistrationData().Name)
-	cp.integrationID, err = cp.subscriptionSource.Register(models.Integration(registrationData))
+	cp.integrationID, err = cp.registerIntegration(registrationData)
 	if err != nil {
 		return fmt.Errorf("could not register integration: %w", err)
 	}
 	cp.logger.Debugf("Registered with integration ID %s", cp.integrationID)
+	cp.registrationMtx.Lock()
+	cp.registrationID = cp.integrationID
+	cp.registrationMtx.Unlock()
 	registrationData.ID = cp.integrationID
+	cp.integrationName = registrationData.Name
 
-	// WaitGroup used for synchronized shutdown of eventsource and subscription source
-	// during cancellation of the context
-	wg := &sync.WaitGroup{}
-	wg.Add(2)
+	// The sources are bound to a context that is cancelled as soon as Register returns.
+	// Before returning, Register waits until every started source confirmed that it stopped, so
+	// no source is left behind sending on one of the update channels
+	ctx, cancel := context.WithCancel(ctx)
+	var stopped []<-chan struct{}
+	defer func() {
+		cancel()
+		for _, done := range stopped {
+			<-done
+		}
+		cp.setRegistered(false)
+	}()
 
 	cp.logger.Debugf("Starting event source for integration ID %s", cp.integrationID)
-	if err := cp.eventSource.Start(ctx, registrationData, eventUpdates, wg); err != nil {
+	eventSourceDone, err := cp.eventSource.Start(ctx, registrationData, eventUpdates, connectionStates, receiveErrors)
+	if err != nil {
 		return err
 	}
+	stopped = append(stopped, eventSourceDone)
 	cp.logger.Debugf("Event source started with data: %+v", registrationData)
 	cp.logger.Debugf("Starting subscription source for integration ID %s", cp.integrationID)
-	if err := cp.subscriptionSource.Start(ctx, registrationData, subscriptionUpdates, wg); err != nil {
+	subscriptionSourceDone, err := cp.subscriptionSource.Start(ctx, registrationData, subscriptionUpdates, subscriptionErrors)
+	if err != nil {
 		return err
 	}
+	stopped = append(stopped, subscriptionSourceDone)
 	cp.logger.Debug("Subscription source started")
-	cp.registered = true
+	if cp.eventSender, err = cp.resolveSender(); err != nil {
+		return err
+	}
+	var events <-chan types.EventUpdate = eventUpdates
+	if len(cp.eventPriorities) > 0 {
+		var prioritizationDone <-chan struct{}
+		events, prioritizationDone = cp.runPrioritization(ctx, eventUpdates)
+		stopped = append(stopped, prioritizationDone)
+	}
+	if cp.selfCheckInterval > 0 {
+		stopped = append(stopped, cp.runSelfCheck(ctx))
+	}
+	cp.auditRecords = nil
+	if cp.auditSink != nil {
+		stopped = append(stopped, cp.runAuditSink(ctx))
+	}
+	if cp.livenessInterval > 0 && cp.livenessEventFn != nil {
+		stopped = append(stopped, cp.runLivenessEvents(ctx))
+	}
+	cp.eventLimit = newEventLimit(cp.maxEventsHandled)
+	cp.storm = newStormDetector(cp.stormRate, cp.stormWindow)
+	var batchIntegration BatchIntegration
+	cp.batch = nil
+	if cp.batchSize > 0 {
+		if bi, ok := integration.(BatchIntegration); ok {
+			batchIntegration = bi
+			cp.batch = newEventBatch(cp.clock, cp.batchSize, cp.batchWindow)
+			defer cp.abandonBatch(ctx)
+		} else {
+			cp.logger.Warn("Integration does not implement BatchIntegration, events are forwarded one by one")
+		}
+	}
+	if len(cp.orderedSubjects) > 0 {
+		cp.orderedQueues = newOrderedQueues(ctx, integration)
+		stopped = append(stopped, cp.orderedQueues.stopped())
+	}
+	// requested subjects not being part of a subscription update yet. If the server does not activate some of
+	// them, a warning is logged once the activation timeout expires
+	pending := subjectSet(registrationData.Subscriptions)
+	var activationTimeout <-chan time.Time
+	if len(pending) > 0 && cp.activationTimeout > 0 {
+		timer := cp.clock.Timer(cp.activationTimeout)
+		defer timer.Stop()
+		activationTimeout = timer.C
+	}
+	// the latest subscription update waiting for the debounce interval to expire
+	var debounced []models.EventSubscription
+	var debounceTimer *clock.Timer
+	var debounceExpired <-chan time.Time
+	defer func() {
+		if debounceTimer != nil {
+			debounceTimer.Stop()
+		}
+	}()
+	cp.warmup = nil
+	if cp.warmupBufferSize > 0 {
+		if wi, ok := integration.(WarmupIntegration); ok {
+			cp.warmup = newWarmup(cp.warmupBufferSize)
+			defer cp.abandonWarmup(ctx)
+			go wi.Warmup(ctx, cp.warmup.signalReady)
+		} else {
+			cp.logger.Warn("Integration does not implement WarmupIntegration, events are forwarded without warmup")
+		}
+	}
+	defer cp.tasks.reset()
+	cp.unmatched = nil
+	if cp.unmatchedBufferSize > 0 && cp.unmatchedTTL > 0 {
+		cp.unmatched = newUnmatchedEvents(cp.clock, cp.unmatchedBufferSize, cp.unmatchedTTL)
+		defer cp.abandonUnmatched(ctx)
+	}
+	cp.setRegistered(true)
+	registered()
+
+	// Received events are handled by a dedicated goroutine, so a slow integration does not hold back
+	// subscription updates. The goroutine is stopped before the state it owns (e.g. the batch) is abandoned
+	handoff := make(chan types.EventUpdate)
+	handled := make(chan struct{})
+	rematch := make(chan struct{}, 1)
+	handlingResult := make(chan error, 1)
+	handlingDone := make(chan struct{})
+	go func() {
+		defer close(handlingDone)
+		handlingResult <- cp.handleEvents(ctx, handoff, handled, rematch, integration, batchIntegration)
+	}()
+	defer func() {
+		close(handoff)
+		<-handlingDone
+	}()
+	// the received event waiting to be taken over by the handling goroutine. No further event is received until
+	// the handling of the event completed, but subscription updates are still applied in the meantime
+	var next types.EventUpdate
+	var receive <-chan types.EventUpdate = events
+	var handOver chan<- types.EventUpdate
 	for {
 		select {
-		case event := <-eventUpdates:
-			cp.logger.Debug("New updates event")
-			err := cp.handle(ctx, event, integration)
-			if errors.Is(err, ErrEventHandleFatal) {
-				return err
+		case received := <-receive:
+			next, receive, handOver = received, nil, handoff
+		case handOver <- next:
+			next, handOver = types.EventUpdate{}, nil
+		case <-handled:
+			receive = events
+		case err := <-handlingResult:
+			// the handling stopped before the received event was taken over, so it is redelivered
+			if handOver != nil {
+				cp.abandonReceived(ctx, next)
 			}
+			return err
 		case subscriptions := <-subscriptionUpdates:
 			cp.logger.Debugf("ControlPlane: Got a subscription update with %d subscriptions", len(subscriptions))
-			cp.currentSubscriptions = subscriptions
-			cp.eventSource.OnSubscriptionUpdate(subjects(subscriptions))
-			cp.logger.Debug("Update successful")
+			if cp.subscriptionDebounce <= 0 {
+				cp.applySubscriptions(subscriptions, pending)
+				requestRematch(rematch)
+				break
+			}
+			// restart the debounce interval, the previous update is superseded
+			if debounceTimer != nil {
+				debounceTimer.Stop()
+			}
+			debounced = subscriptions
+			debounceTimer = cp.clock.Timer(cp.subscriptionDebounce)
+			debounceExpired = debounceTimer.C
+		case <-debounceExpired:
+			debounceExpired = nil
+			cp.applySubscriptions(debounced, pending)
+			requestRematch(rematch)
+		case <-activationTimeout:
+			if len(pending) > 0 {
+				cp.logger.Warnf("Requested subscriptions did not become active within %s: %s", cp.activationTimeout, strings.Join(sortedKeys(pending), ", "))
+			}
+		case err := <-subscriptionErrors:
+			cp.logger.Warnf("Subscription source reported an error: %v", err)
+			if cp.subscriptionErrorFn != nil {
+				cp.subscriptionErrorFn(err)
+			}
+		case err := <-receiveErrors:
+			cp.logger.Warnf("Event source could not receive an event: %v", err)
+			cp.metrics.observeReceiveError()
+			if cp.receiveErrorFn != nil {
+				cp.receiveErrorFn(err)
+			}
+		case state := <-connectionStates:
+			cp.logger.Infof("Connection state of event source changed to %s", state)
+			cp.metrics.observeConnectionState(state)
+			if cp.connectionStateFn != nil {
+				cp.connectionStateFn(state)
+			}
 		case <-ctx.Done():
 			cp.logger.Debug("Unregistering")
-			wg.Wait()
-			cp.registered = false
+			// an event that was already received is still handed over, e.g. to be abandoned together with the batch
+			if handOver != nil {
+				select {
+				case handOver <- next:
+				case <-handlingDone:
+				}
+			}
 			return nil
 		}
 	}
 }
 
+// handleEvents handles the events handed over on the given channel until the channel is closed, the maximum
+// number of events was handled or handling an event failed fatally. Once the handling of an event completed, a
+// signal is sent on the handled channel. The held back events are matched again whenever a signal is received on
+// the rematch channel.
+// The batch, the warmup buffer, the held back events and the ordered queues are owned by this goroutine
+func (cp *ControlPlane) handleEvents(ctx context.Context, handoff <-chan types.EventUpdate, handled chan<- struct{}, rematch <-chan struct{}, integration Integration, batchIntegration BatchIntegration) error {
+	for {
+		select {
+		case received, ok := <-handoff:
+			if !ok {
+				return nil
+			}
+			if stop, err := cp.handleReceived(ctx, received, integration, batchIntegration); stop {
+				return err
+			}
+			select {
+			case handled <- struct{}{}:
+			case <-ctx.Done():
+				return nil
+			}
+		case <-rematch:
+			if err := cp.rematchUnmatched(ctx, integration, batchIntegration); err != nil {
+				return err
+			}
+		case <-cp.warmup.ready():
+			buffered := cp.warmup.release()
+			cp.logger.Infof("Integration signaled ready, forwarding %d buffered events", len(buffered))
+			for _, event := range buffered {
+				if err := cp.processEvent(ctx, event, integration, batchIntegration); err != nil {
+					return err
+				}
+			}
+		case <-cp.batch.expired():
+			if err := cp.flushBatch(ctx, batchIntegration); errors.Is(err, ErrEventHandleFatal) {
+				return err
+			}
+		case <-cp.eventLimit.done():
+			cp.logger.Infof("Handled the maximum number of %d events, stopping", cp.maxEventsHandled)
+			return nil
+		case <-cp.unmatched.expired():
+			cp.dropExpiredUnmatched(ctx)
+		case <-cp.orderedQueues.failed():
+			return cp.orderedQueues.fatalErr
+		}
+	}
+}
+
+// handleReceived handles the events of a received update. It returns true if the handling of further events
+// has to stop, because the maximum number of events was handled or handling an event failed fatally
+func (cp *ControlPlane) handleReceived(ctx context.Context, received types.EventUpdate, integration Integration, batchIntegration BatchIntegration) (bool, error) {
+	events := unpackBatch(received)
+	for i, event := range events {
+		// the limit may have been reached while the event was received, it is redelivered
+		if cp.eventLimit.isReached() {
+			cp.logger.Infof("Handled the maximum number of %d events, stopping", cp.maxEventsHandled)
+			cp.abandonReceived(ctx, types.EventUpdate{Batch: events[i:]})
+			return true, nil
+		}
+		cp.logger.Debug("New updates event")
+		cp.stats.observeReceived()
+		if cp.shedDuringStorm(ctx, event) {
+			continue
+		}
+		if cp.bufferDuringWarmup(ctx, event) {
+			continue
+		}
+		if err := cp.processEvent(ctx, event, integration, batchIntegration); err != nil {
+			return true, err
+		}
+	}
+	return false, nil
+}
+
+// abandonReceived requests the redelivery of the events of a received update that are not handled, because the
+// handling of events stopped
+func (cp *ControlPlane) abandonReceived(ctx context.Context, received types.EventUpdate) {
+	for _, event := range unpackBatch(received) {
+		cp.acknowledge(ctx, event, errEventAbandoned)
+	}
+}
+
+// requestRematch signals the handling goroutine to match the held back events again. Signals
+// that were not picked up yet are coalesced
+func requestRematch(rematch chan<- struct{}) {
+	select {
+	case rematch <- struct{}{}:
+	default:
+	}
+}
+
+// processEvent handles and acknowledges a received event. Only fatal errors are returned
+func (cp *ControlPlane) processEvent(ctx context.Context, event types.EventUpdate, integration Integration, batchIntegration BatchIntegration) error {
+	ctx = withResponseRecorder(ctx, event)
+	start := cp.clock.Now()
+	err := cp.dispatch(ctx, func(ctx context.Context) error { return cp.handle(ctx, event, integration) })
+	if errors.Is(err, errEventBatched) {
+		// batched events are acknowledged together with their batch
+		if cp.batch.full() {
+			if err := cp.flushBatch(ctx, batchIntegration); errors.Is(err, ErrEventHandleFatal) {
+				return err
+			}
+		}
+		return nil
+	}
+	if errors.Is(err, errEventHeldBack) {
+		// held back events are acknowledged once they were matched again or dropped
+		return nil
+	}
+	cp.acknowledgeHandled(ctx, event, err, cp.clock.Since(start))
+	if errors.Is(err, ErrEventHandleFatal) {
+		return err
+	}
+	return nil
+}
+
+// Deregister removes the registration of the integration from the Keptn control plane, e.g. when a replica is
+// shut down. Deregister does not stop the ControlPlane from receiving events, this is done by cancelling the
+// context given to Register. If the integration is not registered, Deregister does nothing
+func (cp *ControlPlane) Deregister(ctx context.Context) error {
+	cp.registrationMtx.Lock()
+	defer cp.registrationMtx.Unlock()
+	if cp.registrationID == "" {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("could not unregister integration: %w", err)
+	}
+	cp.logger.Debugf("Unregistering integration ID %s", cp.registrationID)
+	if err := cp.subscriptionSource.Unregister(cp.registrationID); err != nil {
+		return fmt.Errorf("could not unregister integration: %w", err)
+	}
+	cp.registrationID = ""
+	return nil
+}
+
+// RefreshSubscriptions makes the subscription source fetch the current subscriptions right away instead of waiting
+// for the next regular update, e.g. after an operator changed the subscriptions in Keptn. It returns once the
+// subscriptions were passed to the ControlPlane. Subscriptions are applied while events are being handled, so
+// RefreshSubscriptions can be called from within OnEvent. Only if WithPausedDispatchOnUpdate is used, each update
+// waits for the events being handled, so a call from within OnEvent blocks until the given context is done if
+// another update is waiting to be applied at the same time
+func (cp *ControlPlane) RefreshSubscriptions(ctx context.Context) error {
+	if err := cp.subscriptionSource.Fetch(ctx); err != nil {
+		return fmt.Errorf("could not refresh subscriptions: %w", err)
+	}
+	return nil
+}
+
 // IsRegistered can be called to detect whether the controlPlane is registered and ready to receive events
 func (cp *ControlPlane) IsRegistered() bool {
+	cp.registeredMtx.Lock()
+	defer cp.registeredMtx.Unlock()
 	return cp.registered
 }
 
+// WaitUntilRegistered blocks until the integration is registered and receiving events.
+// An error is returned if the given context is done before
+func (cp *ControlPlane) WaitUntilRegistered(ctx context.Context) error {
+	cp.registeredMtx.Lock()
+	registered := cp.registeredCh
+	cp.registeredMtx.Unlock()
+	select {
+	case <-registered:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("integration did not register: %w", ctx.Err())
+	}
+}
+
+func (cp *ControlPlane) setRegistered(registered bool) {
+	cp.registeredMtx.Lock()
+	defer cp.registeredMtx.Unlock()
+	if registered == cp.registered {
+		return
+	}
+	cp.registered = registered
+	if registered {
+		close(cp.registeredCh)
+	} else {
+		cp.registeredCh = make(chan struct{})
+	}
+}
+
+// EventMetaDataFromContext returns the transport metadata (e.g. subject and delivery count) of the event that is
+// currently handled. The second return value is false if the context does not belong to a received event
+func EventMetaDataFromContext(ctx context.Context) (types.EventUpdateMetaData, bool) {
+	metaData, ok := ctx.Value(types.EventMetaDataKey).(types.EventUpdateMetaData)
+	return metaData, ok
+}
+
+// CorrelationIDFromContext returns the correlation id of the event that is currently handled.
+// The correlation id is included in all log entries of the ControlPlane concerning the event.
+// An empty string is returned if the context was not passed by the ControlPlane
+func CorrelationIDFromContext(ctx context.Context) string {
+	correlationID, _ := ctx.Value(types.CorrelationIDKey).(string)
+	return correlationID
+}
+
+// correlationID returns the id of the event, or its keptn context if the event has no id.
+// If neither is available, a new id is generated
+func correlationID(event models.KeptnContextExtendedCE) string {
+	if event.ID != "" {
+		return event.ID
+	}
+	if event.Shkeptncontext != "" {
+		return event.Shkeptncontext
+	}
+	return uuid.New().String()
+}
+
+// eventLogger returns a logger including the correlation id of the given context in every log entry
+func (cp *ControlPlane) eventLogger(ctx context.Context) logger.Logger {
+	if correlationID := CorrelationIDFromContext(ctx); correlationID != "" {
+		return logger.NewPrefixedLogger(cp.logger, "[correlation-id="+correlationID+"]")
+	}
+	return cp.logger
+}
+
 func (cp *ControlPlane) handle(ctx context.Context, eventUpdate types.EventUpdate, integration Integration) error {
-	cp.logger.Debugf("Received an event of type: %s", eventUpdate.KeptnEvent.Type)
-	for _, subscription := range cp.currentSubscriptions {
-		if subscription.Event == eventUpdate.MetaData.Subject {
-			cp.logger.Debugf("Check if event matches subscription %s", subscription.ID)
-			matcher := eventmatcher.New(subscription)
-			if matcher.Matches(eventUpdate.KeptnEvent) {
-				cp.logger.Info("Forwarding matched event update: ", eventUpdate.KeptnEvent.ID)
-				if err := cp.forwardMatchedEvent(ctx, eventUpdate, integration, subscription); err != nil {
-					return err
-				}
+	ctx = context.WithValue(ctx, types.CorrelationIDKey, correlationID(eventUpdate.KeptnEvent))
+	ctx = context.WithValue(ctx, types.EventMetaDataKey, eventUpdate.MetaData)
+	log := cp.eventLogger(ctx)
+	log.Debugf("Received an event of type: %s", eventType(eventUpdate.KeptnEvent))
+	if eventUpdate.KeptnEvent.Shkeptncontext == "" {
+		switch cp.missingContext {
+		case DropMissingContext:
+			log.Warnf("Dropping event %s: event has no keptn context", eventUpdate.KeptnEvent.ID)
+			cp.dropped(eventUpdate.KeptnEvent, DropReasonMissingContext)
+			return nil
+		case SynthesizeMissingContext:
+			eventUpdate.KeptnEvent.Shkeptncontext = uuid.New().String()
+			log.Infof("Event %s has no keptn context, assigned keptn context %s", eventUpdate.KeptnEvent.ID, eventUpdate.KeptnEvent.Shkeptncontext)
+		}
+	}
+	if cp.IsMuted(eventUpdate.MetaData.Subject) {
+		log.Debugf("Dropping event %s: subject %s is muted", eventUpdate.KeptnEvent.ID, eventUpdate.MetaData.Subject)
+		cp.dropped(eventUpdate.KeptnEvent, DropReasonMuted)
+		return nil
+	}
+	if !cp.isActive() {
+		log.Debugf("Dropping event %s: event was received outside of the active windows", eventUpdate.KeptnEvent.ID)
+		cp.dropped(eventUpdate.KeptnEvent, DropReasonOutsideActiveWindow)
+		return nil
+	}
+	if cp.ignoreSelfEvents && cp.isSelfEvent(eventUpdate.KeptnEvent) {
+		log.Debugf("Dropping event %s: event was produced by the integration itself", eventUpdate.KeptnEvent.ID)
+		cp.dropped(eventUpdate.KeptnEvent, DropReasonSelfEvent)
+		return nil
+	}
+	if size, ok := cp.exceedsMaxEventSize(eventUpdate); ok {
+		log.Warnf("Dropping event %s: size of %d bytes exceeds the maximum event size of %d bytes", eventUpdate.KeptnEvent.ID, size, cp.maxEventSize)
+		cp.dropped(eventUpdate.KeptnEvent, DropReasonMaxSizeExceeded)
+		return nil
+	}
+	if !cp.sampled(eventUpdate) {
+		log.Debugf("Dropping event %s: event is not part of the sample of subject %s", eventUpdate.KeptnEvent.ID, eventUpdate.MetaData.Subject)
+		cp.dropped(eventUpdate.KeptnEvent, DropReasonNotSampled)
+		return nil
+	}
+	if cp.seenBefore(log, eventUpdate.KeptnEvent.ID) {
+		log.Debugf("Dropping event %s: event was already handled", eventUpdate.KeptnEvent.ID)
+		cp.dropped(eventUpdate.KeptnEvent, DropReasonDuplicate)
+		return nil
+	}
+	var handlingErr error
+	var failedSubscriptions []models.EventSubscription
+	var batched []models.KeptnContextExtendedCE
+	matchedSubscriptions, subjectMatched := cp.matchSubscriptions(log, eventUpdate.MetaData.Subject, eventUpdate.KeptnEvent)
+	if len(matchedSubscriptions) > 0 && cp.filterExpression != nil && !cp.filterExpression.Matches(eventUpdate.KeptnEvent) {
+		log.Debugf("Dropping event %s: event does not match the filter expression %s", eventUpdate.KeptnEvent.ID, cp.filterExpression)
+		cp.dropped(eventUpdate.KeptnEvent, DropReasonFilterExpressionMismatch)
+		return nil
+	}
+	switch {
+	case len(matchedSubscriptions) > 0:
+		cp.stats.observeMatched()
+	case subjectMatched:
+		cp.dropped(eventUpdate.KeptnEvent, DropReasonFilterMismatch)
+	case cp.holdBackUnmatched(eventUpdate):
+		return errEventHeldBack
+	default:
+		cp.dropped(eventUpdate.KeptnEvent, DropReasonNoSubscription)
+	}
+	for i, subscription := range matchedSubscriptions {
+		// the .started event is sent only once, even if the event matches multiple subscriptions
+		if cp.autoStarted && i == 0 {
+			cp.sendStartedEvent(ctx, eventUpdate.KeptnEvent, recordingSender(ctx, cp.senderFor(eventUpdate.KeptnEvent)))
+		}
+		if cp.isOrdered(subscription) {
+			log.Info("Queueing matched event update: ", eventUpdate.KeptnEvent.ID)
+			cp.pushOrdered(ctx, eventUpdate, subscription)
+			continue
+		}
+		if cp.batch != nil {
+			log.Info("Batching matched event update: ", eventUpdate.KeptnEvent.ID)
+			if event, ok := cp.prepareEvent(log, eventUpdate.KeptnEvent, subscription); ok {
+				batched = append(batched, event)
+			}
+			continue
+		}
+		log.Info("Forwarding matched event update: ", eventUpdate.KeptnEvent.ID)
+		if err := cp.forwardMatchedEvent(ctx, eventUpdate, integration, subscription); err != nil {
+			if errors.Is(err, ErrEventHandleFatal) {
+				return err
 			}
+			handlingErr = err
+			failedSubscriptions = append(failedSubscriptions, subscription)
 		}
 	}
+	if len(batched) > 0 {
+		// batched events are marked as seen once their batch was handled successfully
+		cp.batch.add(eventUpdate, batched)
+		return errEventBatched
+	}
+	if handlingErr != nil {
+		return &subscriptionFailure{subscriptions: failedSubscriptions, err: handlingErr}
+	}
+	// events that failed are not marked as seen, so their redelivery is forwarded again
+	if len(matchedSubscriptions) > 0 {
+		cp.markSeen(log, eventUpdate.KeptnEvent.ID)
+	}
 	return nil
 }
 
+// exceedsMaxEventSize checks whether the event is larger than the configured maximum event size and returns its size
+func (cp *ControlPlane) exceedsMaxEventSize(eventUpdate types.EventUpdate) (int, bool) {
+	if cp.maxEventSize <= 0 {
+		return 0, false
+	}
+	size := eventUpdate.MetaData.Size
+	if size == 0 {
+		serialized, err := json.Marshal(eventUpdate.KeptnEvent)
+		if err != nil {
+			return 0, false
+		}
+		size = len(serialized)
+	}
+	return size, size > cp.maxEventSize
+}
+
+// sampled checks whether the event is part of the sample of its subject. The event ID is hashed
+// to a value between 0 and 1, which is compared to the sampling ratio of the subject
+func (cp *ControlPlane) sampled(eventUpdate types.EventUpdate) bool {
+	ratio, ok := cp.samplingRatios[eventUpdate.MetaData.Subject]
+	if !ok {
+		return true
+	}
+	hash := sha256.Sum256([]byte(eventUpdate.KeptnEvent.ID))
+	return float64(binary.BigEndian.Uint64(hash[:8]))/math.MaxUint64 < ratio
+}
+
+func (cp *ControlPlane) isSelfEvent(event models.KeptnContextExtendedCE) bool {
+	if event.Source == nil || *event.Source == "" {
+		return false
+	}
+	return *event.Source == cp.integrationID || *event.Source == cp.integrationName
+}
+
+// MatchSubscriptions returns the currently active subscriptions the given event would be forwarded for,
+// without forwarding the event. The subject of the event is derived from its type
+func (cp *ControlPlane) MatchSubscriptions(event models.KeptnContextExtendedCE) []models.EventSubscription {
+	matched, _ := cp.matchSubscriptions(cp.logger, eventType(event), event)
+	return matched
+}
+
+// matchSubscriptions returns the subscriptions matching the event and whether any subscription exists for the subject
+func (cp *ControlPlane) matchSubscriptions(log logger.Logger, subject string, event models.KeptnContextExtendedCE) ([]models.EventSubscription, bool) {
+	matched := []models.EventSubscription{}
+	subjectMatched := false
+	for _, subscription := range cp.subscriptionSnapshot() {
+		if cp.matchesSubject(subscription, subject) || cp.matchesTypePattern(subscription, event) {
+			subjectMatched = true
+			log.Debugf("Check if event matches subscription %s", subscription.ID)
+			if eventmatcher.New(subscription).Matches(event) {
+				matched = append(matched, subscription)
+			}
+		}
+	}
+	return matched, subjectMatched
+}
+
+// matchesSubject checks whether the event type or the mapped subject of the subscription equals the given subject,
+// comparing the normalized subjects if a SubjectNormalizerFn is configured
+func (cp *ControlPlane) matchesSubject(subscription models.EventSubscription, subject string) bool {
+	if subscription.Event == subject || cp.subject(subscription) == subject {
+		return true
+	}
+	if cp.subjectNormalizer == nil {
+		return false
+	}
+	normalized := cp.subjectNormalizer(subject)
+	return cp.subjectNormalizer(subscription.Event) == normalized || cp.subjectNormalizer(cp.subject(subscription)) == normalized
+}
+
+// matchesTypePattern checks whether the type of the event matches the event of the subscription as pattern,
+// if type pattern matching is enabled
+func (cp *ControlPlane) matchesTypePattern(subscription models.EventSubscription, event models.KeptnContextExtendedCE) bool {
+	return cp.typePatternMatching && matchSubject(subscription.Event, eventType(event))
+}
+
+// subscriptionSnapshot returns the subscriptions currently used for matching events. The returned slice is
+// replaced as a whole by subscription updates and never modified, so it can be used without holding the lock
+func (cp *ControlPlane) subscriptionSnapshot() []models.EventSubscription {
+	cp.subscriptionsMtx.RLock()
+	defer cp.subscriptionsMtx.RUnlock()
+	return cp.currentSubscriptions
+}
+
+// applySubscriptions passes the subscriptions to the event source and uses them for matching events.
+// The subjects of the subscriptions are removed from the given set of pending subjects
+func (cp *ControlPlane) applySubscriptions(subscriptions []models.EventSubscription, pending map[string]struct{}) {
+	if cp.pauseOnUpdate {
+		// no event is matched until both the ControlPlane and the event source use the new subscriptions
+		cp.reload.dispatchMtx.Lock()
+		defer cp.reload.dispatchMtx.Unlock()
+	}
+	previous := cp.updateSubscriptions(subscriptions)
+	cp.eventSource.OnSubscriptionUpdate(cp.subjects(subscriptions))
+	cp.logger.Debug("Update successful")
+	if cp.subscriptionDiffFn != nil {
+		if added, removed := diffSubscriptions(previous, subscriptions); len(added) > 0 || len(removed) > 0 {
+			cp.subscriptionDiffFn(added, removed)
+		}
+	}
+	for _, subscription := range subscriptions {
+		delete(pending, subscription.Event)
+	}
+}
+
+// updateSubscriptions replaces the current subscriptions with a copy of the given ones and returns the replaced ones.
+// The copy ensures that the subscriptions used for matching cannot be changed afterwards, e.g. by the subscription source
+func (cp *ControlPlane) updateSubscriptions(subscriptions []models.EventSubscription) []models.EventSubscription {
+	snapshot := append(make([]models.EventSubscription, 0, len(subscriptions)), subscriptions...)
+	cp.subscriptionsMtx.Lock()
+	defer cp.subscriptionsMtx.Unlock()
+	cp.metrics.observeSubscriptionUpdate(len(snapshot), !subscriptionsEqual(cp.currentSubscriptions, snapshot))
+	previous := cp.currentSubscriptions
+	cp.currentSubscriptions = snapshot
+	return previous
+}
+
+// resolveSender returns the sender of the event source. If the event source does not provide a sender,
+// the configured MissingSenderBehavior is applied
+func (cp *ControlPlane) resolveSender() (types.EventSender, error) {
+	if cp.dryRunSender != nil {
+		return cp.dryRunSender, nil
+	}
+	if sender := cp.eventSource.Sender(); sender != nil {
+		return sender, nil
+	}
+	if cp.missingSender == NoOpOnMissingSender {
+		cp.logger.Warn("Event source does not provide a sender, events sent by the integration are dropped")
+		return func(ce models.KeptnContextExtendedCE) error {
+			cp.logger.Warnf("Dropping event of type %s: event source does not provide a sender", eventType(ce))
+			return nil
+		}, nil
+	}
+	return nil, fmt.Errorf("could not register integration: %w", ErrMissingSender)
+}
+
 func (cp *ControlPlane) getSender(sender types.EventSender) types.EventSender {
+	sender = cp.tasks.trackingSender(cp.limitSends(sender))
+	if len(cp.eventExtensions) > 0 {
+		send := sender
+		sender = func(ce models.KeptnContextExtendedCE) error {
+			return send(cp.addExtensions(ce))
+		}
+	}
 	if cp.logForwarder != nil {
 		return func(ce models.KeptnContextExtendedCE) error {
 			err := cp.logForwarder.Forward(ce, cp.integrationID)
@@ -157,33 +1273,198 @@ func (cp *ControlPlane) getSender(sender types.EventSender) types.EventSender {
 	}
 }
 
+// senderFor returns the sender for responses to the given event, which is selected by the configured
+// SenderSelectorFn and falls back to the sender of the event source. In dry-run mode, the dry-run sender is used
+func (cp *ControlPlane) senderFor(event models.KeptnContextExtendedCE) types.EventSender {
+	if cp.senderSelector != nil && cp.dryRunSender == nil {
+		if sender := cp.senderSelector(event); sender != nil {
+			return cp.getSender(sender)
+		}
+	}
+	return cp.getSender(cp.eventSender)
+}
+
 func (cp *ControlPlane) forwardMatchedEvent(ctx context.Context, eventUpdate types.EventUpdate, integration Integration, subscription models.EventSubscription) error {
-	err := eventUpdate.KeptnEvent.AddTemporaryData(
+	log := cp.eventLogger(ctx)
+	event, ok := cp.prepareEvent(log, eventUpdate.KeptnEvent, subscription)
+	if !ok {
+		return nil
+	}
+	eventUpdate.KeptnEvent = event
+	sender := recordingSender(ctx, cp.tagSubscription(cp.senderFor(eventUpdate.KeptnEvent), subscription.ID))
+	integrationCtx, cancel := cp.withHandlerTimeout(context.WithValue(ctx, types.EventSenderKey, sender), subscription)
+	defer cancel()
+	if cp.contextDecorator != nil {
+		integrationCtx = cp.contextDecorator(integrationCtx, eventUpdate.KeptnEvent)
+	}
+	cp.tasks.triggered(eventUpdate.KeptnEvent)
+	start := cp.clock.Now()
+	stopWatching := cp.dumpStacksOnTimeout(integrationCtx, log, eventUpdate.KeptnEvent.ID)
+	err := cp.callIntegrationWithRetry(integrationCtx, integration, eventUpdate.KeptnEvent)
+	stopWatching()
+	cp.audit(eventUpdate, subscription.ID, start, err)
+	cp.sendHandlingResult(ctx, eventUpdate, subscription.ID, cp.clock.Since(start), err)
+	cp.stats.observeHandled(err)
+	if err != nil {
+		if errors.Is(err, ErrEventHandleFatal) {
+			log.Errorf("Fatal error during handling of event: %v", err)
+			return err
+		}
+		log.Warnf("Error during handling of event: %v", err)
+		if cp.autoFinishedOnError {
+			cp.sendFinishedEvent(ctx, eventUpdate.KeptnEvent, keptnv2.ResultFailed, keptnv2.StatusErrored, err.Error(), sender)
+		}
+		if cp.errorEventOnFailure {
+			cp.sendErrorEvent(ctx, eventUpdate.KeptnEvent, err, sender)
+		}
+		return err
+	}
+	cp.eventLimit.observeHandled()
+	return nil
+}
+
+// prepareEvent adds the data of the matched subscription to the event and applies the event transformer.
+// It returns false if the event is dropped because it could not be transformed
+func (cp *ControlPlane) prepareEvent(log logger.Logger, event models.KeptnContextExtendedCE, subscription models.EventSubscription) (models.KeptnContextExtendedCE, bool) {
+	var subscriptionData interface{} = types.AdditionalSubscriptionData{
+		SubscriptionID: subscription.ID,
+	}
+	if cp.subscriptionDataFn != nil {
+		subscriptionData = cp.subscriptionDataFn(subscription)
+	}
+	err := event.AddTemporaryData(
 		tmpDataDistributorKey,
-		types.AdditionalSubscriptionData{
-			SubscriptionID: subscription.ID,
-		},
+		subscriptionData,
 		models.AddTemporaryDataOptions{
 			OverwriteIfExisting: true,
 		},
 	)
 	if err != nil {
-		cp.logger.Warnf("Could not append subscription data to event: %v", err)
+		log.Warnf("Could not append subscription data to event: %v", err)
 	}
-	if err := integration.OnEvent(context.WithValue(ctx, types.EventSenderKey, cp.getSender(cp.eventSource.Sender())), eventUpdate.KeptnEvent); err != nil {
-		if errors.Is(err, ErrEventHandleFatal) {
-			cp.logger.Errorf("Fatal error during handling of event: %v", err)
-			return err
+	if cp.eventTransformer != nil {
+		transformedEvent, err := cp.eventTransformer(event)
+		if err != nil {
+			log.Warnf("Dropping event %s: could not transform event: %v", event.ID, err)
+			cp.dropped(event, DropReasonTransformFailed)
+			return event, false
 		}
-		cp.logger.Warnf("Error during handling of event: %v", err)
+		event = transformedEvent
 	}
-	return nil
+	return event, true
+}
+
+// callIntegration passes the event to the integration. If auto finished events on errors are enabled,
+// a panic of the integration is recovered and returned as error
+func (cp *ControlPlane) callIntegration(ctx context.Context, integration Integration, event models.KeptnContextExtendedCE) (err error) {
+	if cp.autoFinishedOnError {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("integration panicked during handling of event %s: %v", event.ID, r)
+			}
+		}()
+	}
+	return cp.classifyFatal(integration.OnEvent(ctx, event))
+}
+
+// sendStartedEvent sends the .started event corresponding to the given .triggered event.
+// Events of any other kind are ignored
+func (cp *ControlPlane) sendStartedEvent(ctx context.Context, triggeredEvent models.KeptnContextExtendedCE, sender types.EventSender) {
+	cp.sendResponseEvent(ctx, triggeredEvent, "started", keptnv2.EventData{Status: keptnv2.StatusSucceeded}, sender)
+}
+
+// sendFinishedEvent sends the .finished event corresponding to the given .triggered event.
+// Events of any other kind are ignored
+func (cp *ControlPlane) sendFinishedEvent(ctx context.Context, triggeredEvent models.KeptnContextExtendedCE, result keptnv2.ResultType, status keptnv2.StatusType, message string, sender types.EventSender) {
+	cp.sendResponseEvent(ctx, triggeredEvent, "finished", keptnv2.EventData{Result: result, Status: status, Message: message}, sender)
+}
+
+// sendResponseEvent sends an event of the given kind as response to the given .triggered event.
+// Project, stage, service and labels of the response are taken from the .triggered event
+func (cp *ControlPlane) sendResponseEvent(ctx context.Context, triggeredEvent models.KeptnContextExtendedCE, kind string, data keptnv2.EventData, sender types.EventSender) {
+	log := cp.eventLogger(ctx)
+	if triggeredEvent.Type == nil || !keptnv2.IsTriggeredEventType(*triggeredEvent.Type) {
+		return
+	}
+	responseEventType, err := keptnv2.ReplaceEventTypeKind(*triggeredEvent.Type, kind)
+	if err != nil {
+		log.Warnf("Could not determine .%s event type for event %s: %v", kind, triggeredEvent.ID, err)
+		return
+	}
+	triggeredData := keptnv2.EventData{}
+	if err := keptnv2.EventDataAs(triggeredEvent, &triggeredData); err != nil {
+		log.Warnf("Not sending %s event: could not decode data of event %s: %v", responseEventType, triggeredEvent.ID, err)
+		return
+	}
+	data.Project = triggeredData.Project
+	data.Stage = triggeredData.Stage
+	data.Service = triggeredData.Service
+	data.Labels = triggeredData.Labels
+	responseEvent := keptnv2.KeptnEvent(responseEventType, cp.integrationName, data).
+		WithKeptnContext(triggeredEvent.Shkeptncontext).
+		WithTriggeredID(triggeredEvent.ID).
+		KeptnContextExtendedCE
+	log.Debugf("Sending %s event for event %s", responseEventType, triggeredEvent.ID)
+	if err := sender(responseEvent); err != nil {
+		log.Warnf("Could not send %s event: %v", responseEventType, err)
+	}
+}
+
+// subscriptionsEqual checks whether two lists of subscriptions have the same content,
+// treating nil and empty lists as equal
+func subscriptionsEqual(a []models.EventSubscription, b []models.EventSubscription) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !reflect.DeepEqual(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// unpackBatch returns the events of the given update, which is either the update itself or the events of its batch
+func unpackBatch(update types.EventUpdate) []types.EventUpdate {
+	if len(update.Batch) == 0 {
+		return []types.EventUpdate{update}
+	}
+	var events []types.EventUpdate
+	for _, e := range update.Batch {
+		events = append(events, unpackBatch(e)...)
+	}
+	return events
+}
+
+func subjectSet(subscriptions []models.EventSubscription) map[string]struct{} {
+	set := map[string]struct{}{}
+	for _, s := range subscriptions {
+		set[s.Event] = struct{}{}
+	}
+	return set
 }
 
-func subjects(subscriptions []models.EventSubscription) []string {
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// subjects returns the subjects the event source needs to subscribe to for the given subscriptions
+func (cp *ControlPlane) subjects(subscriptions []models.EventSubscription) []string {
 	var ret []string
 	for _, s := range subscriptions {
-		ret = append(ret, s.Event)
+		ret = append(ret, cp.subject(s))
 	}
 	return ret
 }
+
+func (cp *ControlPlane) subject(subscription models.EventSubscription) string {
+	if cp.subjectMapper != nil {
+		return cp.subjectMapper(subscription)
+	}
+	return subscription.Event
+}
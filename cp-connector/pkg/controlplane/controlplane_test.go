@@ -2,17 +2,27 @@ package controlplane
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	fake2 "github.com/keptn/keptn/cp-connector/pkg/fake"
+	"github.com/keptn/keptn/cp-connector/pkg/subscriptionsource"
 	"github.com/keptn/keptn/cp-connector/pkg/types"
 	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/benbjohnson/clock"
+	"github.com/google/uuid"
 	"github.com/keptn/go-utils/pkg/api/models"
 	"github.com/keptn/go-utils/pkg/common/strutils"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
 )
 
 type ExampleIntegration struct {
@@ -69,8 +79,8 @@ func TestControlPlaneEventSourceFailsToStart(t *testing.T) {
 		},
 	}
 	esm := &fake2.EventSourceMock{
-		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, wg *sync.WaitGroup) error {
-			return fmt.Errorf("error occured")
+		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, states chan types.ConnectionState, errC chan error) (<-chan struct{}, error) {
+			return nil, fmt.Errorf("error occured")
 		}}
 	fm := &LogForwarderMock{
 		ForwardFn: func(keptnEvent models.KeptnContextExtendedCE, integrationID string) error {
@@ -84,15 +94,15 @@ func TestControlPlaneEventSourceFailsToStart(t *testing.T) {
 
 func TestControlPlaneSubscriptionSourceFailsToStart(t *testing.T) {
 	ssm := &fake2.SubscriptionSourceMock{
-		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, wg *sync.WaitGroup) error {
-			return fmt.Errorf("error occured")
+		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, errC chan error) (<-chan struct{}, error) {
+			return nil, fmt.Errorf("error occured")
 		},
 		RegisterFn: func(integration models.Integration) (string, error) {
 			return "some-id", nil
 		},
 	}
-	esm := &fake2.EventSourceMock{StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, wg *sync.WaitGroup) error {
-		return nil
+	esm := &fake2.EventSourceMock{StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, states chan types.ConnectionState, errC chan error) (<-chan struct{}, error) {
+		return stopOnCancel(ctx), nil
 	}}
 	fm := &LogForwarderMock{
 		ForwardFn: func(keptnEvent models.KeptnContextExtendedCE, integrationID string) error {
@@ -113,18 +123,18 @@ func TestControlPlaneInboundEventIsForwardedToIntegration(t *testing.T) {
 	callBackSender := func(ce models.KeptnContextExtendedCE) error { return nil }
 
 	ssm := &fake2.SubscriptionSourceMock{
-		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, wg *sync.WaitGroup) error {
+		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, errC chan error) (<-chan struct{}, error) {
 			subsChan = c
-			return nil
+			return stopOnCancel(ctx), nil
 		},
 		RegisterFn: func(integration models.Integration) (string, error) {
 			return "some-id", nil
 		},
 	}
 	esm := &fake2.EventSourceMock{
-		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, wg *sync.WaitGroup) error {
+		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, states chan types.ConnectionState, errC chan error) (<-chan struct{}, error) {
 			eventChan = ces
-			return nil
+			return stopOnCancel(ctx), nil
 		},
 		OnSubscriptionUpdateFn: func(strings []string) {},
 		SenderFn:               func() types.EventSender { return callBackSender },
@@ -178,18 +188,18 @@ func TestControlPlaneInboundEventIsForwardedToIntegrationWithoutLogForwarder(t *
 	callBackSender := func(ce models.KeptnContextExtendedCE) error { return nil }
 
 	ssm := &fake2.SubscriptionSourceMock{
-		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, wg *sync.WaitGroup) error {
+		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, errC chan error) (<-chan struct{}, error) {
 			subsChan = c
-			return nil
+			return stopOnCancel(ctx), nil
 		},
 		RegisterFn: func(integration models.Integration) (string, error) {
 			return "some-id", nil
 		},
 	}
 	esm := &fake2.EventSourceMock{
-		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, wg *sync.WaitGroup) error {
+		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, states chan types.ConnectionState, errC chan error) (<-chan struct{}, error) {
 			eventChan = ces
-			return nil
+			return stopOnCancel(ctx), nil
 		},
 		OnSubscriptionUpdateFn: func(strings []string) {},
 		SenderFn:               func() types.EventSender { return callBackSender },
@@ -238,24 +248,24 @@ func TestControlPlaneIntegrationIDIsForwarded(t *testing.T) {
 	callBackSender := func(ce models.KeptnContextExtendedCE) error { return nil }
 
 	ssm := &fake2.SubscriptionSourceMock{
-		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, wg *sync.WaitGroup) error {
+		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, errC chan error) (<-chan struct{}, error) {
 			if data.ID != "some-other-id" {
-				return fmt.Errorf("error occured")
+				return nil, fmt.Errorf("error occured")
 			}
 			subsChan = c
-			return nil
+			return stopOnCancel(ctx), nil
 		},
 		RegisterFn: func(integration models.Integration) (string, error) {
 			return "some-other-id", nil
 		},
 	}
 	esm := &fake2.EventSourceMock{
-		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, wg *sync.WaitGroup) error {
+		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, states chan types.ConnectionState, errC chan error) (<-chan struct{}, error) {
 			if data.ID != "some-other-id" {
-				return fmt.Errorf("error occured")
+				return nil, fmt.Errorf("error occured")
 			}
 			eventChan = ces
-			return nil
+			return stopOnCancel(ctx), nil
 		},
 		OnSubscriptionUpdateFn: func(strings []string) {},
 		SenderFn:               func() types.EventSender { return callBackSender },
@@ -308,18 +318,18 @@ func TestControlPlaneIntegrationOnEventThrowsIgnoreableError(t *testing.T) {
 	callBackSender := func(ce models.KeptnContextExtendedCE) error { return nil }
 
 	ssm := &fake2.SubscriptionSourceMock{
-		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, wg *sync.WaitGroup) error {
+		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, errC chan error) (<-chan struct{}, error) {
 			subsChan = c
-			return nil
+			return stopOnCancel(ctx), nil
 		},
 		RegisterFn: func(integration models.Integration) (string, error) {
 			return "some-id", nil
 		},
 	}
 	esm := &fake2.EventSourceMock{
-		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, wg *sync.WaitGroup) error {
+		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, states chan types.ConnectionState, errC chan error) (<-chan struct{}, error) {
 			eventChan = ces
-			return nil
+			return stopOnCancel(ctx), nil
 		},
 		OnSubscriptionUpdateFn: func(strings []string) {},
 		SenderFn:               func() types.EventSender { return callBackSender },
@@ -359,18 +369,18 @@ func TestControlPlaneIntegrationOnEventThrowsFatalError(t *testing.T) {
 	callBackSender := func(ce models.KeptnContextExtendedCE) error { return nil }
 
 	ssm := &fake2.SubscriptionSourceMock{
-		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, wg *sync.WaitGroup) error {
+		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, errC chan error) (<-chan struct{}, error) {
 			subsChan = c
-			return nil
+			return stopOnCancel(ctx), nil
 		},
 		RegisterFn: func(integration models.Integration) (string, error) {
 			return "some-id", nil
 		},
 	}
 	esm := &fake2.EventSourceMock{
-		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, wg *sync.WaitGroup) error {
+		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, states chan types.ConnectionState, errC chan error) (<-chan struct{}, error) {
 			eventChan = ces
-			return nil
+			return stopOnCancel(ctx), nil
 		},
 		OnSubscriptionUpdateFn: func(strings []string) {},
 		SenderFn:               func() types.EventSender { return callBackSender },
@@ -409,26 +419,18 @@ func TestControlPlane_IsRegistered(t *testing.T) {
 	callBackSender := func(ce models.KeptnContextExtendedCE) error { return nil }
 
 	ssm := &fake2.SubscriptionSourceMock{
-		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, wg *sync.WaitGroup) error {
+		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, errC chan error) (<-chan struct{}, error) {
 			subsChan = c
-			go func() {
-				<-ctx.Done()
-				wg.Done()
-			}()
-			return nil
+			return stopOnCancel(ctx), nil
 		},
 		RegisterFn: func(integration models.Integration) (string, error) {
 			return "some-id", nil
 		},
 	}
 	esm := &fake2.EventSourceMock{
-		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, wg *sync.WaitGroup) error {
+		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, states chan types.ConnectionState, errC chan error) (<-chan struct{}, error) {
 			eventChan = ces
-			go func() {
-				<-ctx.Done()
-				wg.Done()
-			}()
-			return nil
+			return stopOnCancel(ctx), nil
 		},
 		OnSubscriptionUpdateFn: func(strings []string) {},
 		SenderFn:               func() types.EventSender { return callBackSender },
@@ -462,3 +464,1907 @@ func TestControlPlane_IsRegistered(t *testing.T) {
 		return !controlPlane.IsRegistered()
 	}, time.Second, 100*time.Millisecond)
 }
+
+// stopOnCancel returns a channel that is closed as soon as the given context is cancelled,
+// which is the behavior of a well-behaved source without any goroutines of its own
+func stopOnCancel(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		close(done)
+	}()
+	return done
+}
+
+// fakeSources bundles a fake event source and subscription source which capture
+// the channels handed over by the ControlPlane as well as the events sent via the event source's sender
+type fakeSources struct {
+	mtx       sync.Mutex
+	eventChan chan types.EventUpdate
+	subsChan  chan []models.EventSubscription
+	errChan   chan error
+	stateChan chan types.ConnectionState
+	recvChan  chan error
+	sent      []models.KeptnContextExtendedCE
+	ssm       *fake2.SubscriptionSourceMock
+	esm       *fake2.EventSourceMock
+}
+
+func newFakeSources() *fakeSources {
+	f := &fakeSources{}
+	f.ssm = &fake2.SubscriptionSourceMock{
+		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, errC chan error) (<-chan struct{}, error) {
+			f.mtx.Lock()
+			defer f.mtx.Unlock()
+			f.subsChan = c
+			f.errChan = errC
+			return stopOnCancel(ctx), nil
+		},
+		RegisterFn: func(integration models.Integration) (string, error) {
+			return "some-id", nil
+		},
+	}
+	f.esm = &fake2.EventSourceMock{
+		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, states chan types.ConnectionState, errC chan error) (<-chan struct{}, error) {
+			f.mtx.Lock()
+			defer f.mtx.Unlock()
+			f.eventChan = ces
+			f.stateChan = states
+			f.recvChan = errC
+			return stopOnCancel(ctx), nil
+		},
+		OnSubscriptionUpdateFn: func(strings []string) {},
+		SenderFn: func() types.EventSender {
+			return func(ce models.KeptnContextExtendedCE) error {
+				f.mtx.Lock()
+				defer f.mtx.Unlock()
+				f.sent = append(f.sent, ce)
+				return nil
+			}
+		},
+	}
+	return f
+}
+
+// channels waits until both sources have been started and returns the captured channels
+func (f *fakeSources) channels(t *testing.T) (chan types.EventUpdate, chan []models.EventSubscription) {
+	require.Eventually(t, func() bool {
+		f.mtx.Lock()
+		defer f.mtx.Unlock()
+		return f.eventChan != nil && f.subsChan != nil
+	}, time.Second, time.Millisecond*10)
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.eventChan, f.subsChan
+}
+
+func (f *fakeSources) subscriptionErrors() chan error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.errChan
+}
+
+func (f *fakeSources) connectionStates() chan types.ConnectionState {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.stateChan
+}
+
+func (f *fakeSources) receiveErrors() chan error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.recvChan
+}
+
+func (f *fakeSources) sentEvents() []models.KeptnContextExtendedCE {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return append([]models.KeptnContextExtendedCE{}, f.sent...)
+}
+
+func TestControlPlaneAutoStarted(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithAutoStarted(true))
+
+	var mtx sync.Mutex
+	var received []models.KeptnContextExtendedCE
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{Name: "my-service"} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			received = append(received, ce)
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+
+	subsChan <- []models.EventSubscription{
+		{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"},
+		{ID: "sub-2", Event: "sh.keptn.event.echo.finished"},
+	}
+	eventChan <- types.EventUpdate{
+		KeptnEvent: models.KeptnContextExtendedCE{
+			ID:             "triggered-id",
+			Shkeptncontext: "keptn-context",
+			Type:           strutils.Stringp("sh.keptn.event.echo.triggered"),
+			Data:           keptnv2.EventData{Project: "pr", Stage: "st", Service: "sv"},
+		},
+		MetaData: types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+	}
+	eventChan <- types.EventUpdate{
+		KeptnEvent: models.KeptnContextExtendedCE{ID: "finished-id", Type: strutils.Stringp("sh.keptn.event.echo.finished")},
+		MetaData:   types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.finished"},
+	}
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(received) == 2
+	}, time.Second, time.Millisecond*10)
+
+	sent := sources.sentEvents()
+	require.Len(t, sent, 1)
+	require.Equal(t, "sh.keptn.event.echo.started", *sent[0].Type)
+	require.Equal(t, "keptn-context", sent[0].Shkeptncontext)
+	require.Equal(t, "triggered-id", sent[0].Triggeredid)
+	require.Equal(t, "my-service", *sent[0].Source)
+	startedData := keptnv2.EventData{}
+	require.Nil(t, sent[0].DataAs(&startedData))
+	require.Equal(t, keptnv2.EventData{Project: "pr", Stage: "st", Service: "sv", Status: keptnv2.StatusSucceeded}, startedData)
+}
+
+func TestControlPlaneAutoStartedMultipleMatchingSubscriptions(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithAutoStarted(true))
+
+	received := make(chan models.KeptnContextExtendedCE, 2)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{Name: "my-service"} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			received <- ce
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+
+	subsChan <- []models.EventSubscription{
+		{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"},
+		{ID: "sub-2", Event: "sh.keptn.event.echo.triggered"},
+	}
+	eventChan <- types.EventUpdate{
+		KeptnEvent: models.KeptnContextExtendedCE{
+			ID:   "triggered-id",
+			Type: strutils.Stringp("sh.keptn.event.echo.triggered"),
+			Data: keptnv2.EventData{Project: "pr", Stage: "st", Service: "sv"},
+		},
+		MetaData: types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+	}
+	<-received
+	<-received
+
+	sent := sources.sentEvents()
+	require.Len(t, sent, 1)
+	require.Equal(t, "triggered-id", sent[0].Triggeredid)
+}
+
+func TestControlPlaneAutoStartedInvalidEventData(t *testing.T) {
+	controlPlane := New(nil, nil, nil, WithAutoStarted(true))
+	var sent []models.KeptnContextExtendedCE
+	sender := func(ce models.KeptnContextExtendedCE) error {
+		sent = append(sent, ce)
+		return nil
+	}
+	controlPlane.sendStartedEvent(context.TODO(), models.KeptnContextExtendedCE{ID: "triggered-id", Type: strutils.Stringp("sh.keptn.event.echo.triggered"), Data: "some invalid data"}, sender)
+	require.Empty(t, sent)
+}
+
+func TestControlPlaneAutoStartedDisabled(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil)
+
+	received := make(chan models.KeptnContextExtendedCE, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{Name: "my-service"} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			received <- ce
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- types.EventUpdate{
+		KeptnEvent: models.KeptnContextExtendedCE{ID: "triggered-id", Type: strutils.Stringp("sh.keptn.event.echo.triggered")},
+		MetaData:   types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+	}
+	<-received
+	require.Empty(t, sources.sentEvents())
+}
+
+func TestControlPlaneEventTransformer(t *testing.T) {
+	sources := newFakeSources()
+	transformer := func(ce models.KeptnContextExtendedCE) (models.KeptnContextExtendedCE, error) {
+		eventData := keptnv2.EventData{}
+		if err := ce.DataAs(&eventData); err != nil {
+			return ce, err
+		}
+		eventData.Labels = map[string]string{"transformed": "true"}
+		ce.Data = eventData
+		return ce, nil
+	}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithEventTransformer(transformer))
+
+	received := make(chan models.KeptnContextExtendedCE, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			received <- ce
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- types.EventUpdate{
+		KeptnEvent: models.KeptnContextExtendedCE{ID: "some-id", Type: strutils.Stringp("sh.keptn.event.echo.triggered"), Data: keptnv2.EventData{Project: "pr"}},
+		MetaData:   types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+	}
+
+	ce := <-received
+	eventData := keptnv2.EventData{}
+	require.Nil(t, ce.DataAs(&eventData))
+	require.Equal(t, "pr", eventData.Project)
+	require.Equal(t, map[string]string{"transformed": "true"}, eventData.Labels)
+}
+
+func TestControlPlaneEventTransformerFails(t *testing.T) {
+	sources := newFakeSources()
+	transformer := func(ce models.KeptnContextExtendedCE) (models.KeptnContextExtendedCE, error) {
+		if ce.ID == "invalid" {
+			return ce, fmt.Errorf("invalid event")
+		}
+		return ce, nil
+	}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithEventTransformer(transformer))
+
+	received := make(chan models.KeptnContextExtendedCE, 2)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			received <- ce
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- types.EventUpdate{
+		KeptnEvent: models.KeptnContextExtendedCE{ID: "invalid", Type: strutils.Stringp("sh.keptn.event.echo.triggered")},
+		MetaData:   types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+	}
+	eventChan <- types.EventUpdate{
+		KeptnEvent: models.KeptnContextExtendedCE{ID: "valid", Type: strutils.Stringp("sh.keptn.event.echo.triggered")},
+		MetaData:   types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+	}
+
+	require.Equal(t, "valid", (<-received).ID)
+	require.Len(t, received, 0)
+}
+
+func TestControlPlaneSubscriptionMetrics(t *testing.T) {
+	sources := newFakeSources()
+	var mtx sync.Mutex
+	subjectUpdates := 0
+	sources.esm.OnSubscriptionUpdateFn = func(strings []string) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		subjectUpdates++
+	}
+	registry := prometheus.NewRegistry()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithMetrics(registry))
+
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn:          func(ctx context.Context, ce models.KeptnContextExtendedCE) error { return nil },
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	_, subsChan := sources.channels(t)
+
+	subscriptionUpdates := [][]models.EventSubscription{
+		nil,
+		{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}, {ID: "sub-2", Event: "sh.keptn.event.echo.finished"}},
+		{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}, {ID: "sub-2", Event: "sh.keptn.event.echo.finished"}},
+		{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}},
+		{},
+	}
+	for _, s := range subscriptionUpdates {
+		subsChan <- s
+	}
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return subjectUpdates == len(subscriptionUpdates)
+	}, time.Second, time.Millisecond*10)
+
+	require.Equal(t, float64(0), testutil.ToFloat64(controlPlane.metrics.subscriptions))
+	require.Equal(t, float64(3), testutil.ToFloat64(controlPlane.metrics.subscriptionChanges))
+}
+
+func TestControlPlaneSubscriptionMetricsSharedRegisterer(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	first := New(nil, nil, nil, WithMetrics(registry))
+	second := New(nil, nil, nil, WithMetrics(registry))
+
+	first.metrics.observeSubscriptionUpdate(2, true)
+	second.metrics.observeSubscriptionUpdate(3, true)
+	require.Equal(t, float64(3), testutil.ToFloat64(first.metrics.subscriptions))
+	require.Equal(t, float64(2), testutil.ToFloat64(second.metrics.subscriptionChanges))
+}
+
+func TestControlPlaneMetricsRegistrationErrorIsLogged(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	// a collector with the same name but a different help text cannot be registered next to the one of the ControlPlane
+	registry.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Namespace: metricsNamespace, Name: "subscriptions", Help: "other"}))
+	log := &fake2.LoggerMock{}
+	controlPlane := New(nil, nil, nil, WithMetrics(registry), WithLogger(log))
+
+	require.True(t, log.Contains("Could not register metrics collector"))
+	require.NotNil(t, controlPlane.metrics)
+	controlPlane.metrics.observeSubscriptionUpdate(2, true)
+	require.Equal(t, float64(1), testutil.ToFloat64(controlPlane.metrics.subscriptionChanges))
+}
+
+// newBusyEventSource creates an EventSource that keeps sending the given event
+// until it is cancelled, honoring the shutdown contract of EventSource
+func newBusyEventSource(event types.EventUpdate) *fake2.EventSourceMock {
+	return &fake2.EventSourceMock{
+		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, states chan types.ConnectionState, errC chan error) (<-chan struct{}, error) {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for {
+					select {
+					case ces <- event:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+			return done, nil
+		},
+		OnSubscriptionUpdateFn: func(strings []string) {},
+		SenderFn:               func() types.EventSender { return func(ce models.KeptnContextExtendedCE) error { return nil } },
+	}
+}
+
+func TestControlPlaneCleanShutdown(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+	eventUpdate := types.EventUpdate{KeptnEvent: models.KeptnContextExtendedCE{ID: "some-id", Type: strutils.Stringp("sh.keptn.event.echo.triggered")}, MetaData: types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"}}
+	subscriptionSource := subscriptionsource.NewFixedSubscriptionSource(subscriptionsource.WithFixedSubscriptions(models.EventSubscription{ID: "some-id", Event: "sh.keptn.event.echo.triggered"}))
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	received := make(chan struct{}, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			select {
+			case received <- struct{}{}:
+			default:
+			}
+			return nil
+		},
+	}
+	registerErr := make(chan error)
+	go func() {
+		registerErr <- New(subscriptionSource, newBusyEventSource(eventUpdate), nil).Register(ctx, integration)
+	}()
+	<-received
+	cancel()
+	require.NoError(t, <-registerErr)
+}
+
+func TestControlPlaneCleanShutdownAfterFatalError(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+	eventUpdate := types.EventUpdate{KeptnEvent: models.KeptnContextExtendedCE{ID: "some-id", Type: strutils.Stringp("sh.keptn.event.echo.triggered")}, MetaData: types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"}}
+	subscriptionSource := subscriptionsource.NewFixedSubscriptionSource(subscriptionsource.WithFixedSubscriptions(models.EventSubscription{ID: "some-id", Event: "sh.keptn.event.echo.triggered"}))
+
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			return fmt.Errorf("could not handle event: %w", ErrEventHandleFatal)
+		},
+	}
+	err := New(subscriptionSource, newBusyEventSource(eventUpdate), nil).Register(context.TODO(), integration)
+	require.ErrorIs(t, err, ErrEventHandleFatal)
+}
+
+func TestControlPlaneRedeliversReceivedEventAfterFatalError(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithWarmup(1))
+	started := make(chan struct{})
+	release := make(chan struct{})
+	integration := warmupIntegration{
+		ExampleIntegration: ExampleIntegration{
+			RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+			OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+				require.Equal(t, "buffered", ce.ID)
+				close(started)
+				<-release
+				return fmt.Errorf("could not handle event: %w", ErrEventHandleFatal)
+			},
+		},
+		signalReady: make(chan func(), 1),
+	}
+	registerErr := make(chan error)
+	go func() { registerErr <- controlPlane.Register(context.TODO(), integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	signalReady := <-integration.signalReady
+	eventChan <- eventUpdate("buffered", "sh.keptn.event.echo.triggered")
+	// the overflowing event is only received once the buffered event was buffered
+	overflowAcknowledger := &fakeAcknowledger{}
+	overflow := eventUpdate("overflow", "sh.keptn.event.echo.triggered")
+	overflow.Acknowledger = overflowAcknowledger
+	eventChan <- overflow
+	require.Eventually(t, func() bool {
+		_, nacked := overflowAcknowledger.outcomes()
+		return len(nacked) == 1
+	}, time.Second, 10*time.Millisecond)
+	signalReady()
+	<-started
+
+	// the event is received while the buffered event is still being handled, which then fails fatally
+	acknowledger := &fakeAcknowledger{}
+	received := eventUpdate("received", "sh.keptn.event.echo.triggered")
+	received.Acknowledger = acknowledger
+	eventChan <- received
+	close(release)
+	require.ErrorIs(t, <-registerErr, ErrEventHandleFatal)
+	acked, nacked := acknowledger.outcomes()
+	require.Equal(t, 0, acked)
+	require.Equal(t, []time.Duration{0}, nacked)
+}
+
+type reportingEventSource struct {
+	*fake2.EventSourceMock
+	resources int
+}
+
+func (r reportingEventSource) ActiveResources() int {
+	return r.resources
+}
+
+func TestControlPlaneHealth(t *testing.T) {
+	sources := newFakeSources()
+	eventSource := reportingEventSource{EventSourceMock: sources.esm, resources: 3}
+	log := &fake2.LoggerMock{}
+	controlPlane := New(sources.ssm, eventSource, nil, WithLogger(log), WithSelfCheck(10*time.Millisecond))
+
+	require.Equal(t, HealthStatus{EventSourceResources: 3, SubscriptionSourceResources: -1}, controlPlane.Health())
+
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn:          func(ctx context.Context, ce models.KeptnContextExtendedCE) error { return nil },
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	registerErr := make(chan error)
+	go func() { registerErr <- controlPlane.Register(ctx, integration) }()
+	sources.channels(t)
+
+	require.Eventually(t, func() bool {
+		return log.Contains("Self check: event source owns 3 resources, subscription source owns -1 resources")
+	}, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return controlPlane.Health().Registered }, time.Second, 10*time.Millisecond)
+	require.Equal(t, "some-id", controlPlane.Health().IntegrationID)
+
+	cancel()
+	require.NoError(t, <-registerErr)
+	require.False(t, controlPlane.Health().Registered)
+}
+
+func TestControlPlaneReceiveBuffer(t *testing.T) {
+	const bufferSize = 5
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithReceiveBuffer(bufferSize))
+
+	release := make(chan struct{})
+	var mtx sync.Mutex
+	var handled []string
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			<-release
+			mtx.Lock()
+			defer mtx.Unlock()
+			handled = append(handled, ce.ID)
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	// one event is taken by the blocked handler, the remaining ones fill up the buffer
+	var sent []string
+	for i := 0; i < bufferSize+1; i++ {
+		id := fmt.Sprintf("event-%d", i)
+		select {
+		case eventChan <- types.EventUpdate{
+			KeptnEvent: models.KeptnContextExtendedCE{ID: id, Type: strutils.Stringp("sh.keptn.event.echo.triggered")},
+			MetaData:   types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+		}:
+			sent = append(sent, id)
+		case <-time.After(time.Second):
+			require.FailNow(t, "event source was blocked although buffer was not full")
+		}
+	}
+	close(release)
+
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return reflect.DeepEqual(sent, handled)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestControlPlaneAutoFinishedOnError(t *testing.T) {
+	tests := []struct {
+		name          string
+		onEvent       func(ctx context.Context, ce models.KeptnContextExtendedCE) error
+		wantInMessage string
+	}{
+		{
+			name: "handler returns error",
+			onEvent: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+				return errors.New("handler failed")
+			},
+			wantInMessage: "handler failed",
+		},
+		{
+			name: "handler panics",
+			onEvent: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+				panic("handler exploded")
+			},
+			wantInMessage: "handler exploded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := newFakeSources()
+			controlPlane := New(sources.ssm, sources.esm, nil, WithAutoFinishedOnError(true))
+
+			integration := ExampleIntegration{
+				RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{Name: "my-service"} },
+				OnEventFn:          tt.onEvent,
+			}
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			go func() { _ = controlPlane.Register(ctx, integration) }()
+			eventChan, subsChan := sources.channels(t)
+
+			subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+			eventChan <- types.EventUpdate{
+				KeptnEvent: models.KeptnContextExtendedCE{
+					ID:             "triggered-id",
+					Shkeptncontext: "keptn-context",
+					Type:           strutils.Stringp("sh.keptn.event.echo.triggered"),
+					Data:           keptnv2.EventData{Project: "pr", Stage: "st", Service: "sv"},
+				},
+				MetaData: types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+			}
+			require.Eventually(t, func() bool {
+				return len(sources.sentEvents()) == 1
+			}, time.Second, time.Millisecond*10)
+
+			sent := sources.sentEvents()[0]
+			require.Equal(t, "sh.keptn.event.echo.finished", *sent.Type)
+			require.Equal(t, "keptn-context", sent.Shkeptncontext)
+			require.Equal(t, "triggered-id", sent.Triggeredid)
+			finishedData := keptnv2.EventData{}
+			require.Nil(t, sent.DataAs(&finishedData))
+			require.Equal(t, keptnv2.StatusErrored, finishedData.Status)
+			require.Equal(t, keptnv2.ResultFailed, finishedData.Result)
+			require.Equal(t, "pr", finishedData.Project)
+			require.Contains(t, finishedData.Message, tt.wantInMessage)
+			require.True(t, controlPlane.IsRegistered())
+		})
+	}
+}
+
+func TestControlPlaneAutoFinishedOnErrorNotSentForSuccessfulHandling(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithAutoFinishedOnError(true))
+
+	handled := make(chan struct{})
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{Name: "my-service"} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			close(handled)
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- types.EventUpdate{
+		KeptnEvent: models.KeptnContextExtendedCE{
+			ID:   "triggered-id",
+			Type: strutils.Stringp("sh.keptn.event.echo.triggered"),
+			Data: keptnv2.EventData{Project: "pr", Stage: "st", Service: "sv"},
+		},
+		MetaData: types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+	}
+	<-handled
+	// the next event can only be received once handling of the first one is completed
+	eventChan <- types.EventUpdate{MetaData: types.EventUpdateMetaData{Subject: "unknown"}}
+	require.Empty(t, sources.sentEvents())
+}
+
+func TestControlPlaneMatchSubscriptions(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil)
+
+	forwarded := make(chan string, 10)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			data := types.AdditionalSubscriptionData{}
+			require.Nil(t, ce.GetTemporaryData(tmpDataDistributorKey, &data))
+			forwarded <- data.SubscriptionID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+
+	subscriptions := []models.EventSubscription{
+		{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"},
+		{ID: "sub-2", Event: "sh.keptn.event.echo.triggered", Filter: models.EventSubscriptionFilter{Projects: []string{"pr"}}},
+		{ID: "sub-3", Event: "sh.keptn.event.echo.triggered", Filter: models.EventSubscriptionFilter{Projects: []string{"other"}}},
+		{ID: "sub-4", Event: "sh.keptn.event.echo.finished"},
+	}
+	subsChan <- subscriptions
+	event := models.KeptnContextExtendedCE{
+		ID:   "triggered-id",
+		Type: strutils.Stringp("sh.keptn.event.echo.triggered"),
+		Data: keptnv2.EventData{Project: "pr", Stage: "st", Service: "sv"},
+	}
+	eventChan <- types.EventUpdate{KeptnEvent: event, MetaData: types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"}}
+	// the next event can only be received once handling of the first one is completed
+	eventChan <- types.EventUpdate{MetaData: types.EventUpdateMetaData{Subject: "unknown"}}
+	close(forwarded)
+
+	var forwardedIDs []string
+	for id := range forwarded {
+		forwardedIDs = append(forwardedIDs, id)
+	}
+	var matchedIDs []string
+	for _, s := range controlPlane.MatchSubscriptions(event) {
+		matchedIDs = append(matchedIDs, s.ID)
+	}
+	require.Equal(t, []string{"sub-1", "sub-2"}, matchedIDs)
+	require.Equal(t, forwardedIDs, matchedIDs)
+}
+
+func TestControlPlaneMatchSubscriptionsWithoutSubscriptions(t *testing.T) {
+	controlPlane := New(&fake2.SubscriptionSourceMock{}, &fake2.EventSourceMock{}, nil)
+	matched := controlPlane.MatchSubscriptions(models.KeptnContextExtendedCE{Type: strutils.Stringp("sh.keptn.event.echo.triggered")})
+	require.Empty(t, matched)
+}
+
+func TestControlPlaneTypePatternMatching(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []func(*ControlPlane)
+		eventType string
+		want      []string
+	}{
+		{
+			name:      "single token wildcard",
+			opts:      []func(*ControlPlane){WithTypePatternMatching()},
+			eventType: "sh.keptn.event.dev.deployment.triggered",
+			want:      []string{"sub-1", "sub-2"},
+		},
+		{
+			name:      "trailing wildcard",
+			opts:      []func(*ControlPlane){WithTypePatternMatching()},
+			eventType: "sh.keptn.event.dev.evaluation.finished",
+			want:      []string{"sub-2"},
+		},
+		{
+			name:      "no match",
+			opts:      []func(*ControlPlane){WithTypePatternMatching()},
+			eventType: "sh.keptn.log.error",
+			want:      nil,
+		},
+		{
+			name:      "disabled by default",
+			eventType: "sh.keptn.event.dev.deployment.triggered",
+			want:      nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := newFakeSources()
+			controlPlane := New(sources.ssm, sources.esm, nil, tt.opts...)
+			forwarded := make(chan string, 10)
+			integration := ExampleIntegration{
+				RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+				OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+					data := types.AdditionalSubscriptionData{}
+					require.Nil(t, ce.GetTemporaryData(tmpDataDistributorKey, &data))
+					forwarded <- data.SubscriptionID
+					return nil
+				},
+			}
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			go func() { _ = controlPlane.Register(ctx, integration) }()
+			eventChan, subsChan := sources.channels(t)
+			subsChan <- []models.EventSubscription{
+				{ID: "sub-1", Event: "sh.keptn.event.*.deployment.triggered"},
+				{ID: "sub-2", Event: "sh.keptn.event.>"},
+			}
+			eventChan <- types.EventUpdate{
+				KeptnEvent: models.KeptnContextExtendedCE{ID: "event-id", Type: strutils.Stringp(tt.eventType)},
+				MetaData:   types.EventUpdateMetaData{Subject: tt.eventType},
+			}
+			// the next event can only be received once handling of the first one is completed
+			eventChan <- types.EventUpdate{MetaData: types.EventUpdateMetaData{Subject: "unknown"}}
+			close(forwarded)
+
+			var forwardedIDs []string
+			for id := range forwarded {
+				forwardedIDs = append(forwardedIDs, id)
+			}
+			require.Equal(t, tt.want, forwardedIDs)
+		})
+	}
+}
+
+func TestControlPlaneCorrelationID(t *testing.T) {
+	sources := newFakeSources()
+	log := &fake2.LoggerMock{}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithLogger(log), WithAutoStarted(true), WithAutoFinishedOnError(true))
+
+	handlerCorrelationID := make(chan string, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{Name: "my-service"} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			handlerCorrelationID <- CorrelationIDFromContext(ctx)
+			return errors.New("handler failed")
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- types.EventUpdate{
+		KeptnEvent: models.KeptnContextExtendedCE{
+			ID:             "triggered-id",
+			Shkeptncontext: "keptn-context",
+			Type:           strutils.Stringp("sh.keptn.event.echo.triggered"),
+			Data:           keptnv2.EventData{Project: "pr", Stage: "st", Service: "sv"},
+		},
+		MetaData: types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+	}
+	require.Equal(t, "triggered-id", <-handlerCorrelationID)
+	require.Eventually(t, func() bool {
+		return len(sources.sentEvents()) == 2
+	}, time.Second, time.Millisecond*10)
+
+	var eventLogs []string
+	for _, m := range log.Messages() {
+		if strings.Contains(m, "correlation-id") {
+			eventLogs = append(eventLogs, m)
+		}
+	}
+	// received, subscription check, .started, forwarding, handling error and .finished
+	require.GreaterOrEqual(t, len(eventLogs), 6)
+	for _, m := range eventLogs {
+		require.Contains(t, m, "[correlation-id=triggered-id]")
+	}
+	require.True(t, log.Contains("[correlation-id=triggered-id] Error during handling of event: handler failed"))
+}
+
+func TestCorrelationIDFallback(t *testing.T) {
+	require.Equal(t, "event-id", correlationID(models.KeptnContextExtendedCE{ID: "event-id", Shkeptncontext: "keptn-context"}))
+	require.Equal(t, "keptn-context", correlationID(models.KeptnContextExtendedCE{Shkeptncontext: "keptn-context"}))
+	generated := correlationID(models.KeptnContextExtendedCE{})
+	require.NotEmpty(t, generated)
+	require.NotEqual(t, generated, correlationID(models.KeptnContextExtendedCE{}))
+	require.Empty(t, CorrelationIDFromContext(context.TODO()))
+}
+
+func TestControlPlaneSubscriptionErrorHandler(t *testing.T) {
+	sources := newFakeSources()
+	log := &fake2.LoggerMock{}
+	var mtx sync.Mutex
+	var reported []error
+	controlPlane := New(sources.ssm, sources.esm, nil, WithLogger(log), WithSubscriptionErrorHandler(func(err error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		reported = append(reported, err)
+	}))
+
+	received := make(chan string, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			received <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	errChan := sources.subscriptionErrors()
+
+	errChan <- errors.New("api unavailable")
+	errChan <- errors.New("api still unavailable")
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- types.EventUpdate{
+		KeptnEvent: models.KeptnContextExtendedCE{ID: "event-id", Type: strutils.Stringp("sh.keptn.event.echo.triggered")},
+		MetaData:   types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+	}
+	require.Equal(t, "event-id", <-received)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Len(t, reported, 2)
+	require.EqualError(t, reported[0], "api unavailable")
+	require.EqualError(t, reported[1], "api still unavailable")
+	require.True(t, log.Contains("Subscription source reported an error: api unavailable"))
+	require.True(t, controlPlane.IsRegistered())
+}
+
+func TestControlPlaneIgnoreSelfEvents(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithIgnoreSelfEvents(true))
+
+	received := make(chan string, 3)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{Name: "my-service"} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			received <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	for id, source := range map[string]string{"own-id": "some-id", "own-name": "my-service", "other": "other-service"} {
+		eventChan <- types.EventUpdate{
+			KeptnEvent: models.KeptnContextExtendedCE{ID: id, Source: strutils.Stringp(source), Type: strutils.Stringp("sh.keptn.event.echo.triggered")},
+			MetaData:   types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+		}
+	}
+	// the next event can only be received once handling of the previous ones is completed
+	eventChan <- types.EventUpdate{MetaData: types.EventUpdateMetaData{Subject: "unknown"}}
+	close(received)
+
+	var receivedIDs []string
+	for id := range received {
+		receivedIDs = append(receivedIDs, id)
+	}
+	require.Equal(t, []string{"other"}, receivedIDs)
+}
+
+func TestControlPlaneSelfEventsForwardedByDefault(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil)
+
+	received := make(chan string, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{Name: "my-service"} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			received <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- types.EventUpdate{
+		KeptnEvent: models.KeptnContextExtendedCE{ID: "own-id", Source: strutils.Stringp("some-id"), Type: strutils.Stringp("sh.keptn.event.echo.triggered")},
+		MetaData:   types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+	}
+	require.Equal(t, "own-id", <-received)
+}
+
+func TestControlPlaneWarnsAboutInactiveSubscriptions(t *testing.T) {
+	sources := newFakeSources()
+	log := &fake2.LoggerMock{}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithLogger(log), WithSubscriptionActivationTimeout(50*time.Millisecond))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData {
+			return types.RegistrationData{Subscriptions: []models.EventSubscription{
+				{Event: "sh.keptn.event.echo.triggered"},
+				{Event: "sh.keptn.event.rejected.triggered"},
+				{Event: "sh.keptn.event.trimmed.triggered"},
+			}}
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	_, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	require.Eventually(t, func() bool {
+		return log.Contains("Requested subscriptions did not become active within 50ms: sh.keptn.event.rejected.triggered, sh.keptn.event.trimmed.triggered")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestControlPlaneActivationTimeoutWithMockClock(t *testing.T) {
+	sources := newFakeSources()
+	log := &fake2.LoggerMock{}
+	mockClock := clock.NewMock()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithLogger(log), WithClock(mockClock), WithSubscriptionActivationTimeout(time.Minute))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData {
+			return types.RegistrationData{Subscriptions: []models.EventSubscription{{Event: "sh.keptn.event.rejected.triggered"}}}
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	_, subsChan := sources.channels(t)
+	// the activation timer is running once the first subscription update was received
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	mockClock.Add(59 * time.Second)
+	// the subscription update is handled after the timer was checked, so the warning could not have been missed
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	require.False(t, log.Contains("did not become active"))
+
+	mockClock.Add(time.Second)
+	require.Eventually(t, func() bool {
+		return log.Contains("Requested subscriptions did not become active within 1m0s: sh.keptn.event.rejected.triggered")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestControlPlaneNoWarningIfSubscriptionsBecomeActive(t *testing.T) {
+	sources := newFakeSources()
+	log := &fake2.LoggerMock{}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithLogger(log), WithSubscriptionActivationTimeout(50*time.Millisecond))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData {
+			return types.RegistrationData{Subscriptions: []models.EventSubscription{{Event: "sh.keptn.event.echo.triggered"}}}
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	_, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	time.Sleep(100 * time.Millisecond)
+	require.False(t, log.Contains("did not become active"))
+}
+
+func TestControlPlaneRegisterWithCancelledContext(t *testing.T) {
+	registered := false
+	started := false
+	ssm := &fake2.SubscriptionSourceMock{
+		RegisterFn: func(integration models.Integration) (string, error) {
+			registered = true
+			return "some-id", nil
+		},
+		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, errC chan error) (<-chan struct{}, error) {
+			started = true
+			return stopOnCancel(ctx), nil
+		},
+	}
+	esm := &fake2.EventSourceMock{
+		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, states chan types.ConnectionState, errC chan error) (<-chan struct{}, error) {
+			started = true
+			return stopOnCancel(ctx), nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	err := New(ssm, esm, nil).Register(ctx, ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.False(t, registered)
+	require.False(t, started)
+}
+
+func TestControlPlaneStart(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			return fmt.Errorf("could not handle event: %w", ErrEventHandleFatal)
+		},
+	}
+	result, err := controlPlane.Start(context.TODO(), integration)
+	require.NoError(t, err)
+	// Start returns once the integration is registered
+	require.True(t, controlPlane.IsRegistered())
+	require.Equal(t, "some-id", controlPlane.registrationID)
+
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	require.Never(t, func() bool { return len(result) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	select {
+	case err := <-result:
+		require.ErrorIs(t, err, ErrEventHandleFatal)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "terminal error was not delivered")
+	}
+}
+
+func TestControlPlaneStartDeliversNilOnCancel(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil)
+	ctx, cancel := context.WithCancel(context.TODO())
+	result, err := controlPlane.Start(ctx, ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+	})
+	require.NoError(t, err)
+	cancel()
+	require.NoError(t, <-result)
+}
+
+func TestControlPlaneStartWithCancelledContext(t *testing.T) {
+	sources := newFakeSources()
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	result, err := New(sources.ssm, sources.esm, nil).Start(ctx, ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, result)
+}
+
+func TestControlPlaneEventSampling(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithEventSampling(map[string]float64{"sh.keptn.event.metrics.triggered": 0.25}))
+
+	var mtx sync.Mutex
+	forwarded := map[string]int{}
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			forwarded[*ce.Type]++
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{
+		{ID: "sub-1", Event: "sh.keptn.event.metrics.triggered"},
+		{ID: "sub-2", Event: "sh.keptn.event.echo.triggered"},
+	}
+
+	const events = 2000
+	for i := 0; i < events; i++ {
+		for _, subject := range []string{"sh.keptn.event.metrics.triggered", "sh.keptn.event.echo.triggered"} {
+			eventChan <- types.EventUpdate{
+				KeptnEvent: models.KeptnContextExtendedCE{ID: fmt.Sprintf("%s-%d", subject, i), Type: strutils.Stringp(subject)},
+				MetaData:   types.EventUpdateMetaData{Subject: subject},
+			}
+		}
+	}
+	// the next event can only be received once handling of the previous ones is completed
+	eventChan <- types.EventUpdate{MetaData: types.EventUpdateMetaData{Subject: "unknown"}}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Equal(t, events, forwarded["sh.keptn.event.echo.triggered"])
+	require.InDelta(t, 0.25*events, forwarded["sh.keptn.event.metrics.triggered"], 0.05*events)
+}
+
+func TestControlPlaneEventSamplingIsDeterministic(t *testing.T) {
+	controlPlane := New(nil, nil, nil, WithEventSampling(map[string]float64{"subject": 0.5}))
+	for i := 0; i < 100; i++ {
+		event := types.EventUpdate{KeptnEvent: models.KeptnContextExtendedCE{ID: fmt.Sprintf("event-%d", i)}, MetaData: types.EventUpdateMetaData{Subject: "subject"}}
+		require.Equal(t, controlPlane.sampled(event), controlPlane.sampled(event))
+	}
+	require.True(t, New(nil, nil, nil, WithEventSampling(map[string]float64{"subject": 1})).sampled(types.EventUpdate{MetaData: types.EventUpdateMetaData{Subject: "subject"}}))
+	require.False(t, New(nil, nil, nil, WithEventSampling(map[string]float64{"subject": 0})).sampled(types.EventUpdate{MetaData: types.EventUpdateMetaData{Subject: "subject"}}))
+}
+
+type customSubscriptionData struct {
+	SubscriptionID string   `json:"subscriptionID"`
+	Event          string   `json:"event"`
+	Projects       []string `json:"projects"`
+}
+
+func TestControlPlaneCustomSubscriptionData(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithSubscriptionData(func(subscription models.EventSubscription) interface{} {
+		return customSubscriptionData{SubscriptionID: subscription.ID, Event: subscription.Event, Projects: subscription.Filter.Projects}
+	}))
+
+	received := make(chan models.KeptnContextExtendedCE, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			received <- ce
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered", Filter: models.EventSubscriptionFilter{Projects: []string{"pr"}}}}
+	eventChan <- types.EventUpdate{
+		KeptnEvent: models.KeptnContextExtendedCE{ID: "event-id", Type: strutils.Stringp("sh.keptn.event.echo.triggered"), Data: keptnv2.EventData{Project: "pr"}},
+		MetaData:   types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+	}
+	event := <-received
+
+	data := customSubscriptionData{}
+	require.Nil(t, event.GetTemporaryData(tmpDataDistributorKey, &data))
+	require.Equal(t, customSubscriptionData{SubscriptionID: "sub-1", Event: "sh.keptn.event.echo.triggered", Projects: []string{"pr"}}, data)
+	// the default layout can still be read, as the custom data contains the subscription ID under the same key
+	defaultData := types.AdditionalSubscriptionData{}
+	require.Nil(t, event.GetTemporaryData(tmpDataDistributorKey, &defaultData))
+	require.Equal(t, "sub-1", defaultData.SubscriptionID)
+}
+
+func TestControlPlaneConnectionStateHandler(t *testing.T) {
+	sources := newFakeSources()
+	registry := prometheus.NewRegistry()
+	var mtx sync.Mutex
+	var observed []types.ConnectionState
+	controlPlane := New(sources.ssm, sources.esm, nil, WithMetrics(registry), WithConnectionStateHandler(func(state types.ConnectionState) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		observed = append(observed, state)
+	}))
+	integration := ExampleIntegration{RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} }}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	sources.channels(t)
+
+	sources.connectionStates() <- types.ConnectionStateDisconnected
+	sources.connectionStates() <- types.ConnectionStateConnected
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(observed) == 2
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, []types.ConnectionState{types.ConnectionStateDisconnected, types.ConnectionStateConnected}, observed)
+	require.Equal(t, float64(1), testutil.ToFloat64(controlPlane.metrics.connectionStates.WithLabelValues("disconnected")))
+	require.Equal(t, float64(1), testutil.ToFloat64(controlPlane.metrics.connectionStates.WithLabelValues("connected")))
+}
+
+func TestControlPlaneReceiveErrors(t *testing.T) {
+	sources := newFakeSources()
+	var mtx sync.Mutex
+	var reported []error
+	log := &fake2.LoggerMock{}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithLogger(log), WithMetrics(prometheus.NewRegistry()), WithReceiveErrorHandler(func(err error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		reported = append(reported, err)
+	}))
+	handled := make(chan string, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			handled <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	sources.receiveErrors() <- errors.New("could not unmarshal message")
+	// processing of events continues after the error
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	require.Equal(t, "event-id", <-handled)
+
+	mtx.Lock()
+	require.Len(t, reported, 1)
+	require.EqualError(t, reported[0], "could not unmarshal message")
+	mtx.Unlock()
+	require.Equal(t, float64(1), testutil.ToFloat64(controlPlane.metrics.receiveErrors))
+	require.True(t, log.Contains("could not unmarshal message"))
+}
+
+func TestControlPlaneDeregister(t *testing.T) {
+	var mtx sync.Mutex
+	var unregistered []string
+	pinged := make(chan struct{}, 1)
+	uniformAPI := &fake2.UniformAPIMock{
+		RegisterIntegrationFn: func(integration models.Integration) (string, error) { return "integration-id", nil },
+		PingFn: func(integrationID string) (*models.Integration, error) {
+			select {
+			case pinged <- struct{}{}:
+			default:
+			}
+			return &models.Integration{ID: integrationID}, nil
+		},
+		UnregisterIntegrationFn: func(integrationID string) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			unregistered = append(unregistered, integrationID)
+			return nil
+		},
+	}
+	sources := newFakeSources()
+	controlPlane := New(subscriptionsource.New(uniformAPI), sources.esm, nil)
+	integration := ExampleIntegration{RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} }}
+	ctx, cancel := context.WithCancel(context.TODO())
+	registered := make(chan struct{})
+	go func() {
+		defer close(registered)
+		_ = controlPlane.Register(ctx, integration)
+	}()
+	// the subscription source is started after the integration was registered
+	<-pinged
+
+	require.NoError(t, controlPlane.Deregister(context.TODO()))
+	mtx.Lock()
+	require.Equal(t, []string{"integration-id"}, unregistered)
+	mtx.Unlock()
+
+	// the registration was already removed
+	cancel()
+	<-registered
+	require.NoError(t, controlPlane.Deregister(context.TODO()))
+	require.Len(t, unregistered, 1)
+}
+
+func TestControlPlaneRefreshSubscriptions(t *testing.T) {
+	var pings int32
+	pinged := make(chan struct{}, 1)
+	uniformAPI := &fake2.UniformAPIMock{
+		RegisterIntegrationFn: func(integration models.Integration) (string, error) { return "integration-id", nil },
+		PingFn: func(integrationID string) (*models.Integration, error) {
+			subject := "sh.keptn.event.echo.triggered"
+			if atomic.AddInt32(&pings, 1) > 1 {
+				// the subscriptions were changed in Keptn after the first ping
+				subject = "sh.keptn.event.other.triggered"
+			}
+			select {
+			case pinged <- struct{}{}:
+			default:
+			}
+			return &models.Integration{ID: integrationID, Subscriptions: []models.EventSubscription{{ID: "sub-1", Event: subject}}}, nil
+		},
+	}
+	sources := newFakeSources()
+	controlPlane := New(subscriptionsource.New(uniformAPI, subscriptionsource.WithFetchInterval(time.Hour)), sources.esm, nil)
+	integration := ExampleIntegration{RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} }}
+	require.ErrorIs(t, controlPlane.RefreshSubscriptions(context.TODO()), subscriptionsource.ErrNotStarted)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	<-pinged
+	otherEvent := models.KeptnContextExtendedCE{ID: "event-id", Type: strutils.Stringp("sh.keptn.event.other.triggered")}
+	require.Empty(t, controlPlane.MatchSubscriptions(otherEvent))
+
+	require.NoError(t, controlPlane.RefreshSubscriptions(context.TODO()))
+	require.Equal(t, int32(2), atomic.LoadInt32(&pings))
+	require.Eventually(t, func() bool { return len(controlPlane.MatchSubscriptions(otherEvent)) == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestControlPlaneWithVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   string
+		gitCommit string
+		want      string
+	}{
+		{name: "version and commit", version: "1.2.0", gitCommit: "3f2a9c1", want: "1.2.0+3f2a9c1"},
+		{name: "version only", version: "1.2.0", want: "1.2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registered := make(chan models.Integration, 1)
+			uniformAPI := &fake2.UniformAPIMock{
+				RegisterIntegrationFn: func(integration models.Integration) (string, error) {
+					registered <- integration
+					return "integration-id", nil
+				},
+				PingFn: func(integrationID string) (*models.Integration, error) {
+					return &models.Integration{ID: integrationID}, nil
+				},
+			}
+			sources := newFakeSources()
+			controlPlane := New(subscriptionsource.New(uniformAPI), sources.esm, nil, WithVersion(tt.version, tt.gitCommit))
+			integration := ExampleIntegration{RegistrationDataFn: func() types.RegistrationData {
+				return types.RegistrationData{Name: "my-service", MetaData: models.MetaData{IntegrationVersion: "dev", Hostname: "my-host"}}
+			}}
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			go func() { _ = controlPlane.Register(ctx, integration) }()
+
+			integrationData := <-registered
+			require.Equal(t, tt.want, integrationData.MetaData.IntegrationVersion)
+			require.Equal(t, "my-host", integrationData.MetaData.Hostname)
+			require.Equal(t, "my-service", integrationData.Name)
+		})
+	}
+}
+
+func TestControlPlaneRegisterWithEmptyIntegrationID(t *testing.T) {
+	uniformAPI := &fake2.UniformAPIMock{
+		RegisterIntegrationFn: func(integration models.Integration) (string, error) {
+			return "", nil
+		},
+		PingFn: func(integrationID string) (*models.Integration, error) {
+			require.FailNow(t, "unexpected call of Ping")
+			return nil, nil
+		},
+	}
+	sources := newFakeSources()
+	controlPlane := New(subscriptionsource.New(uniformAPI), sources.esm, nil)
+	integration := ExampleIntegration{RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} }}
+
+	err := controlPlane.Register(context.TODO(), integration)
+	require.ErrorIs(t, err, subscriptionsource.ErrEmptyIntegrationID)
+	require.ErrorContains(t, err, "could not register integration")
+	require.False(t, controlPlane.IsRegistered())
+}
+
+func TestControlPlaneDeregisterWhenNotRegistered(t *testing.T) {
+	uniformAPI := &fake2.UniformAPIMock{
+		UnregisterIntegrationFn: func(integrationID string) error {
+			require.FailNow(t, "unexpected call of UnregisterIntegration")
+			return nil
+		},
+	}
+	controlPlane := New(subscriptionsource.New(uniformAPI), &fake2.EventSourceMock{}, nil)
+	require.NoError(t, controlPlane.Deregister(context.TODO()))
+}
+
+func TestControlPlaneSubjectMapper(t *testing.T) {
+	sources := newFakeSources()
+	updatedSubjects := make(chan []string, 1)
+	sources.esm.OnSubscriptionUpdateFn = func(subjects []string) { updatedSubjects <- subjects }
+	controlPlane := New(sources.ssm, sources.esm, nil, WithSubjectMapper(func(subscription models.EventSubscription) string {
+		return "tenant-a." + subscription.Event
+	}))
+
+	received := make(chan string, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			received <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	require.Equal(t, []string{"tenant-a.sh.keptn.event.echo.triggered"}, <-updatedSubjects)
+
+	event := models.KeptnContextExtendedCE{ID: "event-id", Type: strutils.Stringp("sh.keptn.event.echo.triggered")}
+	eventChan <- types.EventUpdate{KeptnEvent: event, MetaData: types.EventUpdateMetaData{Subject: "tenant-a.sh.keptn.event.echo.triggered"}}
+	require.Equal(t, "event-id", <-received)
+
+	matched := controlPlane.MatchSubscriptions(event)
+	require.Len(t, matched, 1)
+	require.Equal(t, "sub-1", matched[0].ID)
+}
+
+func TestControlPlaneSubjectNormalizer(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithSubjectNormalizer(func(subject string) string {
+		return strings.TrimPrefix(subject, "sh.keptn.event.v1.")
+	}))
+	received := make(chan string, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			received <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.v1.echo.triggered"}}
+
+	eventChan <- eventUpdate("other-version", "echo.triggered")
+	require.Equal(t, "other-version", <-received)
+	eventChan <- eventUpdate("same-version", "sh.keptn.event.v1.echo.triggered")
+	require.Equal(t, "same-version", <-received)
+}
+
+func TestControlPlaneSubscriptionUpdateDuringSlowHandling(t *testing.T) {
+	sources := newFakeSources()
+	updatedSubjects := make(chan []string, 2)
+	sources.esm.OnSubscriptionUpdateFn = func(subjects []string) { updatedSubjects <- subjects }
+	controlPlane := New(sources.ssm, sources.esm, nil)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			close(started)
+			<-release
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	require.Equal(t, []string{"sh.keptn.event.echo.triggered"}, <-updatedSubjects)
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	<-started
+
+	// the subscription update is applied while the integration is still handling the event
+	subsChan <- []models.EventSubscription{{ID: "sub-2", Event: "sh.keptn.event.test.triggered"}}
+	require.Equal(t, []string{"sh.keptn.event.test.triggered"}, <-updatedSubjects)
+	matched := controlPlane.MatchSubscriptions(models.KeptnContextExtendedCE{Type: strutils.Stringp("sh.keptn.event.test.triggered")})
+	require.Len(t, matched, 1)
+	require.Equal(t, "sub-2", matched[0].ID)
+	close(release)
+}
+
+func TestControlPlaneSubscriptionDebounce(t *testing.T) {
+	sources := newFakeSources()
+	var mtx sync.Mutex
+	var updates [][]string
+	sources.esm.OnSubscriptionUpdateFn = func(subjects []string) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		updates = append(updates, subjects)
+	}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithSubscriptionDebounce(100*time.Millisecond))
+	integration := ExampleIntegration{RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} }}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	_, subsChan := sources.channels(t)
+
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	subsChan <- []models.EventSubscription{}
+	subsChan <- []models.EventSubscription{{ID: "sub-2", Event: "sh.keptn.event.deployment.triggered"}}
+
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(updates) > 0
+	}, time.Second, 10*time.Millisecond)
+	// no further updates are applied after the burst
+	time.Sleep(200 * time.Millisecond)
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Equal(t, [][]string{{"sh.keptn.event.deployment.triggered"}}, updates)
+}
+
+func TestControlPlaneEventMetaDataInContext(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil)
+	received := make(chan types.EventUpdateMetaData, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			metaData, ok := EventMetaDataFromContext(ctx)
+			require.True(t, ok)
+			received <- metaData
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	receivedAt := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	update := eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	update.MetaData.DeliveryCount = 3
+	update.MetaData.Timestamp = receivedAt
+	eventChan <- update
+
+	metaData := <-received
+	require.Equal(t, uint64(3), metaData.DeliveryCount)
+	require.Equal(t, receivedAt, metaData.Timestamp)
+	require.Equal(t, "sh.keptn.event.echo.triggered", metaData.Subject)
+
+	_, ok := EventMetaDataFromContext(context.TODO())
+	require.False(t, ok)
+}
+
+func TestControlPlaneFailsOnMissingSender(t *testing.T) {
+	sources := newFakeSources()
+	sources.esm.SenderFn = func() types.EventSender { return nil }
+	integration := ExampleIntegration{RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} }}
+	err := New(sources.ssm, sources.esm, nil).Register(context.TODO(), integration)
+	require.ErrorIs(t, err, ErrMissingSender)
+}
+
+func TestControlPlaneNoOpOnMissingSender(t *testing.T) {
+	sources := newFakeSources()
+	sources.esm.SenderFn = func() types.EventSender { return nil }
+	controlPlane := New(sources.ssm, sources.esm, nil, WithMissingSenderBehavior(NoOpOnMissingSender))
+
+	sendErr := make(chan error, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			sender, ok := ctx.Value(types.EventSenderKey).(types.EventSender)
+			require.True(t, ok)
+			require.NotNil(t, sender)
+			sendErr <- sender(models.KeptnContextExtendedCE{Type: strutils.Stringp("sh.keptn.event.echo.started")})
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	require.NoError(t, <-sendErr)
+}
+
+type tenantKey struct{}
+
+func TestControlPlaneContextDecorator(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithContextDecorator(func(ctx context.Context, ce models.KeptnContextExtendedCE) context.Context {
+		// the sender is already part of the context
+		_, ok := ctx.Value(types.EventSenderKey).(types.EventSender)
+		require.True(t, ok)
+		subscriptionData := types.AdditionalSubscriptionData{}
+		require.NoError(t, ce.GetTemporaryData(tmpDataDistributorKey, &subscriptionData))
+		return context.WithValue(ctx, tenantKey{}, "tenant-of-"+subscriptionData.SubscriptionID)
+	}))
+	tenants := make(chan string, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			tenant, _ := ctx.Value(tenantKey{}).(string)
+			tenants <- tenant
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	require.Equal(t, "tenant-of-sub-1", <-tenants)
+}
+
+func TestControlPlaneMaxEventSize(t *testing.T) {
+	sources := newFakeSources()
+	log := &fake2.LoggerMock{}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithLogger(log), WithMaxEventSize(1024))
+	received := make(chan string, 2)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			received <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	acknowledger := &fakeAcknowledger{}
+	oversized := eventUpdate("oversized", "sh.keptn.event.echo.triggered")
+	oversized.MetaData.Size = 2048
+	oversized.Acknowledger = acknowledger
+	eventChan <- oversized
+	// without a size reported by the event source, the serialized event is measured
+	unmeasured := eventUpdate("unmeasured", "sh.keptn.event.echo.triggered")
+	unmeasured.KeptnEvent.Data = map[string]string{"payload": strings.Repeat("x", 2048)}
+	eventChan <- unmeasured
+	small := eventUpdate("small", "sh.keptn.event.echo.triggered")
+	small.MetaData.Size = 512
+	eventChan <- small
+
+	require.Equal(t, "small", <-received)
+	require.Empty(t, received)
+	require.True(t, log.Contains("Dropping event oversized: size of 2048 bytes exceeds the maximum event size of 1024 bytes"))
+	require.True(t, log.Contains("Dropping event unmeasured"))
+	acked, nacked := acknowledger.outcomes()
+	require.Equal(t, 1, acked)
+	require.Empty(t, nacked)
+}
+
+func TestControlPlaneMissingContextPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    MissingContextPolicy
+		forwarded bool
+		// synthesized is true if a keptn context is expected to be assigned
+		synthesized bool
+	}{
+		{name: "forward", policy: ForwardMissingContext, forwarded: true},
+		{name: "drop", policy: DropMissingContext, forwarded: false},
+		{name: "synthesize", policy: SynthesizeMissingContext, forwarded: true, synthesized: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := newFakeSources()
+			controlPlane := New(sources.ssm, sources.esm, nil, WithMissingContextPolicy(tt.policy))
+			received := make(chan models.KeptnContextExtendedCE, 2)
+			integration := ExampleIntegration{
+				RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+				OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+					received <- ce
+					return nil
+				},
+			}
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			go func() { _ = controlPlane.Register(ctx, integration) }()
+			eventChan, subsChan := sources.channels(t)
+			subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+			eventChan <- eventUpdate("without-context", "sh.keptn.event.echo.triggered")
+			withContext := eventUpdate("with-context", "sh.keptn.event.echo.triggered")
+			withContext.KeptnEvent.Shkeptncontext = "keptn-context"
+			eventChan <- withContext
+
+			if tt.forwarded {
+				event := <-received
+				require.Equal(t, "without-context", event.ID)
+				if tt.synthesized {
+					_, err := uuid.Parse(event.Shkeptncontext)
+					require.NoError(t, err)
+				} else {
+					require.Empty(t, event.Shkeptncontext)
+				}
+			}
+			event := <-received
+			require.Equal(t, "with-context", event.ID)
+			require.Equal(t, "keptn-context", event.Shkeptncontext)
+		})
+	}
+}
+
+func TestControlPlaneWaitUntilRegistered(t *testing.T) {
+	sources := newFakeSources()
+	release := make(chan struct{})
+	sources.ssm.RegisterFn = func(integration models.Integration) (string, error) {
+		<-release
+		return "some-id", nil
+	}
+	controlPlane := New(sources.ssm, sources.esm, nil)
+	integration := ExampleIntegration{RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} }}
+
+	waitErr := make(chan error)
+	go func() { waitErr <- controlPlane.WaitUntilRegistered(context.TODO()) }()
+	ctx, cancel := context.WithCancel(context.TODO())
+	registerDone := make(chan struct{})
+	go func() {
+		defer close(registerDone)
+		_ = controlPlane.Register(ctx, integration)
+	}()
+
+	select {
+	case <-waitErr:
+		require.FailNow(t, "WaitUntilRegistered returned before the integration was registered")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case err := <-waitErr:
+		require.NoError(t, err)
+		require.True(t, controlPlane.IsRegistered())
+	case <-time.After(time.Second):
+		require.FailNow(t, "WaitUntilRegistered did not return after the integration was registered")
+	}
+
+	// once the registration stopped, waiting blocks again
+	cancel()
+	<-registerDone
+	require.False(t, controlPlane.IsRegistered())
+	timeoutCtx, timeoutCancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer timeoutCancel()
+	require.ErrorIs(t, controlPlane.WaitUntilRegistered(timeoutCtx), context.DeadlineExceeded)
+}
+
+func TestControlPlaneWithSenderSelector(t *testing.T) {
+	sources := newFakeSources()
+	var mtx sync.Mutex
+	sentTo := map[string][]string{}
+	senderOf := func(cluster string) types.EventSender {
+		return func(ce models.KeptnContextExtendedCE) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			sentTo[cluster] = append(sentTo[cluster], ce.ID)
+			return nil
+		}
+	}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithSenderSelector(func(ce models.KeptnContextExtendedCE) types.EventSender {
+		if *ce.Type == "sh.keptn.event.deployment.triggered" {
+			return senderOf("cluster-a")
+		}
+		return nil
+	}))
+	handled := make(chan string)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			sender := ctx.Value(types.EventSenderKey).(types.EventSender)
+			require.NoError(t, sender(models.KeptnContextExtendedCE{ID: "response-" + ce.ID}))
+			handled <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{
+		{ID: "sub-1", Event: "sh.keptn.event.deployment.triggered"},
+		{ID: "sub-2", Event: "sh.keptn.event.test.triggered"},
+	}
+
+	eventChan <- eventUpdate("deployment", "sh.keptn.event.deployment.triggered")
+	require.Equal(t, "deployment", <-handled)
+	eventChan <- eventUpdate("test", "sh.keptn.event.test.triggered")
+	require.Equal(t, "test", <-handled)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Equal(t, map[string][]string{"cluster-a": {"response-deployment"}}, sentTo)
+	require.Len(t, sources.sentEvents(), 1)
+	require.Equal(t, "response-test", sources.sentEvents()[0].ID)
+}
+
+func TestNewWithoutOptions(t *testing.T) {
+	controlPlane := New(nil, nil, nil)
+	require.NotNil(t, controlPlane.logger)
+	require.NotNil(t, controlPlane.clock)
+	require.Nil(t, controlPlane.metrics)
+	require.Equal(t, time.Minute, controlPlane.activationTimeout)
+	require.Equal(t, time.Second, controlPlane.redeliveryDelayFn(1))
+	require.Equal(t, time.Duration(0), controlPlane.handlerTimeout)
+	require.False(t, controlPlane.autoStarted)
+	require.Empty(t, controlPlane.Describe().EnabledOptions)
+}
+
+func TestNewWithSeveralOptions(t *testing.T) {
+	log := &fake2.LoggerMock{}
+	options := []Option{
+		WithLogger(log),
+		WithMetrics(prometheus.NewRegistry()),
+		WithHandlerTimeout(time.Minute),
+		WithSubscriptionActivationTimeout(10 * time.Second),
+	}
+	// options can be mixed with plain functions configuring the control plane
+	options = append(options, func(plane *ControlPlane) { plane.receiveBuffer = 5 })
+	controlPlane := New(nil, nil, nil, options...)
+	require.Same(t, log, controlPlane.logger)
+	require.NotNil(t, controlPlane.metrics)
+	require.Equal(t, time.Minute, controlPlane.handlerTimeout)
+	require.Equal(t, 10*time.Second, controlPlane.activationTimeout)
+	require.Equal(t, 5, controlPlane.receiveBuffer)
+}
+
+func TestControlPlaneMatchesConsistentSubscriptionSnapshot(t *testing.T) {
+	const subject = "sh.keptn.event.echo.triggered"
+	const generations, events = 50, 50
+	subscriptionsOf := func(generation int) []models.EventSubscription {
+		return []models.EventSubscription{
+			{ID: fmt.Sprintf("gen-%d-a", generation), Event: subject},
+			{ID: fmt.Sprintf("gen-%d-b", generation), Event: subject},
+		}
+	}
+	generationOf := func(subscriptionID string) string {
+		return strings.TrimSuffix(strings.TrimSuffix(subscriptionID, "-a"), "-b")
+	}
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil)
+	var mtx sync.Mutex
+	matched := map[string][]string{}
+	handled := make(chan struct{}, 2*events)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			data := types.AdditionalSubscriptionData{}
+			require.Nil(t, ce.GetTemporaryData(tmpDataDistributorKey, &data))
+			mtx.Lock()
+			matched[ce.ID] = append(matched[ce.ID], data.SubscriptionID)
+			mtx.Unlock()
+			handled <- struct{}{}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- subscriptionsOf(0)
+	require.Eventually(t, func() bool {
+		return len(controlPlane.MatchSubscriptions(eventUpdate("probe", subject).KeptnEvent)) == 2
+	}, time.Second, time.Millisecond)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for generation := 1; generation <= generations; generation++ {
+			subsChan <- subscriptionsOf(generation)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < events; i++ {
+			subscriptions := controlPlane.MatchSubscriptions(eventUpdate("probe", subject).KeptnEvent)
+			require.Len(t, subscriptions, 2)
+			require.Equal(t, generationOf(subscriptions[0].ID), generationOf(subscriptions[1].ID))
+		}
+	}()
+	for i := 0; i < events; i++ {
+		eventChan <- eventUpdate(fmt.Sprintf("event-%d", i), subject)
+	}
+	for i := 0; i < 2*events; i++ {
+		select {
+		case <-handled:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "events were not handled")
+		}
+	}
+	wg.Wait()
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Len(t, matched, events)
+	for id, subscriptionIDs := range matched {
+		require.Len(t, subscriptionIDs, 2, id)
+		require.Equal(t, generationOf(subscriptionIDs[0]), generationOf(subscriptionIDs[1]), id)
+	}
+}
@@ -0,0 +1,135 @@
+package controlplane
+
+import (
+	"sort"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+)
+
+// ControlPlaneState is a snapshot of the configuration and the runtime state of a ControlPlane,
+// e.g. to be attached to support tickets. Functions configured via options are only reported as enabled option,
+// values coming from outside of the ControlPlane (e.g. the registration data of the integration) are left out
+type ControlPlaneState struct {
+	Registered    bool   `json:"registered"`
+	IntegrationID string `json:"integrationID,omitempty"`
+	// Subscriptions are the subscriptions currently used for matching events
+	Subscriptions []models.EventSubscription `json:"subscriptions"`
+	MutedSubjects []string                   `json:"mutedSubjects,omitempty"`
+	// EnabledOptions lists the enabled options that are not reflected by one of the other fields, e.g. "WithAutoStarted"
+	EnabledOptions       []string                 `json:"enabledOptions,omitempty"`
+	ReceiveBuffer        int                      `json:"receiveBuffer,omitempty"`
+	SelfCheckInterval    time.Duration            `json:"selfCheckInterval,omitempty"`
+	ActivationTimeout    time.Duration            `json:"activationTimeout,omitempty"`
+	SubscriptionDebounce time.Duration            `json:"subscriptionDebounce,omitempty"`
+	HandlerTimeout       time.Duration            `json:"handlerTimeout,omitempty"`
+	SubscriptionTimeouts map[string]time.Duration `json:"subscriptionTimeouts,omitempty"`
+	HandlerAttempts      int                      `json:"handlerAttempts,omitempty"`
+	MaxDeliveries        int                      `json:"maxDeliveries,omitempty"`
+	MaxEventSize         int                      `json:"maxEventSize,omitempty"`
+	MaxEventsHandled     int                      `json:"maxEventsHandled,omitempty"`
+	MaxConcurrentSends   int                      `json:"maxConcurrentSends,omitempty"`
+	BatchSize            int                      `json:"batchSize,omitempty"`
+	BatchWindow          time.Duration            `json:"batchWindow,omitempty"`
+	WarmupBufferSize     int                      `json:"warmupBufferSize,omitempty"`
+	EventPriorities      map[string]int           `json:"eventPriorities,omitempty"`
+	SamplingRatios       map[string]float64       `json:"samplingRatios,omitempty"`
+	OrderedSubjects      []string                 `json:"orderedSubjects,omitempty"`
+	StormRatePerSec      float64                  `json:"stormRatePerSec,omitempty"`
+	StormWindow          time.Duration            `json:"stormWindow,omitempty"`
+	// ResultEventType is the type of the events published after every handled event
+	ResultEventType string `json:"resultEventType,omitempty"`
+}
+
+// Describe returns a snapshot of the effective configuration and the current runtime state of the ControlPlane
+func (cp *ControlPlane) Describe() ControlPlaneState {
+	cp.subscriptionsMtx.RLock()
+	subscriptions := append([]models.EventSubscription{}, cp.currentSubscriptions...)
+	cp.subscriptionsMtx.RUnlock()
+	return ControlPlaneState{
+		Registered:           cp.IsRegistered(),
+		IntegrationID:        cp.integrationID,
+		Subscriptions:        subscriptions,
+		MutedSubjects:        cp.MutedSubjects(),
+		EnabledOptions:       cp.enabledOptions(),
+		ReceiveBuffer:        cp.receiveBuffer,
+		SelfCheckInterval:    cp.selfCheckInterval,
+		ActivationTimeout:    cp.activationTimeout,
+		SubscriptionDebounce: cp.subscriptionDebounce,
+		HandlerTimeout:       cp.handlerTimeout,
+		SubscriptionTimeouts: copyMap(cp.subscriptionTimeouts),
+		HandlerAttempts:      cp.handlerAttempts,
+		MaxDeliveries:        cp.maxDeliveries,
+		MaxEventSize:         cp.maxEventSize,
+		MaxEventsHandled:     cp.maxEventsHandled,
+		MaxConcurrentSends:   cp.maxConcurrentSends,
+		BatchSize:            cp.batchSize,
+		BatchWindow:          cp.batchWindow,
+		WarmupBufferSize:     cp.warmupBufferSize,
+		EventPriorities:      copyMap(cp.eventPriorities),
+		SamplingRatios:       copyMap(cp.samplingRatios),
+		OrderedSubjects:      sortedKeys(cp.orderedSubjects),
+		StormRatePerSec:      cp.stormRate,
+		StormWindow:          cp.stormWindow,
+		ResultEventType:      cp.resultEventSubject,
+	}
+}
+
+// enabledOptions returns the sorted names of the enabled options that have no dedicated field in ControlPlaneState
+func (cp *ControlPlane) enabledOptions() []string {
+	enabled := map[string]bool{
+		"WithAckPolicy":                cp.ackPolicy != nil,
+		"WithActiveWindows":            len(cp.activeWindows) > 0,
+		"WithAuditSink":                cp.auditSink != nil,
+		"WithAutoFinishedOnError":      cp.autoFinishedOnError,
+		"WithAutoStarted":              cp.autoStarted,
+		"WithConnectionStateHandler":   cp.connectionStateFn != nil,
+		"WithContextDecorator":         cp.contextDecorator != nil,
+		"WithDeadLetterHandler":        cp.deadLetterFn != nil,
+		"WithDryRunSender":             cp.dryRunSender != nil,
+		"WithDropReasonHandler":        cp.dropReasonFn != nil,
+		"WithErrorEventOnFailure":      cp.errorEventOnFailure,
+		"WithEventExtensions":          len(cp.eventExtensions) > 0,
+		"WithEventTransformer":         cp.eventTransformer != nil,
+		"WithFatalClassifier":          cp.fatalClassifier != nil,
+		"WithFilterExpression":         cp.filterExpression != nil,
+		"WithIdempotencyStore":         cp.idempotencyStore != nil,
+		"WithIgnoreSelfEvents":         cp.ignoreSelfEvents,
+		"WithLivenessEvent":            cp.livenessEventFn != nil,
+		"WithMetrics":                  cp.metrics != nil,
+		"WithPausedDispatchOnUpdate":   cp.pauseOnUpdate,
+		"WithReadinessCheck":           cp.readinessCheck != nil,
+		"WithReceiveErrorHandler":      cp.receiveErrorFn != nil,
+		"WithRedeliveryOnReload":       cp.redeliverOnReload,
+		"WithSenderSelector":           cp.senderSelector != nil,
+		"WithStackTraceOnTimeout":      cp.stackTraceOnTimeout,
+		"WithStormLoadShedding":        cp.stormShedding,
+		"WithSubjectMapper":            cp.subjectMapper != nil,
+		"WithSubjectNormalizer":        cp.subjectNormalizer != nil,
+		"WithSubscriptionData":         cp.subscriptionDataFn != nil,
+		"WithSubscriptionDiffHandler":  cp.subscriptionDiffFn != nil,
+		"WithSubscriptionErrorHandler": cp.subscriptionErrorFn != nil,
+		"WithTaskTracking":             cp.tasks != nil,
+		"WithTypePatternMatching":      cp.typePatternMatching,
+		"WithUnmatchedEventBuffer":     cp.unmatchedBufferSize > 0 && cp.unmatchedTTL > 0,
+	}
+	var options []string
+	for option, ok := range enabled {
+		if ok {
+			options = append(options, option)
+		}
+	}
+	sort.Strings(options)
+	return options
+}
+
+func copyMap[K comparable, V any](m map[K]V) map[K]V {
+	if m == nil {
+		return nil
+	}
+	c := make(map[K]V, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,58 @@
+package controlplane
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneDescribe(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil,
+		WithAutoStarted(true),
+		WithHandlerTimeout(time.Minute),
+		WithHandlerRetry(3, nil),
+		WithEventPriority(map[string]int{"sh.keptn.event.rollback.triggered": 10}),
+		WithStormProtection(100, time.Second),
+	)
+	state := controlPlane.Describe()
+	require.False(t, state.Registered)
+	require.Empty(t, state.Subscriptions)
+	require.Equal(t, []string{"WithAutoStarted"}, state.EnabledOptions)
+	require.Equal(t, time.Minute, state.HandlerTimeout)
+	require.Equal(t, 3, state.HandlerAttempts)
+	require.Equal(t, time.Minute, state.ActivationTimeout)
+	require.Equal(t, map[string]int{"sh.keptn.event.rollback.triggered": 10}, state.EventPriorities)
+	require.Equal(t, float64(100), state.StormRatePerSec)
+
+	integration := ExampleIntegration{RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} }}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	_, subsChan := sources.channels(t)
+	subscriptions := []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	subsChan <- subscriptions
+	controlPlane.Mute("sh.keptn.event.echo.triggered")
+
+	require.Eventually(t, func() bool {
+		return len(controlPlane.Describe().Subscriptions) == 1
+	}, time.Second, 10*time.Millisecond)
+	state = controlPlane.Describe()
+	require.True(t, state.Registered)
+	require.Equal(t, "some-id", state.IntegrationID)
+	require.Equal(t, subscriptions, state.Subscriptions)
+	require.Equal(t, []string{"sh.keptn.event.echo.triggered"}, state.MutedSubjects)
+
+	// the snapshot does not share the maps of the control plane
+	state.EventPriorities["sh.keptn.event.rollback.triggered"] = 0
+	require.Equal(t, 10, controlPlane.Describe().EventPriorities["sh.keptn.event.rollback.triggered"])
+
+	serialized, err := json.Marshal(state)
+	require.NoError(t, err)
+	require.Contains(t, string(serialized), `"integrationID":"some-id"`)
+}
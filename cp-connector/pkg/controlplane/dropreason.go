@@ -0,0 +1,78 @@
+package controlplane
+
+import "github.com/keptn/go-utils/pkg/api/models"
+
+// DropReason describes why a received event was not forwarded to the integration
+type DropReason int
+
+const (
+	// DropReasonNoSubscription is reported for events whose subject does not belong to any subscription
+	DropReasonNoSubscription DropReason = iota
+	// DropReasonFilterMismatch is reported for events not matching the filter of any subscription for their subject
+	DropReasonFilterMismatch
+	// DropReasonMissingContext is reported for events without a keptn context if DropMissingContext is used
+	DropReasonMissingContext
+	// DropReasonSelfEvent is reported for events produced by the integration itself if WithIgnoreSelfEvents is used
+	DropReasonSelfEvent
+	// DropReasonMaxSizeExceeded is reported for events exceeding the size configured via WithMaxEventSize
+	DropReasonMaxSizeExceeded
+	// DropReasonNotSampled is reported for events not being part of the sample configured via WithEventSampling
+	DropReasonNotSampled
+	// DropReasonDuplicate is reported for events that were already handled according to the idempotency store
+	DropReasonDuplicate
+	// DropReasonTransformFailed is reported for events the event transformer returned an error for
+	DropReasonTransformFailed
+	// DropReasonMuted is reported for events received on a subject muted via ControlPlane.Mute
+	DropReasonMuted
+	// DropReasonOutsideActiveWindow is reported for events received outside the windows configured via WithActiveWindows
+	DropReasonOutsideActiveWindow
+	// DropReasonFilterExpressionMismatch is reported for events not matching the expression configured via WithFilterExpression
+	DropReasonFilterExpressionMismatch
+)
+
+func (r DropReason) String() string {
+	switch r {
+	case DropReasonNoSubscription:
+		return "no subscription"
+	case DropReasonFilterMismatch:
+		return "filter mismatch"
+	case DropReasonMissingContext:
+		return "missing context"
+	case DropReasonSelfEvent:
+		return "self event"
+	case DropReasonMaxSizeExceeded:
+		return "max size exceeded"
+	case DropReasonNotSampled:
+		return "not sampled"
+	case DropReasonDuplicate:
+		return "duplicate"
+	case DropReasonTransformFailed:
+		return "transform failed"
+	case DropReasonMuted:
+		return "muted"
+	case DropReasonOutsideActiveWindow:
+		return "outside active window"
+	case DropReasonFilterExpressionMismatch:
+		return "filter expression mismatch"
+	default:
+		return "unknown"
+	}
+}
+
+// DropReasonHandlerFn is called for every received event that is not forwarded to the integration
+type DropReasonHandlerFn func(models.KeptnContextExtendedCE, DropReason)
+
+// WithDropReasonHandler sets a function that is called with the reason whenever a received event is not forwarded
+// to the integration, e.g. to debug why an integration does not receive an event
+func WithDropReasonHandler(handler DropReasonHandlerFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.dropReasonFn = handler
+	}
+}
+
+func (cp *ControlPlane) dropped(event models.KeptnContextExtendedCE, reason DropReason) {
+	cp.stats.observeDropped(reason)
+	if cp.dropReasonFn != nil {
+		cp.dropReasonFn(event, reason)
+	}
+}
@@ -0,0 +1,164 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneDropReasons(t *testing.T) {
+	const subject = "sh.keptn.event.echo.triggered"
+	seenStore := NewInMemoryIdempotencyStore(10)
+	require.NoError(t, seenStore.MarkSeen("event-id"))
+
+	tests := []struct {
+		name   string
+		opts   []func(*ControlPlane)
+		update func(*types.EventUpdate)
+		want   DropReason
+	}{
+		{
+			name:   "no subscription",
+			update: func(u *types.EventUpdate) { u.MetaData.Subject = "sh.keptn.event.other.triggered" },
+			want:   DropReasonNoSubscription,
+		},
+		{
+			name:   "filter mismatch",
+			update: func(u *types.EventUpdate) { u.KeptnEvent.Data = keptnv2.EventData{Project: "other-project"} },
+			want:   DropReasonFilterMismatch,
+		},
+		{
+			name:   "missing context",
+			opts:   []func(*ControlPlane){WithMissingContextPolicy(DropMissingContext)},
+			update: func(u *types.EventUpdate) { u.KeptnEvent.Shkeptncontext = "" },
+			want:   DropReasonMissingContext,
+		},
+		{
+			name:   "self event",
+			opts:   []func(*ControlPlane){WithIgnoreSelfEvents(true)},
+			update: func(u *types.EventUpdate) { u.KeptnEvent.Source = strutils.Stringp("my-service") },
+			want:   DropReasonSelfEvent,
+		},
+		{
+			name:   "max size exceeded",
+			opts:   []func(*ControlPlane){WithMaxEventSize(10)},
+			update: func(u *types.EventUpdate) { u.MetaData.Size = 100 },
+			want:   DropReasonMaxSizeExceeded,
+		},
+		{
+			name: "not sampled",
+			opts: []func(*ControlPlane){WithEventSampling(map[string]float64{subject: 0})},
+			want: DropReasonNotSampled,
+		},
+		{
+			name: "duplicate",
+			opts: []func(*ControlPlane){WithIdempotencyStore(seenStore)},
+			want: DropReasonDuplicate,
+		},
+		{
+			name: "muted",
+			opts: []func(*ControlPlane){func(cp *ControlPlane) { cp.Mute(subject) }},
+			want: DropReasonMuted,
+		},
+		{
+			name: "outside active window",
+			opts: []func(*ControlPlane){WithClock(clock.NewMock()), WithActiveWindows([]TimeWindow{{Start: 9 * time.Hour, End: 17 * time.Hour}})},
+			want: DropReasonOutsideActiveWindow,
+		},
+		{
+			name: "filter expression mismatch",
+			opts: []func(*ControlPlane){WithFilterExpression(`data.project == "other-project"`)},
+			want: DropReasonFilterExpressionMismatch,
+		},
+		{
+			name: "transform failed",
+			opts: []func(*ControlPlane){WithEventTransformer(func(ce models.KeptnContextExtendedCE) (models.KeptnContextExtendedCE, error) {
+				return ce, errors.New("invalid event")
+			})},
+			want: DropReasonTransformFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := newFakeSources()
+			reasons := make(chan DropReason, 1)
+			opts := append([]func(*ControlPlane){WithDropReasonHandler(func(ce models.KeptnContextExtendedCE, reason DropReason) {
+				require.Equal(t, "event-id", ce.ID)
+				reasons <- reason
+			})}, tt.opts...)
+			controlPlane := New(sources.ssm, sources.esm, nil, opts...)
+			integration := ExampleIntegration{
+				RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{Name: "my-service"} },
+				OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+					require.FailNow(t, "unexpected call of OnEvent")
+					return nil
+				},
+			}
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			go func() { _ = controlPlane.Register(ctx, integration) }()
+			eventChan, subsChan := sources.channels(t)
+			subsChan <- []models.EventSubscription{{
+				ID:     "sub-1",
+				Event:  subject,
+				Filter: models.EventSubscriptionFilter{Projects: []string{"my-project"}},
+			}}
+			update := types.EventUpdate{
+				KeptnEvent: models.KeptnContextExtendedCE{
+					ID:             "event-id",
+					Shkeptncontext: "keptn-context",
+					Type:           strutils.Stringp(subject),
+					Data:           keptnv2.EventData{Project: "my-project"},
+				},
+				MetaData: types.EventUpdateMetaData{Subject: subject},
+			}
+			if tt.update != nil {
+				tt.update(&update)
+			}
+			eventChan <- update
+
+			select {
+			case reason := <-reasons:
+				require.Equal(t, tt.want, reason)
+			case <-time.After(time.Second):
+				require.FailNow(t, "drop reason was not reported")
+			}
+		})
+	}
+}
+
+func TestControlPlaneNoDropReasonForForwardedEvent(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithDropReasonHandler(func(ce models.KeptnContextExtendedCE, reason DropReason) {
+		require.FailNow(t, "unexpected drop reason", reason.String())
+	}))
+	handled := make(chan string, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			handled <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	require.Equal(t, "event-id", <-handled)
+}
+
+func TestDropReasonString(t *testing.T) {
+	require.Equal(t, "no subscription", DropReasonNoSubscription.String())
+	require.Equal(t, "transform failed", DropReasonTransformFailed.String())
+	require.Equal(t, "unknown", DropReason(-1).String())
+}
@@ -0,0 +1,42 @@
+package controlplane
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// WithDryRunSender configures the ControlPlane to write the events sent by the integration to the given writer
+// as one JSON document per line, instead of sending them via the event source or a selected sender, e.g. to develop
+// an integration locally without a running Keptn. If the writer is nil, the events are written to stdout
+func WithDryRunSender(w io.Writer) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		if w == nil {
+			w = os.Stdout
+		}
+		ns.dryRunSender = newDryRunSender(w)
+	}
+}
+
+// newDryRunSender returns a sender writing the events to the given writer. Writes of concurrently
+// sent events are serialized, so that the lines of the events do not interleave
+func newDryRunSender(w io.Writer) types.EventSender {
+	var mtx sync.Mutex
+	return func(ce models.KeptnContextExtendedCE) error {
+		serialized, err := json.Marshal(ce)
+		if err != nil {
+			return fmt.Errorf("could not serialize event of type %s: %w", eventType(ce), err)
+		}
+		mtx.Lock()
+		defer mtx.Unlock()
+		if _, err := w.Write(append(serialized, '\n')); err != nil {
+			return fmt.Errorf("could not write event of type %s: %w", eventType(ce), err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,57 @@
+package controlplane
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneDryRunSender(t *testing.T) {
+	sources := newFakeSources()
+	out := &bytes.Buffer{}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithDryRunSender(out), WithSenderSelector(func(event models.KeptnContextExtendedCE) types.EventSender {
+		return func(ce models.KeptnContextExtendedCE) error {
+			require.FailNow(t, "unexpected call of selected sender")
+			return nil
+		}
+	}))
+
+	sendErr := make(chan error, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			sender := ctx.Value(types.EventSenderKey).(types.EventSender)
+			if err := sender(models.KeptnContextExtendedCE{ID: "started-id", Type: strutils.Stringp("sh.keptn.event.echo.started")}); err != nil {
+				sendErr <- err
+				return nil
+			}
+			sendErr <- sender(models.KeptnContextExtendedCE{ID: "finished-id", Type: strutils.Stringp("sh.keptn.event.echo.finished")})
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	require.NoError(t, <-sendErr)
+
+	var written []models.KeptnContextExtendedCE
+	decoder := json.NewDecoder(out)
+	for decoder.More() {
+		var ce models.KeptnContextExtendedCE
+		require.NoError(t, decoder.Decode(&ce))
+		written = append(written, ce)
+	}
+	require.Len(t, written, 2)
+	require.Equal(t, "started-id", written[0].ID)
+	require.Equal(t, "sh.keptn.event.echo.finished", *written[1].Type)
+	require.Empty(t, sources.sentEvents())
+}
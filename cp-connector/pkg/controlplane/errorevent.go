@@ -0,0 +1,51 @@
+package controlplane
+
+import (
+	"context"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// errorEventData contains the data of an error log event and the context of the event whose handling failed,
+// so that the error event can be routed by project, stage and service
+type errorEventData struct {
+	keptnv2.ErrorLogEvent
+	Project string `json:"project,omitempty"`
+	Stage   string `json:"stage,omitempty"`
+	Service string `json:"service,omitempty"`
+}
+
+// sendErrorEvent publishes an event describing why the handling of the given event failed.
+// By default, the event is of type sh.keptn.log.error, which is picked up by the log forwarder
+func (cp *ControlPlane) sendErrorEvent(ctx context.Context, event models.KeptnContextExtendedCE, handlingErr error, sender types.EventSender) {
+	log := cp.eventLogger(ctx)
+	subject := cp.errorEventSubject
+	if subject == "" {
+		subject = keptnv2.ErrorLogEventName
+	}
+	data := errorEventData{
+		ErrorLogEvent: keptnv2.ErrorLogEvent{
+			Message:       handlingErr.Error(),
+			IntegrationID: cp.integrationID,
+		},
+	}
+	if taskName, _, err := keptnv2.ParseTaskEventType(eventType(event)); err == nil {
+		data.Task = taskName
+	}
+	eventData := keptnv2.EventData{}
+	if err := keptnv2.EventDataAs(event, &eventData); err == nil {
+		data.Project = eventData.Project
+		data.Stage = eventData.Stage
+		data.Service = eventData.Service
+	}
+	errorEvent := keptnv2.KeptnEvent(subject, cp.integrationName, data).
+		WithKeptnContext(event.Shkeptncontext).
+		WithTriggeredID(event.ID).
+		KeptnContextExtendedCE
+	log.Debugf("Sending %s event for event %s", subject, event.ID)
+	if err := sender(errorEvent); err != nil {
+		log.Warnf("Could not send %s event: %v", subject, err)
+	}
+}
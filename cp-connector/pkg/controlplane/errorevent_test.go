@@ -0,0 +1,98 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneErrorEventOnFailure(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []func(*ControlPlane)
+		wantSubject string
+	}{
+		{
+			name:        "default subject",
+			opts:        []func(*ControlPlane){WithErrorEventOnFailure(true)},
+			wantSubject: keptnv2.ErrorLogEventName,
+		},
+		{
+			name:        "custom subject",
+			opts:        []func(*ControlPlane){WithErrorEventOnFailure(true), WithErrorEventSubject("my.org.alerts.handler-failed")},
+			wantSubject: "my.org.alerts.handler-failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := newFakeSources()
+			controlPlane := New(sources.ssm, sources.esm, nil, tt.opts...)
+			integration := ExampleIntegration{
+				RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{Name: "my-service"} },
+				OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+					return errors.New("handler failed")
+				},
+			}
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			go func() { _ = controlPlane.Register(ctx, integration) }()
+			eventChan, subsChan := sources.channels(t)
+			subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+			eventChan <- types.EventUpdate{
+				KeptnEvent: models.KeptnContextExtendedCE{
+					ID:             "triggered-id",
+					Shkeptncontext: "keptn-context",
+					Type:           strutils.Stringp("sh.keptn.event.echo.triggered"),
+					Data:           keptnv2.EventData{Project: "my-project", Stage: "dev", Service: "svc"},
+				},
+				MetaData: types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+			}
+
+			require.Eventually(t, func() bool { return len(sources.sentEvents()) == 1 }, time.Second, 10*time.Millisecond)
+			errorEvent := sources.sentEvents()[0]
+			require.Equal(t, tt.wantSubject, *errorEvent.Type)
+			require.Equal(t, "keptn-context", errorEvent.Shkeptncontext)
+			require.Equal(t, "triggered-id", errorEvent.Triggeredid)
+			require.Equal(t, "my-service", *errorEvent.Source)
+
+			data := keptnv2.ErrorLogEvent{}
+			require.NoError(t, keptnv2.EventDataAs(errorEvent, &data))
+			require.Equal(t, keptnv2.ErrorLogEvent{Message: "handler failed", IntegrationID: "some-id", Task: "echo"}, data)
+			eventData := keptnv2.EventData{}
+			require.NoError(t, keptnv2.EventDataAs(errorEvent, &eventData))
+			require.Equal(t, "my-project", eventData.Project)
+			require.Equal(t, "dev", eventData.Stage)
+			require.Equal(t, "svc", eventData.Service)
+		})
+	}
+}
+
+func TestControlPlaneNoErrorEventByDefault(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithErrorEventSubject("my.org.alerts.handler-failed"))
+	handled := make(chan struct{})
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			close(handled)
+			return errors.New("handler failed")
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	<-handled
+	// the next event can only be received once the failed event was completely handled
+	eventChan <- eventUpdate("other-event-id", "sh.keptn.event.other.triggered")
+	cancel()
+	require.Empty(t, sources.sentEvents())
+}
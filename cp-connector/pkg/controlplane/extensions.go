@@ -0,0 +1,44 @@
+package controlplane
+
+import "github.com/keptn/go-utils/pkg/api/models"
+
+// WithEventExtensions configures the ControlPlane to add the given extensions (e.g. trace or tenant IDs) to every
+// event sent via the sender of the event source, including the events sent on behalf of the integration.
+// Extensions already set on the sent event take precedence over the given ones
+func WithEventExtensions(extensions map[string]interface{}) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.eventExtensions = extensions
+	}
+}
+
+// addExtensions returns the event with the configured extensions merged into its extensions.
+// The extensions of the given event are not modified
+func (cp *ControlPlane) addExtensions(event models.KeptnContextExtendedCE) models.KeptnContextExtendedCE {
+	return cp.mergeExtensions(event, cp.eventExtensions)
+}
+
+// mergeExtensions returns the event with the given extensions merged into its extensions.
+// Extensions already set on the event take precedence, the extensions of the given event are not modified
+func (cp *ControlPlane) mergeExtensions(event models.KeptnContextExtendedCE, extensions map[string]interface{}) models.KeptnContextExtendedCE {
+	if len(extensions) == 0 {
+		return event
+	}
+	var own map[string]interface{}
+	switch eventExtensions := event.Extensions.(type) {
+	case nil:
+	case map[string]interface{}:
+		own = eventExtensions
+	default:
+		cp.logger.Warnf("Could not add extensions to event of type %s: extensions of type %T are not supported", eventType(event), event.Extensions)
+		return event
+	}
+	merged := make(map[string]interface{}, len(extensions)+len(own))
+	for k, v := range extensions {
+		merged[k] = v
+	}
+	for k, v := range own {
+		merged[k] = v
+	}
+	event.Extensions = merged
+	return event
+}
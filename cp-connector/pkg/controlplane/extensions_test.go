@@ -0,0 +1,53 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneEventExtensions(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithAutoStarted(true), WithEventExtensions(map[string]interface{}{
+		"tenantid": "tenant-a",
+		"traceid":  "default-trace",
+	}))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			sender := ctx.Value(types.EventSenderKey).(types.EventSender)
+			return sender(models.KeptnContextExtendedCE{
+				ID:         "finished-id",
+				Type:       strutils.Stringp("sh.keptn.event.echo.finished"),
+				Extensions: map[string]interface{}{"traceid": "trace-1"},
+			})
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- eventUpdate("triggered-id", "sh.keptn.event.echo.triggered")
+
+	require.Eventually(t, func() bool { return len(sources.sentEvents()) == 2 }, time.Second, 10*time.Millisecond)
+	sent := sources.sentEvents()
+	require.Equal(t, "sh.keptn.event.echo.started", *sent[0].Type)
+	require.Equal(t, map[string]interface{}{"tenantid": "tenant-a", "traceid": "default-trace"}, sent[0].Extensions)
+	require.Equal(t, "finished-id", sent[1].ID)
+	require.Equal(t, map[string]interface{}{"tenantid": "tenant-a", "traceid": "trace-1", SubscriptionIDExtension: "sub-1"}, sent[1].Extensions)
+}
+
+func TestControlPlaneAddExtensionsKeepsEvent(t *testing.T) {
+	controlPlane := New(nil, nil, nil, WithEventExtensions(map[string]interface{}{"tenantid": "tenant-a"}))
+	own := map[string]interface{}{"traceid": "trace-1"}
+
+	event := controlPlane.addExtensions(models.KeptnContextExtendedCE{Extensions: own})
+	require.Equal(t, map[string]interface{}{"tenantid": "tenant-a", "traceid": "trace-1"}, event.Extensions)
+	require.Equal(t, map[string]interface{}{"traceid": "trace-1"}, own, "the extensions of the sent event must not be modified")
+}
@@ -0,0 +1,47 @@
+package controlplane
+
+import (
+	"errors"
+)
+
+// FatalClassifierFn decides whether an error returned by the integration is fatal
+type FatalClassifierFn func(err error) bool
+
+// WithFatalClassifier sets a function declaring which errors returned by the integration are fatal, e.g. errors
+// of third-party libraries identified by their type or code. Fatal errors are treated like errors wrapping
+// ErrEventHandleFatal, i.e. they are not retried and stop the ControlPlane. Errors wrapping ErrEventHandleFatal
+// remain fatal, regardless of the classifier
+func WithFatalClassifier(classifier FatalClassifierFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.fatalClassifier = classifier
+	}
+}
+
+// fatalError marks an error classified as fatal by the FatalClassifierFn, keeping the original error
+// available to errors.Is and errors.As
+type fatalError struct {
+	err error
+}
+
+func (e fatalError) Error() string {
+	return e.err.Error()
+}
+
+func (e fatalError) Unwrap() error {
+	return e.err
+}
+
+func (e fatalError) Is(target error) bool {
+	return target == ErrEventHandleFatal
+}
+
+// classifyFatal marks the error returned by the integration as fatal if the configured FatalClassifierFn says so
+func (cp *ControlPlane) classifyFatal(err error) error {
+	if err == nil || cp.fatalClassifier == nil || errors.Is(err, ErrEventHandleFatal) {
+		return err
+	}
+	if cp.fatalClassifier(err) {
+		return fatalError{err: err}
+	}
+	return err
+}
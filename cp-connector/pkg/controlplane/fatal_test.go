@@ -0,0 +1,78 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+// quotaError is an error of a third-party library that is identified by its code
+type quotaError struct {
+	code int
+}
+
+func (e quotaError) Error() string {
+	return fmt.Sprintf("quota error %d", e.code)
+}
+
+func TestControlPlaneWithFatalClassifier(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithHandlerRetry(3, nil), WithFatalClassifier(func(err error) bool {
+		var qe quotaError
+		return errors.As(err, &qe) && qe.code == 429
+	}))
+	calls := make(chan string, 10)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			calls <- ce.ID
+			if ce.ID == "non-fatal" {
+				return quotaError{code: 500}
+			}
+			return fmt.Errorf("could not call API: %w", quotaError{code: 429})
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	registerErr := make(chan error, 1)
+	go func() { registerErr <- controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- eventUpdate("non-fatal", "sh.keptn.event.echo.triggered")
+	eventChan <- eventUpdate("fatal", "sh.keptn.event.echo.triggered")
+
+	select {
+	case err := <-registerErr:
+		require.ErrorIs(t, err, ErrEventHandleFatal)
+		var qe quotaError
+		require.ErrorAs(t, err, &qe)
+		require.Equal(t, 429, qe.code)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "fatal error did not stop the control plane")
+	}
+	close(calls)
+	var handled []string
+	for id := range calls {
+		handled = append(handled, id)
+	}
+	// the non-fatal error is retried, the fatal one is not
+	require.Equal(t, []string{"non-fatal", "non-fatal", "non-fatal", "fatal"}, handled)
+}
+
+func TestClassifyFatal(t *testing.T) {
+	cp := New(nil, nil, nil)
+	err := errors.New("some error")
+	require.Equal(t, err, cp.classifyFatal(err))
+	require.Nil(t, cp.classifyFatal(nil))
+
+	cp = New(nil, nil, nil, WithFatalClassifier(func(err error) bool { return false }))
+	fatal := fmt.Errorf("could not handle event: %w", ErrEventHandleFatal)
+	require.ErrorIs(t, cp.classifyFatal(fatal), ErrEventHandleFatal)
+	require.NotErrorIs(t, cp.classifyFatal(err), ErrEventHandleFatal)
+}
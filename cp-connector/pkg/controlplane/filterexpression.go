@@ -0,0 +1,15 @@
+package controlplane
+
+import (
+	"github.com/keptn/keptn/cp-connector/pkg/eventmatcher"
+)
+
+// WithFilterExpression configures the ControlPlane to only forward events matching the given filter expression,
+// in addition to matching a subscription, e.g. `data.service == "checkout" && type endswith ".triggered"`.
+// See eventmatcher.FilterExpression for the syntax. If the syntax of the expression is invalid, the
+// integration is not registered and Register returns the error
+func WithFilterExpression(expression string) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.filterExpression, ns.filterExpressionErr = eventmatcher.ParseFilterExpression(expression)
+	}
+}
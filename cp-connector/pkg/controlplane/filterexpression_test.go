@@ -0,0 +1,55 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneWithFilterExpression(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithFilterExpression(`data.service == "checkout" && type endswith ".triggered"`))
+	handled := make(chan string, 2)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			handled <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	carts := eventUpdate("carts-event", "sh.keptn.event.echo.triggered")
+	carts.KeptnEvent.Data = keptnv2.EventData{Service: "carts"}
+	eventChan <- carts
+	checkout := eventUpdate("checkout-event", "sh.keptn.event.echo.triggered")
+	checkout.KeptnEvent.Data = keptnv2.EventData{Service: "checkout"}
+	eventChan <- checkout
+
+	select {
+	case id := <-handled:
+		require.Equal(t, "checkout-event", id)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "matching event was not forwarded")
+	}
+	require.Empty(t, handled)
+}
+
+func TestControlPlaneWithInvalidFilterExpression(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithFilterExpression(`data.service == "checkout" &&`))
+	err := controlPlane.Register(context.TODO(), ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+	})
+	require.ErrorContains(t, err, "invalid filter expression")
+	require.False(t, controlPlane.IsRegistered())
+}
@@ -0,0 +1,99 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/logger"
+)
+
+// maxStackDumpSize is the maximum size of the stack dump logged if a handler exceeds its timeout
+const maxStackDumpSize = 8 << 20
+
+// WithHandlerTimeout configures the ControlPlane to cancel the context passed to the integration once the handling
+// of an event took longer than the given timeout, including retries configured via WithHandlerRetry
+func WithHandlerTimeout(timeout time.Duration) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.handlerTimeout = timeout
+	}
+}
+
+// WithSubscriptionTimeouts configures handler timeouts for the events of specific subscriptions, overriding the
+// timeout configured via WithHandlerTimeout. The timeouts are keyed by the event of the subscription, which can
+// also be a subject pattern like "sh.keptn.event.*.triggered". Exact subjects take precedence over patterns.
+// If multiple patterns match, the shortest timeout is used
+func WithSubscriptionTimeouts(timeouts map[string]time.Duration) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.subscriptionTimeouts = timeouts
+	}
+}
+
+// handlerTimeoutFor returns the handler timeout for events of the given subscription. A timeout <= 0 means that
+// the handling is not limited
+func (cp *ControlPlane) handlerTimeoutFor(subscription models.EventSubscription) time.Duration {
+	if timeout, ok := cp.subscriptionTimeouts[subscription.Event]; ok {
+		return timeout
+	}
+	timeout, matched := cp.handlerTimeout, false
+	for pattern, t := range cp.subscriptionTimeouts {
+		if matchSubject(pattern, subscription.Event) && (!matched || t < timeout) {
+			timeout, matched = t, true
+		}
+	}
+	return timeout
+}
+
+// withHandlerTimeout derives the context passed to the integration for events of the given subscription
+func (cp *ControlPlane) withHandlerTimeout(ctx context.Context, subscription models.EventSubscription) (context.Context, context.CancelFunc) {
+	if timeout := cp.handlerTimeoutFor(subscription); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
+}
+
+// WithStackTraceOnTimeout configures the ControlPlane to log the stacks of all goroutines, tagged with the ID of the
+// event, if the handling of an event exceeds the timeout configured via WithHandlerTimeout or WithSubscriptionTimeouts.
+// This shows where a handler is stuck
+func WithStackTraceOnTimeout(enabled bool) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.stackTraceOnTimeout = enabled
+	}
+}
+
+// dumpStacksOnTimeout logs the stacks of all goroutines if the deadline of the given context expires before the
+// returned function is called
+func (cp *ControlPlane) dumpStacksOnTimeout(ctx context.Context, log logger.Logger, eventID string) func() {
+	if _, ok := ctx.Deadline(); !ok || !cp.stackTraceOnTimeout {
+		return func() {}
+	}
+	finished := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-finished:
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				log.Warnf("Handling of event %s exceeded its timeout, stacks of all goroutines:\n%s", eventID, goroutineStacks())
+			}
+		}
+	}()
+	return func() {
+		close(finished)
+		<-stopped
+	}
+}
+
+func goroutineStacks() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxStackDumpSize {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
@@ -0,0 +1,126 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	fake2 "github.com/keptn/keptn/cp-connector/pkg/fake"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneSubscriptionTimeouts(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithHandlerTimeout(5*time.Minute), WithSubscriptionTimeouts(map[string]time.Duration{
+		"sh.keptn.event.deployment.triggered": 10 * time.Minute,
+		"sh.keptn.event.test.triggered":       time.Minute,
+	}))
+	type deadline struct {
+		remaining time.Duration
+		ok        bool
+	}
+	deadlines := make(chan deadline)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			d, ok := ctx.Deadline()
+			deadlines <- deadline{remaining: time.Until(d), ok: ok}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{
+		{ID: "sub-1", Event: "sh.keptn.event.deployment.triggered"},
+		{ID: "sub-2", Event: "sh.keptn.event.test.triggered"},
+		{ID: "sub-3", Event: "sh.keptn.event.evaluation.triggered"},
+	}
+
+	tests := []struct {
+		subject string
+		want    time.Duration
+	}{
+		{subject: "sh.keptn.event.deployment.triggered", want: 10 * time.Minute},
+		{subject: "sh.keptn.event.test.triggered", want: time.Minute},
+		{subject: "sh.keptn.event.evaluation.triggered", want: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		eventChan <- eventUpdate("event-id", tt.subject)
+		d := <-deadlines
+		require.True(t, d.ok, tt.subject)
+		require.LessOrEqual(t, d.remaining, tt.want, tt.subject)
+		require.Greater(t, d.remaining, tt.want-time.Second, tt.subject)
+	}
+}
+
+func TestControlPlaneWithoutHandlerTimeout(t *testing.T) {
+	controlPlane := New(nil, nil, nil)
+	ctx, cancel := controlPlane.withHandlerTimeout(context.TODO(), models.EventSubscription{Event: "sh.keptn.event.test.triggered"})
+	defer cancel()
+	_, ok := ctx.Deadline()
+	require.False(t, ok)
+}
+
+func TestControlPlaneHandlerTimeoutFor(t *testing.T) {
+	controlPlane := New(nil, nil, nil, WithHandlerTimeout(time.Minute), WithSubscriptionTimeouts(map[string]time.Duration{
+		"sh.keptn.event.test.triggered": 3 * time.Minute,
+		"sh.keptn.event.*.triggered":    2 * time.Minute,
+		"sh.keptn.event.>":              30 * time.Second,
+	}))
+	timeout := func(event string) time.Duration {
+		return controlPlane.handlerTimeoutFor(models.EventSubscription{Event: event})
+	}
+	require.Equal(t, 3*time.Minute, timeout("sh.keptn.event.test.triggered"))
+	require.Equal(t, 30*time.Second, timeout("sh.keptn.event.deployment.triggered"))
+	require.Equal(t, 30*time.Second, timeout("sh.keptn.event.deployment.finished"))
+	require.Equal(t, time.Minute, timeout("other.event"))
+}
+
+func TestControlPlaneStackTraceOnTimeout(t *testing.T) {
+	sources := newFakeSources()
+	log := &fake2.LoggerMock{}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithLogger(log), WithHandlerTimeout(10*time.Millisecond), WithStackTraceOnTimeout(true))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	require.Eventually(t, func() bool {
+		return log.Contains("Handling of event event-id exceeded its timeout") && log.Contains("goroutine ")
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestControlPlaneNoStackTraceWithoutTimeout(t *testing.T) {
+	sources := newFakeSources()
+	log := &fake2.LoggerMock{}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithLogger(log), WithHandlerTimeout(time.Minute), WithStackTraceOnTimeout(true))
+	handled := make(chan string, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			handled <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	require.Equal(t, "event-id", <-handled)
+	cancel()
+	require.False(t, log.Contains("exceeded its timeout"))
+}
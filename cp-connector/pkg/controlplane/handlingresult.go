@@ -0,0 +1,61 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// handlingResultData is the data of the event published for every event handled by the integration
+type handlingResultData struct {
+	EventID        string `json:"eventId"`
+	Subject        string `json:"subject"`
+	SubscriptionID string `json:"subscriptionId,omitempty"`
+	// Outcome is one of "succeeded", "failed" and "fatal", see AuditOutcome
+	Outcome string `json:"outcome"`
+	// DurationMs is the time the integration took to handle the event in milliseconds, including retries
+	DurationMs int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// WithHandlingResultEvents configures the ControlPlane to publish an event of the given type via the event source
+// after every event forwarded to the integration was handled, e.g. so that a sidecar can aggregate the handling
+// results. The event carries the ID and subject of the handled event, the outcome and the duration of the handling.
+// Other than the events of the integration, the result events are not passed on to the log forwarder.
+// An empty type disables the result events, which is the default
+func WithHandlingResultEvents(subject string) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.resultEventSubject = subject
+	}
+}
+
+// sendHandlingResult publishes the result of the handling of the given event, if enabled via WithHandlingResultEvents
+func (cp *ControlPlane) sendHandlingResult(ctx context.Context, eventUpdate types.EventUpdate, subscriptionID string, duration time.Duration, handlingErr error) {
+	if cp.resultEventSubject == "" {
+		return
+	}
+	data := handlingResultData{
+		EventID:        eventUpdate.KeptnEvent.ID,
+		Subject:        eventUpdate.MetaData.Subject,
+		SubscriptionID: subscriptionID,
+		Outcome:        AuditOutcomeSucceeded.String(),
+		DurationMs:     duration.Milliseconds(),
+	}
+	if handlingErr != nil {
+		data.Outcome = AuditOutcomeFailed.String()
+		if errors.Is(handlingErr, ErrEventHandleFatal) {
+			data.Outcome = AuditOutcomeFatal.String()
+		}
+		data.Error = handlingErr.Error()
+	}
+	resultEvent := keptnv2.KeptnEvent(cp.resultEventSubject, cp.integrationName, data).
+		WithKeptnContext(eventUpdate.KeptnEvent.Shkeptncontext).
+		WithTriggeredID(eventUpdate.KeptnEvent.ID).
+		KeptnContextExtendedCE
+	if err := cp.eventSender(resultEvent); err != nil {
+		cp.eventLogger(ctx).Warnf("Could not send %s event for event %s: %v", cp.resultEventSubject, eventUpdate.KeptnEvent.ID, err)
+	}
+}
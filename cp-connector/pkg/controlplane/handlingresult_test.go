@@ -0,0 +1,51 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneHandlingResultEvents(t *testing.T) {
+	sources := newFakeSources()
+	mockClock := clock.NewMock()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithClock(mockClock), WithHandlingResultEvents("sh.keptn.event.handled"))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{Name: "my-service"} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			mockClock.Add(1500 * time.Millisecond)
+			if ce.ID == "failing" {
+				return errors.New("handling failed")
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- eventUpdate("succeeding", "sh.keptn.event.echo.triggered")
+	eventChan <- eventUpdate("failing", "sh.keptn.event.echo.triggered")
+
+	require.Eventually(t, func() bool { return len(sources.sentEvents()) == 2 }, time.Second, 10*time.Millisecond)
+	sent := sources.sentEvents()
+	for _, resultEvent := range sent {
+		require.Equal(t, "sh.keptn.event.handled", *resultEvent.Type)
+		require.Equal(t, "my-service", *resultEvent.Source)
+	}
+	require.Equal(t, "succeeding", sent[0].Triggeredid)
+
+	data := handlingResultData{}
+	require.NoError(t, sent[0].DataAs(&data))
+	require.Equal(t, handlingResultData{EventID: "succeeding", Subject: "sh.keptn.event.echo.triggered", SubscriptionID: "sub-1", Outcome: "succeeded", DurationMs: 1500}, data)
+	data = handlingResultData{}
+	require.NoError(t, sent[1].DataAs(&data))
+	require.Equal(t, handlingResultData{EventID: "failing", Subject: "sh.keptn.event.echo.triggered", SubscriptionID: "sub-1", Outcome: "failed", DurationMs: 1500, Error: "handling failed"}, data)
+}
@@ -0,0 +1,62 @@
+package controlplane
+
+import (
+	"context"
+
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// resourcesUnknown is reported for sources not implementing types.ResourceReporter
+const resourcesUnknown = -1
+
+// HealthStatus describes the current state of the ControlPlane
+type HealthStatus struct {
+	// Registered is true if the ControlPlane is registered and receiving events
+	Registered bool
+	// IntegrationID is the ID assigned to the integration during registration
+	IntegrationID string
+	// EventSourceResources is the number of resources (e.g. broker subscriptions, goroutines) owned by the event source.
+	// It is -1 if the event source does not report its resources
+	EventSourceResources int
+	// SubscriptionSourceResources is the number of resources owned by the subscription source.
+	// It is -1 if the subscription source does not report its resources
+	SubscriptionSourceResources int
+}
+
+// Health returns the current HealthStatus of the ControlPlane
+func (cp *ControlPlane) Health() HealthStatus {
+	return HealthStatus{
+		Registered:                  cp.IsRegistered(),
+		IntegrationID:               cp.integrationID,
+		EventSourceResources:        activeResources(cp.eventSource),
+		SubscriptionSourceResources: activeResources(cp.subscriptionSource),
+	}
+}
+
+// runSelfCheck periodically logs the resources owned by the sources until the context is cancelled.
+// The returned channel is closed as soon as the self check stopped
+func (cp *ControlPlane) runSelfCheck(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := cp.clock.Ticker(cp.selfCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				health := cp.Health()
+				cp.logger.Debugf("Self check: event source owns %d resources, subscription source owns %d resources", health.EventSourceResources, health.SubscriptionSourceResources)
+			}
+		}
+	}()
+	return done
+}
+
+func activeResources(source interface{}) int {
+	if reporter, ok := source.(types.ResourceReporter); ok {
+		return reporter.ActiveResources()
+	}
+	return resourcesUnknown
+}
@@ -0,0 +1,82 @@
+package controlplane
+
+import (
+	"sync"
+
+	"github.com/keptn/keptn/cp-connector/pkg/logger"
+)
+
+// IdempotencyStore keeps track of the events that have already been forwarded to the integration.
+// The ControlPlane consults the store before forwarding an event and skips events whose ID was seen before.
+// Implementations backed by an external storage (e.g. Redis) keep this information across restarts
+type IdempotencyStore interface {
+	// SeenBefore returns whether the given key was marked as seen
+	SeenBefore(key string) (bool, error)
+	// MarkSeen marks the given key as seen
+	MarkSeen(key string) error
+}
+
+// InMemoryIdempotencyStore is an IdempotencyStore keeping the seen keys in memory.
+// Once the capacity is reached, the oldest keys are forgotten
+type InMemoryIdempotencyStore struct {
+	capacity int
+	seen     map[string]struct{}
+	order    []string
+	mtx      sync.Mutex
+}
+
+var _ IdempotencyStore = (*InMemoryIdempotencyStore)(nil)
+
+// NewInMemoryIdempotencyStore creates a new InMemoryIdempotencyStore remembering up to capacity keys.
+// A capacity <= 0 means that keys are never forgotten
+func NewInMemoryIdempotencyStore(capacity int) *InMemoryIdempotencyStore {
+	return &InMemoryIdempotencyStore{
+		capacity: capacity,
+		seen:     map[string]struct{}{},
+	}
+}
+
+func (s *InMemoryIdempotencyStore) SeenBefore(key string) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, ok := s.seen[key]
+	return ok, nil
+}
+
+func (s *InMemoryIdempotencyStore) MarkSeen(key string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.seen[key]; ok {
+		return nil
+	}
+	if s.capacity > 0 && len(s.order) >= s.capacity {
+		delete(s.seen, s.order[0])
+		s.order = s.order[1:]
+	}
+	s.seen[key] = struct{}{}
+	s.order = append(s.order, key)
+	return nil
+}
+
+// seenBefore checks whether the event with the given ID was already forwarded. If the store cannot be
+// consulted, the event is treated as not seen, since dropping it might lose it entirely
+func (cp *ControlPlane) seenBefore(log logger.Logger, eventID string) bool {
+	if cp.idempotencyStore == nil || eventID == "" {
+		return false
+	}
+	seen, err := cp.idempotencyStore.SeenBefore(eventID)
+	if err != nil {
+		log.Warnf("Could not check whether event %s was seen before: %v", eventID, err)
+		return false
+	}
+	return seen
+}
+
+func (cp *ControlPlane) markSeen(log logger.Logger, eventID string) {
+	if cp.idempotencyStore == nil || eventID == "" {
+		return
+	}
+	if err := cp.idempotencyStore.MarkSeen(eventID); err != nil {
+		log.Warnf("Could not mark event %s as seen: %v", eventID, err)
+	}
+}
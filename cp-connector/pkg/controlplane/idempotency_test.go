@@ -0,0 +1,197 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeIdempotencyStore struct {
+	mtx          sync.Mutex
+	seen         map[string]bool
+	marked       []string
+	seenBeforeFn func(key string) (bool, error)
+}
+
+func (f *fakeIdempotencyStore) SeenBefore(key string) (bool, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.seenBeforeFn != nil {
+		return f.seenBeforeFn(key)
+	}
+	return f.seen[key], nil
+}
+
+func (f *fakeIdempotencyStore) MarkSeen(key string) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.marked = append(f.marked, key)
+	return nil
+}
+
+func (f *fakeIdempotencyStore) markedKeys() []string {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return append([]string{}, f.marked...)
+}
+
+func TestControlPlaneIdempotencyStore(t *testing.T) {
+	sources := newFakeSources()
+	store := &fakeIdempotencyStore{seen: map[string]bool{"seen-event": true}}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithIdempotencyStore(store))
+
+	received := make(chan string, 2)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			received <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	eventChan <- eventUpdate("seen-event", "sh.keptn.event.echo.triggered")
+	eventChan <- eventUpdate("new-event", "sh.keptn.event.echo.triggered")
+	require.Equal(t, "new-event", <-received)
+	require.Eventually(t, func() bool { return len(store.markedKeys()) == 1 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"new-event"}, store.markedKeys())
+	require.Empty(t, received)
+}
+
+func TestControlPlaneIdempotencyStoreUnavailable(t *testing.T) {
+	sources := newFakeSources()
+	store := &fakeIdempotencyStore{seenBeforeFn: func(key string) (bool, error) {
+		return false, errors.New("store unavailable")
+	}}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithIdempotencyStore(store))
+
+	received := make(chan string, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			received <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	require.Equal(t, "event-id", <-received)
+}
+
+func TestControlPlaneIdempotencyStoreForwardsRedeliveryOfFailedEvent(t *testing.T) {
+	sources := newFakeSources()
+	store := NewInMemoryIdempotencyStore(10)
+	controlPlane := New(sources.ssm, sources.esm, nil, WithIdempotencyStore(store))
+
+	var calls int32
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				return errors.New("handling failed")
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	failed := &fakeAcknowledger{}
+	update := eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	update.Acknowledger = failed
+	eventChan <- update
+	require.Eventually(t, func() bool {
+		_, nacked := failed.outcomes()
+		return len(nacked) == 1
+	}, time.Second, 10*time.Millisecond)
+	seen, err := store.SeenBefore("event-id")
+	require.NoError(t, err)
+	require.False(t, seen, "a failed event must not be marked as seen")
+
+	// the redelivery is forwarded again and marked as seen once it was handled
+	redelivered := &fakeAcknowledger{}
+	update = eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	update.MetaData.DeliveryCount = 2
+	update.Acknowledger = redelivered
+	eventChan <- update
+	require.Eventually(t, func() bool {
+		acked, _ := redelivered.outcomes()
+		return acked == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	seen, err = store.SeenBefore("event-id")
+	require.NoError(t, err)
+	require.True(t, seen)
+}
+
+func TestControlPlaneIdempotencyStoreMarksBatchedEventsAfterFlush(t *testing.T) {
+	sources := newFakeSources()
+	store := &fakeIdempotencyStore{seen: map[string]bool{}}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithIdempotencyStore(store), WithBatching(2, time.Minute))
+
+	var calls int32
+	integration := batchIntegration{
+		ExampleIntegration: ExampleIntegration{
+			RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		},
+		OnEventsFn: func(ctx context.Context, events []models.KeptnContextExtendedCE) error {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				return errors.New("handling failed")
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	eventChan <- eventUpdate("event-1", "sh.keptn.event.echo.triggered")
+	eventChan <- eventUpdate("event-2", "sh.keptn.event.echo.triggered")
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 1 }, time.Second, 10*time.Millisecond)
+	require.Empty(t, store.markedKeys(), "events of a failed batch must not be marked as seen")
+
+	eventChan <- eventUpdate("event-1", "sh.keptn.event.echo.triggered")
+	eventChan <- eventUpdate("event-2", "sh.keptn.event.echo.triggered")
+	require.Eventually(t, func() bool { return len(store.markedKeys()) == 2 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	require.Equal(t, []string{"event-1", "event-2"}, store.markedKeys())
+}
+
+func TestInMemoryIdempotencyStore(t *testing.T) {
+	store := NewInMemoryIdempotencyStore(2)
+	require.NoError(t, store.MarkSeen("a"))
+	require.NoError(t, store.MarkSeen("b"))
+	seen, err := store.SeenBefore("a")
+	require.NoError(t, err)
+	require.True(t, seen)
+
+	// exceeding the capacity forgets the oldest key
+	require.NoError(t, store.MarkSeen("c"))
+	seen, _ = store.SeenBefore("a")
+	require.False(t, seen)
+	seen, _ = store.SeenBefore("b")
+	require.True(t, seen)
+	seen, _ = store.SeenBefore("c")
+	require.True(t, seen)
+}
@@ -0,0 +1,68 @@
+package controlplane
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// WithIDPersistence configures the ControlPlane to store the integration ID assigned during the registration in the
+// file at the given path, and to register with the stored ID when the integration is started again. This way,
+// restarts of the integration keep its integration ID. If the registration with the stored ID is rejected,
+// the integration is registered without an ID, as if no ID was stored
+func WithIDPersistence(path string) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.idPersistencePath = path
+	}
+}
+
+// registerIntegration registers the integration at the subscription source and returns the assigned integration ID.
+// If ID persistence is enabled, the stored ID is reused and the assigned ID is stored
+func (cp *ControlPlane) registerIntegration(registrationData types.RegistrationData) (string, error) {
+	if cp.idPersistencePath == "" {
+		return cp.subscriptionSource.Register(models.Integration(registrationData))
+	}
+	var integrationID string
+	var err error
+	if storedID := cp.readIntegrationID(); storedID != "" {
+		cp.logger.Debugf("Registering with stored integration ID %s", storedID)
+		withStoredID := registrationData
+		withStoredID.ID = storedID
+		if integrationID, err = cp.subscriptionSource.Register(models.Integration(withStoredID)); err != nil {
+			cp.logger.Warnf("Registration with stored integration ID %s was rejected, registering without ID: %v", storedID, err)
+		}
+	}
+	if integrationID == "" {
+		if integrationID, err = cp.subscriptionSource.Register(models.Integration(registrationData)); err != nil {
+			return "", err
+		}
+	}
+	cp.writeIntegrationID(integrationID)
+	return integrationID, nil
+}
+
+func (cp *ControlPlane) readIntegrationID() string {
+	content, err := os.ReadFile(cp.idPersistencePath)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			cp.logger.Warnf("Could not read stored integration ID: %v", err)
+		}
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// writeIntegrationID stores the integration ID. A failure does not affect the current registration,
+// so it is only logged
+func (cp *ControlPlane) writeIntegrationID(integrationID string) {
+	if integrationID == "" {
+		return
+	}
+	if err := os.WriteFile(cp.idPersistencePath, []byte(integrationID), 0600); err != nil {
+		cp.logger.Warnf("Could not store integration ID: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+// registerAndStop registers the integration and stops the control plane again once it is registered
+func registerAndStop(t *testing.T, controlPlane *ControlPlane) {
+	integration := ExampleIntegration{RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{Name: "my-service"} }}
+	ctx, cancel := context.WithCancel(context.TODO())
+	registerErr := make(chan error)
+	go func() { registerErr <- controlPlane.Register(ctx, integration) }()
+	require.Eventually(t, controlPlane.IsRegistered, time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-registerErr)
+}
+
+func TestControlPlaneIDPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "integration-id")
+	var requestedIDs []string
+	newControlPlane := func() *ControlPlane {
+		sources := newFakeSources()
+		sources.ssm.RegisterFn = func(integration models.Integration) (string, error) {
+			requestedIDs = append(requestedIDs, integration.ID)
+			if integration.ID != "" {
+				return integration.ID, nil
+			}
+			return "assigned-id", nil
+		}
+		return New(sources.ssm, sources.esm, nil, WithIDPersistence(path))
+	}
+
+	registerAndStop(t, newControlPlane())
+	stored, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "assigned-id", string(stored))
+
+	// after a restart, the stored ID is used for the registration
+	restarted := newControlPlane()
+	registerAndStop(t, restarted)
+	require.Equal(t, "assigned-id", restarted.integrationID)
+	require.Equal(t, []string{"", "assigned-id"}, requestedIDs)
+}
+
+func TestControlPlaneIDPersistenceStoredIDRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "integration-id")
+	require.NoError(t, os.WriteFile(path, []byte("stale-id\n"), 0600))
+	var requestedIDs []string
+	sources := newFakeSources()
+	sources.ssm.RegisterFn = func(integration models.Integration) (string, error) {
+		requestedIDs = append(requestedIDs, integration.ID)
+		if integration.ID == "stale-id" {
+			return "", errors.New("unknown integration")
+		}
+		return "fresh-id", nil
+	}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithIDPersistence(path))
+
+	registerAndStop(t, controlPlane)
+	require.Equal(t, []string{"stale-id", ""}, requestedIDs)
+	require.Equal(t, "fresh-id", controlPlane.integrationID)
+	stored, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "fresh-id", string(stored))
+}
+
+func TestControlPlaneIDPersistenceRegistrationFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "integration-id")
+	sources := newFakeSources()
+	sources.ssm.RegisterFn = func(integration models.Integration) (string, error) {
+		return "", errors.New("registration failed")
+	}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithIDPersistence(path))
+	integration := ExampleIntegration{RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} }}
+
+	require.ErrorContains(t, controlPlane.Register(context.TODO(), integration), "registration failed")
+	_, err := os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,30 @@
+package controlplane
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// ErrLagNotSupported is returned by Lag if the event source does not implement types.OffsetReporter
+var ErrLagNotSupported = errors.New("event source does not report offsets")
+
+// Lag returns the number of events the event source lags behind the stream of its broker, e.g. to alert on or to
+// autoscale the integration based on the consumer lag. It is only available for event sources implementing
+// types.OffsetReporter, for other event sources ErrLagNotSupported is returned
+func (cp *ControlPlane) Lag() (int64, error) {
+	reporter, ok := cp.eventSource.(types.OffsetReporter)
+	if !ok {
+		return 0, ErrLagNotSupported
+	}
+	current, latest, err := reporter.Offsets()
+	if err != nil {
+		return 0, fmt.Errorf("could not determine lag: %w", err)
+	}
+	// the latest offset of the stream may be outdated if it is fetched less frequently than events are received
+	if latest < current {
+		return 0, nil
+	}
+	return latest - current, nil
+}
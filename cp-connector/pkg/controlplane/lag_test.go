@@ -0,0 +1,53 @@
+package controlplane
+
+import (
+	"errors"
+	"testing"
+
+	fake2 "github.com/keptn/keptn/cp-connector/pkg/fake"
+	"github.com/stretchr/testify/require"
+)
+
+type offsetEventSource struct {
+	*fake2.EventSourceMock
+	current int64
+	latest  int64
+	err     error
+}
+
+func (o offsetEventSource) Offsets() (int64, int64, error) {
+	return o.current, o.latest, o.err
+}
+
+func TestControlPlaneLag(t *testing.T) {
+	sources := newFakeSources()
+	tests := []struct {
+		name    string
+		source  offsetEventSource
+		want    int64
+		wantErr bool
+	}{
+		{name: "lagging", source: offsetEventSource{current: 40, latest: 52}, want: 12},
+		{name: "up to date", source: offsetEventSource{current: 52, latest: 52}, want: 0},
+		{name: "outdated latest offset", source: offsetEventSource{current: 53, latest: 52}, want: 0},
+		{name: "offsets unavailable", source: offsetEventSource{err: errors.New("stream not found")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.source.EventSourceMock = sources.esm
+			lag, err := New(sources.ssm, tt.source, nil).Lag()
+			if tt.wantErr {
+				require.ErrorContains(t, err, "stream not found")
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, lag)
+		})
+	}
+}
+
+func TestControlPlaneLagNotSupported(t *testing.T) {
+	sources := newFakeSources()
+	_, err := New(sources.ssm, sources.esm, nil).Lag()
+	require.ErrorIs(t, err, ErrLagNotSupported)
+}
@@ -0,0 +1,45 @@
+package controlplane
+
+import (
+	"context"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+)
+
+// LivenessEventFn builds the event sent periodically while the integration is registered
+type LivenessEventFn func() models.KeptnContextExtendedCE
+
+// WithLivenessEvent configures the ControlPlane to send the event built by the given function in the given interval
+// while the integration is registered, e.g. to keep the integration visibly alive in the Keptn UI.
+// The first event is sent once the interval expired after the registration
+func WithLivenessEvent(interval time.Duration, build LivenessEventFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.livenessInterval = interval
+		ns.livenessEventFn = build
+	}
+}
+
+// runLivenessEvents periodically sends the liveness event until the context is cancelled.
+// The returned channel is closed as soon as no more liveness events are sent
+func (cp *ControlPlane) runLivenessEvents(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	sender := cp.getSender(cp.eventSender)
+	ticker := cp.clock.Ticker(cp.livenessInterval)
+	go func() {
+		defer close(done)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				event := cp.livenessEventFn()
+				if err := sender(event); err != nil {
+					cp.logger.Warnf("Could not send liveness event of type %s: %v", eventType(event), err)
+				}
+			}
+		}
+	}()
+	return done
+}
@@ -0,0 +1,55 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneLivenessEvents(t *testing.T) {
+	sources := newFakeSources()
+	mockClock := clock.NewMock()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithClock(mockClock), WithLivenessEvent(time.Minute, func() models.KeptnContextExtendedCE {
+		return models.KeptnContextExtendedCE{Type: strutils.Stringp("sh.keptn.event.liveness"), Source: strutils.Stringp("my-service")}
+	}))
+	integration := ExampleIntegration{RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} }}
+	ctx, cancel := context.WithCancel(context.TODO())
+	registerErr := make(chan error)
+	go func() { registerErr <- controlPlane.Register(ctx, integration) }()
+	require.Eventually(t, controlPlane.IsRegistered, time.Second, 10*time.Millisecond)
+	sent := func() int { return len(sources.sentEvents()) }
+
+	mockClock.Add(59 * time.Second)
+	require.Equal(t, 0, sent())
+	mockClock.Add(time.Second)
+	require.Eventually(t, func() bool { return sent() == 1 }, time.Second, 10*time.Millisecond)
+	mockClock.Add(time.Minute)
+	require.Eventually(t, func() bool { return sent() == 2 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, "sh.keptn.event.liveness", *sources.sentEvents()[1].Type)
+
+	// no more liveness events are sent once the registration stopped
+	cancel()
+	require.NoError(t, <-registerErr)
+	mockClock.Add(time.Minute)
+	require.Equal(t, 2, sent())
+}
+
+func TestControlPlaneWithoutLivenessEvents(t *testing.T) {
+	sources := newFakeSources()
+	mockClock := clock.NewMock()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithClock(mockClock))
+	integration := ExampleIntegration{RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} }}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	require.Eventually(t, controlPlane.IsRegistered, time.Second, 10*time.Millisecond)
+
+	mockClock.Add(time.Hour)
+	require.Empty(t, sources.sentEvents())
+}
@@ -0,0 +1,22 @@
+package controlplane
+
+import (
+	"errors"
+
+	"github.com/keptn/keptn/cp-connector/pkg/logger"
+)
+
+// ErrLogLevelNotSupported is returned by SetLogLevel if the logger of the ControlPlane does not support levels
+var ErrLogLevelNotSupported = errors.New("logger does not support changing the log level")
+
+// SetLogLevel changes the level of the logger of the ControlPlane at runtime, e.g. to enable debug logs during
+// an incident. The default logger supports levels, a logger set via WithLogger has to implement logger.LevelSetter,
+// e.g. by wrapping it into a logger.LevelLogger
+func (cp *ControlPlane) SetLogLevel(level logger.Level) error {
+	setter, ok := cp.logger.(logger.LevelSetter)
+	if !ok {
+		return ErrLogLevelNotSupported
+	}
+	setter.SetLevel(level)
+	return nil
+}
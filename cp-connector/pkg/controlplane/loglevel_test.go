@@ -0,0 +1,54 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	fake2 "github.com/keptn/keptn/cp-connector/pkg/fake"
+	"github.com/keptn/keptn/cp-connector/pkg/logger"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneSetLogLevel(t *testing.T) {
+	sources := newFakeSources()
+	log := &fake2.LoggerMock{}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithLogger(logger.NewLevelLogger(log, logger.InfoLevel)))
+	handled := make(chan string)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			handled <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	eventChan <- eventUpdate("before", "sh.keptn.event.echo.triggered")
+	require.Equal(t, "before", <-handled)
+	require.False(t, log.Contains("Received an event of type"))
+
+	require.NoError(t, controlPlane.SetLogLevel(logger.DebugLevel))
+	eventChan <- eventUpdate("after", "sh.keptn.event.echo.triggered")
+	require.Equal(t, "after", <-handled)
+	require.True(t, log.Contains("Received an event of type: sh.keptn.event.echo.triggered"))
+}
+
+func TestControlPlaneSetLogLevelNotSupported(t *testing.T) {
+	controlPlane := New(nil, nil, nil, WithLogger(&fake2.LoggerMock{}))
+	require.ErrorIs(t, controlPlane.SetLogLevel(logger.DebugLevel), ErrLogLevelNotSupported)
+	require.NoError(t, New(nil, nil, nil).SetLogLevel(logger.WarnLevel))
+}
+
+func TestParseLogLevel(t *testing.T) {
+	level, err := logger.ParseLevel("DEBUG")
+	require.NoError(t, err)
+	require.Equal(t, logger.DebugLevel, level)
+	_, err = logger.ParseLevel("verbose")
+	require.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package controlplane
+
+import "sync/atomic"
+
+// eventLimit counts the events forwarded to the integration during a registration and signals
+// once the maximum number of events configured via WithMaxEventsHandled was reached
+type eventLimit struct {
+	max     int64
+	handled int64
+	reached chan struct{}
+}
+
+func newEventLimit(max int) *eventLimit {
+	if max <= 0 {
+		return nil
+	}
+	return &eventLimit{max: int64(max), reached: make(chan struct{})}
+}
+
+// WithMaxEventsHandled configures Register to return once the given number of events was forwarded to the
+// integration successfully, e.g. for integrations running as short-lived jobs. Events already being handled
+// when the limit is reached are allowed to finish, events received but not handled yet are redelivered
+func WithMaxEventsHandled(n int) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.maxEventsHandled = n
+	}
+}
+
+// observeHandled counts a successfully forwarded event
+func (l *eventLimit) observeHandled() {
+	if l == nil {
+		return
+	}
+	if atomic.AddInt64(&l.handled, 1) == l.max {
+		close(l.reached)
+	}
+}
+
+// done returns a channel that is closed once the limit was reached. Without a limit, the channel is nil
+func (l *eventLimit) done() <-chan struct{} {
+	if l == nil {
+		return nil
+	}
+	return l.reached
+}
+
+func (l *eventLimit) isReached() bool {
+	return l != nil && atomic.LoadInt64(&l.handled) >= l.max
+}
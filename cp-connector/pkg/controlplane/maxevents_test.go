@@ -0,0 +1,96 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneMaxEventsHandled(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithMaxEventsHandled(3))
+	var mtx sync.Mutex
+	var handled []string
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			handled = append(handled, ce.ID)
+			if ce.ID == "failing" {
+				return errors.New("handling failed")
+			}
+			return nil
+		},
+	}
+	registerErr := make(chan error)
+	go func() { registerErr <- controlPlane.Register(context.TODO(), integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	// failed events are not counted
+	eventChan <- eventUpdate("failing", "sh.keptn.event.echo.triggered")
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		for i := 0; i < 5; i++ {
+			select {
+			case eventChan <- eventUpdate(fmt.Sprintf("event-%d", i), "sh.keptn.event.echo.triggered"):
+			case <-time.After(time.Second):
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-registerErr:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Register did not return after handling the maximum number of events")
+	}
+	require.False(t, controlPlane.IsRegistered())
+	<-sent
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Equal(t, []string{"failing", "event-0", "event-1", "event-2"}, handled)
+}
+
+func TestControlPlaneMaxEventsHandledRedeliversRemainingEvents(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithMaxEventsHandled(1))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn:          func(ctx context.Context, ce models.KeptnContextExtendedCE) error { return nil },
+	}
+	registerErr := make(chan error)
+	go func() { registerErr <- controlPlane.Register(context.TODO(), integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	acknowledgers := []*fakeAcknowledger{{}, {}, {}}
+	var batch []types.EventUpdate
+	for i, acknowledger := range acknowledgers {
+		update := eventUpdate(fmt.Sprintf("event-%d", i), "sh.keptn.event.echo.triggered")
+		update.Acknowledger = acknowledger
+		batch = append(batch, update)
+	}
+	eventChan <- types.EventUpdate{Batch: batch}
+	require.NoError(t, <-registerErr)
+
+	acked, nacked := acknowledgers[0].outcomes()
+	require.Equal(t, 1, acked)
+	require.Empty(t, nacked)
+	// the events exceeding the limit are redelivered immediately, since they did not fail
+	for _, acknowledger := range acknowledgers[1:] {
+		acked, nacked := acknowledger.outcomes()
+		require.Equal(t, 0, acked)
+		require.Equal(t, []time.Duration{0}, nacked)
+	}
+}
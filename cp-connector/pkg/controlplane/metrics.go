@@ -0,0 +1,137 @@
+package controlplane
+
+import (
+	"errors"
+
+	"github.com/keptn/keptn/cp-connector/pkg/logger"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "keptn_cp_connector"
+
+// metrics holds the prometheus collectors maintained by the ControlPlane
+type metrics struct {
+	subscriptions       prometheus.Gauge
+	subscriptionChanges prometheus.Counter
+	connectionStates    *prometheus.CounterVec
+	receiveErrors       prometheus.Counter
+	eventStorms         prometheus.Counter
+	shedEvents          prometheus.Counter
+	acknowledgements    *prometheus.CounterVec
+}
+
+// Outcomes of settling an event, used as label of the acknowledgements metric
+const (
+	ackOutcomeAck         = "ack"
+	ackOutcomeNack        = "nack"
+	ackOutcomeNackDelayed = "nack_delayed"
+	ackOutcomeDeadLetter  = "dead_letter"
+)
+
+func newMetrics(registerer prometheus.Registerer, log logger.Logger) *metrics {
+	m := &metrics{
+		subscriptions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "subscriptions",
+			Help:      "Number of currently active subscriptions",
+		}),
+		subscriptionChanges: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "subscription_changes_total",
+			Help:      "Number of received subscription updates that changed the active subscriptions",
+		}),
+		connectionStates: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "connection_state_changes_total",
+			Help:      "Number of transitions of the event source connection, partitioned by the new state",
+		}, []string{"state"}),
+		receiveErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "receive_errors_total",
+			Help:      "Number of events the event source failed to receive, e.g. because of malformed messages",
+		}),
+		eventStorms: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "event_storms_total",
+			Help:      "Number of detected event storms, i.e. periods of receive rates above the configured threshold",
+		}),
+		shedEvents: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "shed_events_total",
+			Help:      "Number of events rejected for redelivery during event storms",
+		}),
+		acknowledgements: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "acknowledgements_total",
+			Help:      "Number of events settled with the broker, partitioned by subject and outcome (ack, nack, nack_delayed, dead_letter)",
+		}, []string{"subject", "outcome"}),
+	}
+	m.subscriptions = registerCollector(registerer, log, m.subscriptions).(prometheus.Gauge)
+	m.subscriptionChanges = registerCollector(registerer, log, m.subscriptionChanges).(prometheus.Counter)
+	m.connectionStates = registerCollector(registerer, log, m.connectionStates).(*prometheus.CounterVec)
+	m.receiveErrors = registerCollector(registerer, log, m.receiveErrors).(prometheus.Counter)
+	m.eventStorms = registerCollector(registerer, log, m.eventStorms).(prometheus.Counter)
+	m.shedEvents = registerCollector(registerer, log, m.shedEvents).(prometheus.Counter)
+	m.acknowledgements = registerCollector(registerer, log, m.acknowledgements).(*prometheus.CounterVec)
+	return m
+}
+
+// registerCollector registers the collector at the given registerer. If an equal collector
+// was already registered (e.g. by a previously created ControlPlane), the existing one is returned.
+// Other registration errors are logged, the returned collector is then not exported
+func registerCollector(registerer prometheus.Registerer, log logger.Logger, collector prometheus.Collector) prometheus.Collector {
+	if err := registerer.Register(collector); err != nil {
+		are := prometheus.AlreadyRegisteredError{}
+		if errors.As(err, &are) {
+			return are.ExistingCollector
+		}
+		log.Warnf("Could not register metrics collector: %v", err)
+	}
+	return collector
+}
+
+func (m *metrics) observeSubscriptionUpdate(count int, changed bool) {
+	if m == nil {
+		return
+	}
+	m.subscriptions.Set(float64(count))
+	if changed {
+		m.subscriptionChanges.Inc()
+	}
+}
+
+func (m *metrics) observeConnectionState(state types.ConnectionState) {
+	if m == nil {
+		return
+	}
+	m.connectionStates.WithLabelValues(string(state)).Inc()
+}
+
+func (m *metrics) observeReceiveError() {
+	if m == nil {
+		return
+	}
+	m.receiveErrors.Inc()
+}
+
+func (m *metrics) observeEventStorm() {
+	if m == nil {
+		return
+	}
+	m.eventStorms.Inc()
+}
+
+func (m *metrics) observeShedEvent() {
+	if m == nil {
+		return
+	}
+	m.shedEvents.Inc()
+}
+
+func (m *metrics) observeAcknowledgement(subject string, outcome string) {
+	if m == nil {
+		return
+	}
+	m.acknowledgements.WithLabelValues(subject, outcome).Inc()
+}
@@ -0,0 +1,46 @@
+package controlplane
+
+import "sort"
+
+// Mute stops the forwarding of events received on the given subject, e.g. to silence a noisy subject during an
+// incident. Events of a muted subject are acknowledged and dropped until the subject is unmuted
+func (cp *ControlPlane) Mute(subject string) {
+	cp.mutedMtx.Lock()
+	defer cp.mutedMtx.Unlock()
+	if cp.muted == nil {
+		cp.muted = map[string]struct{}{}
+	}
+	cp.muted[subject] = struct{}{}
+	cp.logger.Infof("Muted subject %s", subject)
+}
+
+// Unmute resumes the forwarding of events received on the given subject
+func (cp *ControlPlane) Unmute(subject string) {
+	cp.mutedMtx.Lock()
+	defer cp.mutedMtx.Unlock()
+	if _, ok := cp.muted[subject]; !ok {
+		return
+	}
+	delete(cp.muted, subject)
+	cp.logger.Infof("Unmuted subject %s", subject)
+}
+
+// IsMuted checks whether the given subject is currently muted
+func (cp *ControlPlane) IsMuted(subject string) bool {
+	cp.mutedMtx.RLock()
+	defer cp.mutedMtx.RUnlock()
+	_, ok := cp.muted[subject]
+	return ok
+}
+
+// MutedSubjects returns the currently muted subjects in alphabetical order
+func (cp *ControlPlane) MutedSubjects() []string {
+	cp.mutedMtx.RLock()
+	defer cp.mutedMtx.RUnlock()
+	subjects := make([]string, 0, len(cp.muted))
+	for subject := range cp.muted {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+	return subjects
+}
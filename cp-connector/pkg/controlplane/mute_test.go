@@ -0,0 +1,53 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneMute(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil)
+	handled := make(chan string, 10)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			handled <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{
+		{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"},
+		{ID: "sub-2", Event: "sh.keptn.event.noisy.triggered"},
+	}
+
+	controlPlane.Mute("sh.keptn.event.noisy.triggered")
+	require.True(t, controlPlane.IsMuted("sh.keptn.event.noisy.triggered"))
+	require.False(t, controlPlane.IsMuted("sh.keptn.event.echo.triggered"))
+	require.Equal(t, []string{"sh.keptn.event.noisy.triggered"}, controlPlane.MutedSubjects())
+
+	acknowledger := &fakeAcknowledger{}
+	muted := eventUpdate("muted", "sh.keptn.event.noisy.triggered")
+	muted.Acknowledger = acknowledger
+	eventChan <- muted
+	eventChan <- eventUpdate("not-muted", "sh.keptn.event.echo.triggered")
+	require.Equal(t, "not-muted", <-handled)
+	// muted events are acknowledged, so they are not redelivered
+	acked, nacked := acknowledger.outcomes()
+	require.Equal(t, 1, acked)
+	require.Empty(t, nacked)
+
+	controlPlane.Unmute("sh.keptn.event.noisy.triggered")
+	require.False(t, controlPlane.IsMuted("sh.keptn.event.noisy.triggered"))
+	require.Empty(t, controlPlane.MutedSubjects())
+	eventChan <- eventUpdate("unmuted", "sh.keptn.event.noisy.triggered")
+	require.Equal(t, "unmuted", <-handled)
+}
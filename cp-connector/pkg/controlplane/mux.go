@@ -0,0 +1,135 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"strings"
+	"sync"
+)
+
+var _ Integration = (*Mux)(nil)
+
+// ErrNoMatchingRoute is returned by Mux.OnEvent if no route (and no fallback route)
+// is able to handle the received event
+var ErrNoMatchingRoute = errors.New("no matching route")
+
+// HandlerFunc is a function that handles a single Keptn event
+type HandlerFunc func(context.Context, models.KeptnContextExtendedCE) error
+
+type route struct {
+	subject string
+	handler HandlerFunc
+}
+
+// Mux is an Integration that dispatches incoming events to handlers registered per event subject.
+// Subjects can either be exact (e.g. "sh.keptn.event.deployment.triggered") or NATS style patterns,
+// where "*" matches exactly one token and ">" matches one or more trailing tokens
+// (e.g. "sh.keptn.event.*.triggered" or "sh.keptn.event.>").
+// The registration data of the Mux contains one subscription per registered route
+type Mux struct {
+	mtx             sync.RWMutex
+	registration    types.RegistrationData
+	routes          []route
+	fallbackHandler HandlerFunc
+}
+
+// NewMux creates a new Mux using the given registration data as base for
+// the registration data returned by Mux.RegistrationData
+func NewMux(registrationData types.RegistrationData) *Mux {
+	return &Mux{
+		registration: registrationData,
+		routes:       []route{},
+	}
+}
+
+// Handle registers a handler for the given subject or subject pattern.
+// Registering a handler for a subject that already has a route replaces the existing handler
+func (m *Mux) Handle(subject string, handler HandlerFunc) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for i, r := range m.routes {
+		if r.subject == subject {
+			m.routes[i].handler = handler
+			return
+		}
+	}
+	m.routes = append(m.routes, route{subject: subject, handler: handler})
+}
+
+// HandleFallback registers a handler that is called for events not matching any registered route
+func (m *Mux) HandleFallback(handler HandlerFunc) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.fallbackHandler = handler
+}
+
+// OnEvent dispatches the event to the route matching the event type.
+// Exact routes take precedence over pattern routes, pattern routes are evaluated in registration order.
+// If no route matches, the event is passed to the fallback handler. If no fallback handler is registered
+// an error wrapping ErrNoMatchingRoute is returned
+func (m *Mux) OnEvent(ctx context.Context, event models.KeptnContextExtendedCE) error {
+	handler := m.handlerFor(event)
+	if handler == nil {
+		return fmt.Errorf("unable to handle event of type %s: %w", eventType(event), ErrNoMatchingRoute)
+	}
+	return handler(ctx, event)
+}
+
+// RegistrationData returns the registration data of the Mux containing one subscription per registered route
+func (m *Mux) RegistrationData() types.RegistrationData {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	data := m.registration
+	data.Subscriptions = append([]models.EventSubscription{}, m.registration.Subscriptions...)
+	for _, r := range m.routes {
+		data.Subscriptions = append(data.Subscriptions, models.EventSubscription{Event: r.subject})
+	}
+	return data
+}
+
+func (m *Mux) handlerFor(event models.KeptnContextExtendedCE) HandlerFunc {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	subject := eventType(event)
+	for _, r := range m.routes {
+		if r.subject == subject {
+			return r.handler
+		}
+	}
+	for _, r := range m.routes {
+		if matchSubject(r.subject, subject) {
+			return r.handler
+		}
+	}
+	return m.fallbackHandler
+}
+
+// matchSubject checks whether a subject matches a NATS style subject pattern.
+// Within the pattern "*" matches exactly one token and ">" (only allowed as last token)
+// matches one or more tokens
+func matchSubject(pattern string, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+	for i, p := range patternTokens {
+		if p == ">" {
+			return i == len(patternTokens)-1 && len(subjectTokens) > i
+		}
+		if i >= len(subjectTokens) {
+			return false
+		}
+		if p != "*" && p != subjectTokens[i] {
+			return false
+		}
+	}
+	return len(patternTokens) == len(subjectTokens)
+}
+
+func eventType(event models.KeptnContextExtendedCE) string {
+	if event.Type == nil {
+		return ""
+	}
+	return *event.Type
+}
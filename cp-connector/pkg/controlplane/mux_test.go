@@ -0,0 +1,130 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMuxExactRoute(t *testing.T) {
+	var called []string
+	mux := NewMux(types.RegistrationData{Name: "mux"})
+	mux.Handle("sh.keptn.event.deployment.triggered", func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+		called = append(called, "exact")
+		return nil
+	})
+	mux.Handle("sh.keptn.event.*.triggered", func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+		called = append(called, "pattern")
+		return nil
+	})
+	err := mux.OnEvent(context.TODO(), models.KeptnContextExtendedCE{Type: strutils.Stringp("sh.keptn.event.deployment.triggered")})
+	require.Nil(t, err)
+	require.Equal(t, []string{"exact"}, called)
+}
+
+func TestMuxPatternRoute(t *testing.T) {
+	var called []string
+	mux := NewMux(types.RegistrationData{Name: "mux"})
+	mux.Handle("sh.keptn.event.deployment.triggered", func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+		called = append(called, "exact")
+		return nil
+	})
+	mux.Handle("sh.keptn.event.*.triggered", func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+		called = append(called, "pattern")
+		return nil
+	})
+	mux.Handle("sh.keptn.event.>", func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+		called = append(called, "tail")
+		return nil
+	})
+	require.Nil(t, mux.OnEvent(context.TODO(), models.KeptnContextExtendedCE{Type: strutils.Stringp("sh.keptn.event.test.triggered")}))
+	require.Nil(t, mux.OnEvent(context.TODO(), models.KeptnContextExtendedCE{Type: strutils.Stringp("sh.keptn.event.test.finished")}))
+	require.Equal(t, []string{"pattern", "tail"}, called)
+}
+
+func TestMuxFallbackRoute(t *testing.T) {
+	fallbackCalled := false
+	mux := NewMux(types.RegistrationData{Name: "mux"})
+	mux.Handle("sh.keptn.event.deployment.triggered", func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+		require.FailNow(t, "unexpected call of route handler")
+		return nil
+	})
+	mux.HandleFallback(func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+		fallbackCalled = true
+		return nil
+	})
+	err := mux.OnEvent(context.TODO(), models.KeptnContextExtendedCE{Type: strutils.Stringp("sh.keptn.event.test.triggered")})
+	require.Nil(t, err)
+	require.True(t, fallbackCalled)
+}
+
+func TestMuxNoMatchingRoute(t *testing.T) {
+	mux := NewMux(types.RegistrationData{Name: "mux"})
+	mux.Handle("sh.keptn.event.deployment.triggered", func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+		require.FailNow(t, "unexpected call of route handler")
+		return nil
+	})
+	err := mux.OnEvent(context.TODO(), models.KeptnContextExtendedCE{Type: strutils.Stringp("sh.keptn.event.test.triggered")})
+	require.ErrorIs(t, err, ErrNoMatchingRoute)
+	require.NotErrorIs(t, err, ErrEventHandleFatal)
+}
+
+func TestMuxRegistrationData(t *testing.T) {
+	mux := NewMux(types.RegistrationData{
+		Name:          "mux",
+		Subscriptions: []models.EventSubscription{{Event: "sh.keptn.event.echo.triggered"}},
+	})
+	mux.Handle("sh.keptn.event.deployment.triggered", func(ctx context.Context, ce models.KeptnContextExtendedCE) error { return nil })
+	mux.Handle("sh.keptn.event.*.finished", func(ctx context.Context, ce models.KeptnContextExtendedCE) error { return nil })
+	mux.HandleFallback(func(ctx context.Context, ce models.KeptnContextExtendedCE) error { return nil })
+
+	data := mux.RegistrationData()
+	require.Equal(t, "mux", data.Name)
+	require.Equal(t, []models.EventSubscription{
+		{Event: "sh.keptn.event.echo.triggered"},
+		{Event: "sh.keptn.event.deployment.triggered"},
+		{Event: "sh.keptn.event.*.finished"},
+	}, data.Subscriptions)
+}
+
+func TestMuxDuplicateRouteReplacesHandler(t *testing.T) {
+	var called []string
+	mux := NewMux(types.RegistrationData{Name: "mux"})
+	mux.Handle("sh.keptn.event.deployment.triggered", func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+		called = append(called, "first")
+		return nil
+	})
+	mux.Handle("sh.keptn.event.deployment.triggered", func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+		called = append(called, "second")
+		return nil
+	})
+	require.Nil(t, mux.OnEvent(context.TODO(), models.KeptnContextExtendedCE{Type: strutils.Stringp("sh.keptn.event.deployment.triggered")}))
+	require.Equal(t, []string{"second"}, called)
+	require.Equal(t, []models.EventSubscription{{Event: "sh.keptn.event.deployment.triggered"}}, mux.RegistrationData().Subscriptions)
+}
+
+func TestMatchSubject(t *testing.T) {
+	tests := []struct {
+		pattern string
+		subject string
+		want    bool
+	}{
+		{pattern: "sh.keptn.event.test.triggered", subject: "sh.keptn.event.test.triggered", want: true},
+		{pattern: "sh.keptn.event.test.triggered", subject: "sh.keptn.event.test.finished", want: false},
+		{pattern: "sh.keptn.event.*.triggered", subject: "sh.keptn.event.test.triggered", want: true},
+		{pattern: "sh.keptn.event.*.triggered", subject: "sh.keptn.event.stage.test.triggered", want: false},
+		{pattern: "sh.keptn.event.>", subject: "sh.keptn.event.stage.test.triggered", want: true},
+		{pattern: "sh.keptn.event.>", subject: "sh.keptn.event", want: false},
+		{pattern: "sh.keptn.>.triggered", subject: "sh.keptn.event.triggered", want: false},
+		{pattern: "sh.keptn.event.*", subject: "sh.keptn.event", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.subject, func(t *testing.T) {
+			require.Equal(t, tt.want, matchSubject(tt.pattern, tt.subject))
+		})
+	}
+}
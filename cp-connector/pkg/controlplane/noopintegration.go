@@ -0,0 +1,50 @@
+package controlplane
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/logger"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+var _ Integration = (*NoopIntegration)(nil)
+
+// NoopIntegration is an Integration that only logs the received events, e.g. to verify during a deployment or in a
+// smoke test that the ControlPlane is able to connect, register and receive events. Every event is reported as
+// handled successfully, so it is acknowledged
+type NoopIntegration struct {
+	registration types.RegistrationData
+	logger       logger.Logger
+	received     int64
+}
+
+// NewNoopIntegration creates a new NoopIntegration with the given name that subscribes to the given subjects.
+// The received events are logged using the given logger, or the default logger if it is nil
+func NewNoopIntegration(name string, subjects []string, log logger.Logger) *NoopIntegration {
+	if log == nil {
+		log = logger.NewDefaultLogger()
+	}
+	return &NoopIntegration{
+		registration: types.NewRegistrationData(name, subjects...),
+		logger:       log,
+	}
+}
+
+// OnEvent logs the received event
+func (n *NoopIntegration) OnEvent(ctx context.Context, event models.KeptnContextExtendedCE) error {
+	atomic.AddInt64(&n.received, 1)
+	n.logger.Infof("Received event %s of type %s", event.ID, eventType(event))
+	return nil
+}
+
+// RegistrationData returns the registration data the NoopIntegration was created with
+func (n *NoopIntegration) RegistrationData() types.RegistrationData {
+	return n.registration
+}
+
+// Received returns the number of events received so far
+func (n *NoopIntegration) Received() int {
+	return int(atomic.LoadInt64(&n.received))
+}
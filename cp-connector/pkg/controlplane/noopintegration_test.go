@@ -0,0 +1,44 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	fake2 "github.com/keptn/keptn/cp-connector/pkg/fake"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoopIntegration(t *testing.T) {
+	sources := newFakeSources()
+	registered := make(chan models.Integration, 1)
+	sources.ssm.RegisterFn = func(integration models.Integration) (string, error) {
+		registered <- integration
+		return "some-id", nil
+	}
+	log := &fake2.LoggerMock{}
+	integration := NewNoopIntegration("smoke-test", []string{"sh.keptn.event.echo.triggered"}, log)
+	controlPlane := New(sources.ssm, sources.esm, nil)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+
+	registration := <-registered
+	require.Equal(t, "smoke-test", registration.Name)
+	require.Equal(t, []models.EventSubscription{{Event: "sh.keptn.event.echo.triggered"}}, registration.Subscriptions)
+
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	acknowledger := &fakeAcknowledger{}
+	update := eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	update.Acknowledger = acknowledger
+	eventChan <- update
+
+	require.Eventually(t, func() bool {
+		acked, _ := acknowledger.outcomes()
+		return acked == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, 1, integration.Received())
+	require.True(t, log.Contains("Received event event-id of type sh.keptn.event.echo.triggered"))
+}
@@ -0,0 +1,133 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// orderedQueueSize is the number of events queued per ordered subscription before the ControlPlane blocks
+const orderedQueueSize = 100
+
+type orderedEvent struct {
+	ctx          context.Context
+	update       types.EventUpdate
+	subscription models.EventSubscription
+}
+
+// orderedQueues holds a FIFO queue per subscription requiring strict ordering. Each queue is processed
+// by a single dedicated worker, so events of a subscription are forwarded in the order they were received,
+// independently of the events of other subscriptions.
+// The queues are only accessed by the goroutine handling the received events
+type orderedQueues struct {
+	ctx         context.Context
+	integration Integration
+	queues      map[string]chan orderedEvent
+	wg          sync.WaitGroup
+	// fatal is closed once handling an ordered event failed fatally, fatalErr holds the error
+	fatal    chan struct{}
+	fatalErr error
+	failOnce sync.Once
+}
+
+// queuedContext carries the values of the context an event was queued with, e.g. its correlation ID, but is only
+// cancelled together with the ordered queues, not once the dispatch that queued the event returned
+type queuedContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c queuedContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
+
+func newOrderedQueues(ctx context.Context, integration Integration) *orderedQueues {
+	return &orderedQueues{
+		ctx:         ctx,
+		integration: integration,
+		queues:      map[string]chan orderedEvent{},
+		fatal:       make(chan struct{}),
+	}
+}
+
+// pushOrdered queues the event for the given subscription, starting the worker of the subscription if necessary.
+// It blocks while the queue is full
+func (cp *ControlPlane) pushOrdered(ctx context.Context, eventUpdate types.EventUpdate, subscription models.EventSubscription) {
+	q := cp.orderedQueues
+	queue, ok := q.queues[subscription.ID]
+	if !ok {
+		queue = make(chan orderedEvent, orderedQueueSize)
+		q.queues[subscription.ID] = queue
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			for {
+				select {
+				case event := <-queue:
+					if cp.eventLimit.isReached() {
+						continue
+					}
+					if err := cp.forwardOrdered(q, event); errors.Is(err, ErrEventHandleFatal) {
+						q.fail(err)
+						return
+					}
+				case <-q.ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	select {
+	case queue <- orderedEvent{ctx: queuedContext{Context: q.ctx, values: ctx}, update: eventUpdate, subscription: subscription}:
+	case <-q.fatal:
+	case <-q.ctx.Done():
+	}
+}
+
+// forwardOrdered forwards a queued event to the integration. Ordered events are acknowledged when being queued,
+// so an event whose handling was cancelled by a reload is forwarded again once the reload finished
+func (cp *ControlPlane) forwardOrdered(q *orderedQueues, event orderedEvent) error {
+	for {
+		err := cp.dispatch(event.ctx, func(ctx context.Context) error {
+			return cp.forwardMatchedEvent(ctx, event.update, q.integration, event.subscription)
+		})
+		if !errors.Is(err, errCancelledByReload) || q.ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+// fail records the fatal error of an ordered event, so the goroutine handling the received events stops
+func (q *orderedQueues) fail(err error) {
+	q.failOnce.Do(func() {
+		q.fatalErr = err
+		close(q.fatal)
+	})
+}
+
+// failed returns a channel that is closed once handling an ordered event failed fatally
+func (q *orderedQueues) failed() <-chan struct{} {
+	if q == nil {
+		return nil
+	}
+	return q.fatal
+}
+
+// stopped returns a channel that is closed as soon as all workers stopped after the context was cancelled
+func (q *orderedQueues) stopped() <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-q.ctx.Done()
+		q.wg.Wait()
+	}()
+	return done
+}
+
+func (cp *ControlPlane) isOrdered(subscription models.EventSubscription) bool {
+	_, ok := cp.orderedSubjects[subscription.Event]
+	return ok
+}
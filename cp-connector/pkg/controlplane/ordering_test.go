@@ -0,0 +1,257 @@
+package controlplane
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlanePerSubscriptionOrdering(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithPerSubscriptionOrdering(
+		"sh.keptn.event.deployment.triggered",
+		"sh.keptn.event.test.triggered",
+	))
+
+	release := make(chan struct{})
+	var mtx sync.Mutex
+	handled := map[string][]string{}
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			// the deployment subscription is blocked, which must not hold back the test subscription
+			if *ce.Type == "sh.keptn.event.deployment.triggered" {
+				<-release
+			}
+			mtx.Lock()
+			defer mtx.Unlock()
+			handled[*ce.Type] = append(handled[*ce.Type], ce.ID)
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{
+		{ID: "sub-1", Event: "sh.keptn.event.deployment.triggered"},
+		{ID: "sub-2", Event: "sh.keptn.event.test.triggered"},
+	}
+
+	for i := 0; i < 5; i++ {
+		eventChan <- eventUpdate(fmt.Sprintf("deployment-%d", i), "sh.keptn.event.deployment.triggered")
+		eventChan <- eventUpdate(fmt.Sprintf("test-%d", i), "sh.keptn.event.test.triggered")
+	}
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(handled["sh.keptn.event.test.triggered"]) == 5
+	}, time.Second, 10*time.Millisecond)
+	close(release)
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(handled["sh.keptn.event.deployment.triggered"]) == 5
+	}, time.Second, 10*time.Millisecond)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Equal(t, []string{"deployment-0", "deployment-1", "deployment-2", "deployment-3", "deployment-4"}, handled["sh.keptn.event.deployment.triggered"])
+	require.Equal(t, []string{"test-0", "test-1", "test-2", "test-3", "test-4"}, handled["sh.keptn.event.test.triggered"])
+}
+
+func TestControlPlaneOrderedWorkersStopOnCancel(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithPerSubscriptionOrdering("sh.keptn.event.deployment.triggered"))
+	handled := make(chan string, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			handled <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	registerDone := make(chan error)
+	go func() { registerDone <- controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.deployment.triggered"}}
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.deployment.triggered")
+	require.Equal(t, "event-id", <-handled)
+
+	cancel()
+	select {
+	case err := <-registerDone:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		require.FailNow(t, "Register did not return after cancelling the context")
+	}
+}
+
+func TestControlPlaneContextIsolationUnderConcurrency(t *testing.T) {
+	const workers, eventsPerWorker = 10, 20
+	var subjects []string
+	var subscriptions []models.EventSubscription
+	for i := 0; i < workers; i++ {
+		subject := fmt.Sprintf("sh.keptn.event.task-%d.triggered", i)
+		subjects = append(subjects, subject)
+		subscriptions = append(subscriptions, models.EventSubscription{ID: fmt.Sprintf("sub-%d", i), Event: subject})
+	}
+
+	var mtx sync.Mutex
+	var contaminated []string
+	report := func(format string, args ...interface{}) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		contaminated = append(contaminated, fmt.Sprintf(format, args...))
+	}
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithPerSubscriptionOrdering(subjects...), WithContextDecorator(func(ctx context.Context, ce models.KeptnContextExtendedCE) context.Context {
+		// the context must not carry the value stamped for any other event
+		if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+			report("decorator of event %s saw value %s", ce.ID, tenant)
+		}
+		return context.WithValue(ctx, tenantKey{}, ce.ID)
+	}))
+
+	// the first event of every worker waits for the first events of all other workers,
+	// so the events are handled concurrently
+	var started sync.WaitGroup
+	started.Add(workers)
+	allStarted := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(allStarted)
+	}()
+	handled := make(chan struct{}, workers*eventsPerWorker)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			defer func() { handled <- struct{}{} }()
+			if tenant, _ := ctx.Value(tenantKey{}).(string); tenant != ce.ID {
+				report("event %s was handled with value %s", ce.ID, tenant)
+			}
+			var worker, event int
+			if _, err := fmt.Sscanf(ce.ID, "event-%d-%d", &worker, &event); err == nil && event == 0 {
+				started.Done()
+				select {
+				case <-allStarted:
+				case <-time.After(time.Second):
+					report("event %s was not handled concurrently", ce.ID)
+				}
+			}
+			// the context of the event still carries its own value after the other events were handled
+			if tenant, _ := ctx.Value(tenantKey{}).(string); tenant != ce.ID {
+				report("event %s ended with value %s", ce.ID, tenant)
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- subscriptions
+
+	for event := 0; event < eventsPerWorker; event++ {
+		for worker := 0; worker < workers; worker++ {
+			eventChan <- eventUpdate(fmt.Sprintf("event-%d-%d", worker, event), subjects[worker])
+		}
+	}
+	for i := 0; i < workers*eventsPerWorker; i++ {
+		select {
+		case <-handled:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "events were not handled", "%d of %d events handled", i, workers*eventsPerWorker)
+		}
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Empty(t, contaminated)
+}
+
+func TestControlPlaneOrderedEventNotCancelledByDispatch(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithRedeliveryOnReload(), WithPerSubscriptionOrdering("sh.keptn.event.echo.triggered"))
+	handled := make(chan error, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			require.Equal(t, "event-id", ctx.Value(types.CorrelationIDKey))
+			select {
+			case <-ctx.Done():
+				handled <- ctx.Err()
+			case <-time.After(50 * time.Millisecond):
+				handled <- nil
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	require.NoError(t, <-handled)
+}
+
+func TestControlPlaneOrderedEventForwardedAgainAfterReload(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithRedeliveryOnReload(), WithPerSubscriptionOrdering("sh.keptn.event.echo.triggered"))
+	started := make(chan string, 2)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			started <- ce.ID
+			if len(started) == 1 {
+				// the first attempt is handled until the reload cancels it
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	require.Eventually(t, func() bool { return len(started) == 1 }, time.Second, time.Millisecond)
+
+	controlPlane.ReloadConfig(func() {})
+	require.Eventually(t, func() bool { return len(started) == 2 }, time.Second, time.Millisecond)
+	require.Equal(t, "event-id", <-started)
+	require.Equal(t, "event-id", <-started)
+}
+
+func TestControlPlaneOrderedFatalErrorStopsRegistration(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithPerSubscriptionOrdering("sh.keptn.event.echo.triggered"))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			return fmt.Errorf("could not handle event: %w", ErrEventHandleFatal)
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	registerErr := make(chan error, 1)
+	go func() { registerErr <- controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	select {
+	case err := <-registerErr:
+		require.ErrorIs(t, err, ErrEventHandleFatal)
+	case <-time.After(time.Second):
+		require.FailNow(t, "registration did not stop after a fatal error")
+	}
+}
@@ -0,0 +1,113 @@
+package controlplane
+
+import (
+	"context"
+
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// defaultPriorityQueueSize is the number of events queued for prioritization if no receive buffer is configured
+const defaultPriorityQueueSize = 100
+
+// maxPriorityOvertakes is the number of times a queued event can be overtaken by events with a
+// higher priority before it is handled regardless of its priority
+const maxPriorityOvertakes = 10
+
+type queuedEvent struct {
+	update    types.EventUpdate
+	priority  int
+	overtaken int
+}
+
+// priorityQueue holds the received events in the order they were received
+type priorityQueue struct {
+	events []queuedEvent
+}
+
+func (q *priorityQueue) push(update types.EventUpdate, priority int) {
+	q.events = append(q.events, queuedEvent{update: update, priority: priority})
+}
+
+// next returns the index of the event to be handled next. This is the oldest event with the highest priority,
+// unless the oldest event was already overtaken too often
+func (q *priorityQueue) next() int {
+	if q.events[0].overtaken >= maxPriorityOvertakes {
+		return 0
+	}
+	next := 0
+	for i, e := range q.events {
+		if e.priority > q.events[next].priority {
+			next = i
+		}
+	}
+	return next
+}
+
+// remove removes the event at the given index, counting it as overtaking all older events
+func (q *priorityQueue) remove(index int) {
+	for i := 0; i < index; i++ {
+		q.events[i].overtaken++
+	}
+	q.events = append(q.events[:index], q.events[index+1:]...)
+}
+
+func (q *priorityQueue) len() int {
+	return len(q.events)
+}
+
+// priority returns the priority configured for the subject of the event. Exact subjects take precedence
+// over subject patterns. If multiple patterns match, the highest priority is used
+func (cp *ControlPlane) priority(update types.EventUpdate) int {
+	subject := update.MetaData.Subject
+	if p, ok := cp.eventPriorities[subject]; ok {
+		return p
+	}
+	priority, matched := 0, false
+	for pattern, p := range cp.eventPriorities {
+		if matchSubject(pattern, subject) && (!matched || p > priority) {
+			priority, matched = p, true
+		}
+	}
+	return priority
+}
+
+// runPrioritization receives the events from the given channel and passes them on to the returned channel,
+// handing out queued events with a higher priority first.
+// The returned done channel is closed as soon as the prioritization stopped after the context was cancelled
+func (cp *ControlPlane) runPrioritization(ctx context.Context, in <-chan types.EventUpdate) (<-chan types.EventUpdate, <-chan struct{}) {
+	out := make(chan types.EventUpdate)
+	done := make(chan struct{})
+	queueSize := cp.receiveBuffer
+	if queueSize <= 0 {
+		queueSize = defaultPriorityQueueSize
+	}
+	go func() {
+		defer close(done)
+		queue := &priorityQueue{}
+		for {
+			var receive <-chan types.EventUpdate
+			if queue.len() < queueSize {
+				receive = in
+			}
+			var send chan types.EventUpdate
+			var next int
+			var nextUpdate types.EventUpdate
+			if queue.len() > 0 {
+				send = out
+				next = queue.next()
+				nextUpdate = queue.events[next].update
+			}
+			select {
+			case received := <-receive:
+				for _, update := range unpackBatch(received) {
+					queue.push(update, cp.priority(update))
+				}
+			case send <- nextUpdate:
+				queue.remove(next)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, done
+}
@@ -0,0 +1,119 @@
+package controlplane
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func eventUpdate(id string, subject string) types.EventUpdate {
+	return types.EventUpdate{
+		KeptnEvent: models.KeptnContextExtendedCE{ID: id, Type: strutils.Stringp(subject)},
+		MetaData:   types.EventUpdateMetaData{Subject: subject},
+	}
+}
+
+func TestControlPlaneEventPriority(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithEventPriority(map[string]int{
+		"sh.keptn.event.rollback.triggered": 10,
+		"sh.keptn.event.*.finished":         -1,
+	}))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var mtx sync.Mutex
+	var handled []string
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			if ce.ID == "first" {
+				close(started)
+				<-release
+			}
+			mtx.Lock()
+			defer mtx.Unlock()
+			handled = append(handled, ce.ID)
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{
+		{ID: "sub-1", Event: "sh.keptn.event.deployment.triggered"},
+		{ID: "sub-2", Event: "sh.keptn.event.rollback.triggered"},
+		{ID: "sub-3", Event: "sh.keptn.event.deployment.finished"},
+	}
+
+	// the slow handler blocks on the first event, all further events are queued
+	eventChan <- eventUpdate("first", "sh.keptn.event.deployment.triggered")
+	<-started
+	eventChan <- eventUpdate("finished-1", "sh.keptn.event.deployment.finished")
+	eventChan <- eventUpdate("low-1", "sh.keptn.event.deployment.triggered")
+	eventChan <- eventUpdate("high-1", "sh.keptn.event.rollback.triggered")
+	eventChan <- eventUpdate("low-2", "sh.keptn.event.deployment.triggered")
+	eventChan <- eventUpdate("high-2", "sh.keptn.event.rollback.triggered")
+	close(release)
+
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(handled) == 6
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"first", "high-1", "high-2", "low-1", "low-2", "finished-1"}, handled)
+}
+
+func TestPriorityQueueOrder(t *testing.T) {
+	queue := &priorityQueue{}
+	queue.push(eventUpdate("low-1", "low"), 0)
+	queue.push(eventUpdate("high-1", "high"), 5)
+	queue.push(eventUpdate("low-2", "low"), 0)
+	queue.push(eventUpdate("high-2", "high"), 5)
+
+	var order []string
+	for queue.len() > 0 {
+		next := queue.next()
+		order = append(order, queue.events[next].update.KeptnEvent.ID)
+		queue.remove(next)
+	}
+	require.Equal(t, []string{"high-1", "high-2", "low-1", "low-2"}, order)
+}
+
+func TestPriorityQueueBoundsStarvation(t *testing.T) {
+	queue := &priorityQueue{}
+	queue.push(eventUpdate("low", "low"), 0)
+	for i := 0; i < 2*maxPriorityOvertakes; i++ {
+		queue.push(eventUpdate(fmt.Sprintf("high-%d", i), "high"), 5)
+	}
+
+	position := -1
+	for i := 0; queue.len() > 0; i++ {
+		next := queue.next()
+		if queue.events[next].update.KeptnEvent.ID == "low" {
+			position = i
+		}
+		queue.remove(next)
+	}
+	require.Equal(t, maxPriorityOvertakes, position)
+}
+
+func TestControlPlanePriorityOfSubject(t *testing.T) {
+	controlPlane := New(nil, nil, nil, WithEventPriority(map[string]int{
+		"sh.keptn.event.rollback.triggered": 10,
+		"sh.keptn.event.*.triggered":        5,
+		"sh.keptn.event.>":                  1,
+	}))
+	require.Equal(t, 10, controlPlane.priority(eventUpdate("", "sh.keptn.event.rollback.triggered")))
+	require.Equal(t, 5, controlPlane.priority(eventUpdate("", "sh.keptn.event.deployment.triggered")))
+	require.Equal(t, 1, controlPlane.priority(eventUpdate("", "sh.keptn.event.deployment.finished")))
+	require.Equal(t, 0, controlPlane.priority(eventUpdate("", "other.event")))
+}
@@ -0,0 +1,42 @@
+package controlplane
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// initialReadinessBackoff is the time waited before the readiness check is run again after it failed for the first time
+	initialReadinessBackoff = 100 * time.Millisecond
+	// maxReadinessBackoff is the maximum time waited between two runs of the readiness check
+	maxReadinessBackoff = 10 * time.Second
+)
+
+// ReadinessCheckFn reports whether the dependencies of an integration are ready by returning nil
+type ReadinessCheckFn func(ctx context.Context) error
+
+// waitUntilReady runs the readiness check until it succeeds, doubling the time waited between two runs up to
+// maxReadinessBackoff. It returns the context error if the context is cancelled before the check succeeded
+func (cp *ControlPlane) waitUntilReady(ctx context.Context) error {
+	if cp.readinessCheck == nil {
+		return nil
+	}
+	backoff := initialReadinessBackoff
+	for {
+		err := cp.readinessCheck(ctx)
+		if err == nil {
+			return nil
+		}
+		cp.logger.Infof("Integration is not ready yet, checking again in %s: %v", backoff, err)
+		timer := cp.clock.Timer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		if backoff *= 2; backoff > maxReadinessBackoff {
+			backoff = maxReadinessBackoff
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneReadinessCheck(t *testing.T) {
+	sources := newFakeSources()
+	var checks int32
+	var checksBeforeRegister int32
+	sources.ssm.RegisterFn = func(integration models.Integration) (string, error) {
+		atomic.StoreInt32(&checksBeforeRegister, atomic.LoadInt32(&checks))
+		return "some-id", nil
+	}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithReadinessCheck(func(ctx context.Context) error {
+		if atomic.AddInt32(&checks, 1) <= 2 {
+			return errors.New("database not reachable")
+		}
+		return nil
+	}))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+
+	sources.channels(t)
+	require.Equal(t, int32(3), atomic.LoadInt32(&checks))
+	require.Equal(t, int32(3), atomic.LoadInt32(&checksBeforeRegister))
+}
+
+func TestControlPlaneReadinessCheckContextCancelled(t *testing.T) {
+	sources := newFakeSources()
+	sources.ssm.RegisterFn = func(integration models.Integration) (string, error) {
+		require.FailNow(t, "unexpected call of Register")
+		return "", nil
+	}
+	checked := make(chan struct{}, 1)
+	controlPlane := New(sources.ssm, sources.esm, nil, WithReadinessCheck(func(ctx context.Context) error {
+		select {
+		case checked <- struct{}{}:
+		default:
+		}
+		return errors.New("database not reachable")
+	}))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	registerErr := make(chan error)
+	go func() { registerErr <- controlPlane.Register(ctx, integration) }()
+	<-checked
+	cancel()
+	select {
+	case err := <-registerErr:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		require.FailNow(t, "registration did not stop after the context was cancelled")
+	}
+}
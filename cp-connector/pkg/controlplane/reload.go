@@ -0,0 +1,108 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// errCancelledByReload is the handling result of events whose handling was cancelled by a configuration reload
+var errCancelledByReload = errors.New("handling cancelled by configuration reload")
+
+// reloadCoordinator pauses the dispatch of events while the configuration of the integration is reloaded
+type reloadCoordinator struct {
+	// dispatchMtx is held for reading while an event is dispatched and for writing while the configuration is reloaded
+	dispatchMtx sync.RWMutex
+	mtx         sync.Mutex
+	reloading   bool
+	inFlight    map[*inFlightEvent]struct{}
+}
+
+type inFlightEvent struct {
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// WithRedeliveryOnReload configures ReloadConfig to cancel the handling of the events that are in flight when
+// the reload starts. The cancelled events are redelivered immediately, so they are handled under the new
+// configuration. Events can only be redelivered if the event source supports acknowledging events
+func WithRedeliveryOnReload() func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.redeliverOnReload = true
+	}
+}
+
+// WithPausedDispatchOnUpdate configures the ControlPlane to pause the dispatch of events while a
+// subscription update is applied. The update waits until the events currently being handled are finished, and no
+// further event is matched until both the ControlPlane and the event source use the new subscriptions.
+// Without the option, every event is still matched against a consistent set of subscriptions, but an event may be
+// matched against the new subscriptions while the event source still receives the subjects of the previous ones
+func WithPausedDispatchOnUpdate() func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.pauseOnUpdate = true
+	}
+}
+
+// ReloadConfig pauses the dispatch of events while reload is called, e.g. to swap the routing rules of the
+// integration. ReloadConfig waits until the events currently being handled are finished, or cancels their handling
+// if WithRedeliveryOnReload is used. No further event is dispatched to the integration until reload returned
+func (cp *ControlPlane) ReloadConfig(reload func()) {
+	r := &cp.reload
+	r.mtx.Lock()
+	r.reloading = true
+	if cp.redeliverOnReload {
+		for event := range r.inFlight {
+			event.cancelled = true
+			event.cancel()
+		}
+	}
+	r.mtx.Unlock()
+
+	r.dispatchMtx.Lock()
+	defer r.dispatchMtx.Unlock()
+	cp.logger.Info("Reloading configuration, dispatching of events is paused")
+	reload()
+	r.mtx.Lock()
+	r.reloading = false
+	r.mtx.Unlock()
+	cp.logger.Info("Configuration reloaded, dispatching of events is resumed")
+}
+
+// dispatch runs the handling of an event unless the configuration is currently reloaded, in which case it
+// blocks until the reload finished. If the handling was cancelled by a reload, errCancelledByReload is returned
+func (cp *ControlPlane) dispatch(ctx context.Context, handle func(ctx context.Context) error) error {
+	r := &cp.reload
+	r.dispatchMtx.RLock()
+	defer r.dispatchMtx.RUnlock()
+	if !cp.redeliverOnReload {
+		return handle(ctx)
+	}
+
+	ctx, untrack := r.track(ctx)
+	err := handle(ctx)
+	// batched and held back events are handled after the reload
+	if untrack() && !errors.Is(err, errEventBatched) && !errors.Is(err, errEventHeldBack) {
+		return errCancelledByReload
+	}
+	return err
+}
+
+// track registers the handling of an event as in flight, so it is cancelled by a reload. The returned function
+// ends the tracking and reports whether the handling was cancelled by a reload
+func (r *reloadCoordinator) track(ctx context.Context) (context.Context, func() bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	event := &inFlightEvent{cancel: cancel}
+	r.mtx.Lock()
+	if r.inFlight == nil {
+		r.inFlight = map[*inFlightEvent]struct{}{}
+	}
+	r.inFlight[event] = struct{}{}
+	r.mtx.Unlock()
+	return ctx, func() bool {
+		cancel()
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+		delete(r.inFlight, event)
+		return event.cancelled
+	}
+}
@@ -0,0 +1,143 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneReloadConfigPausesDispatch(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil)
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			started <- ce.ID
+			if ce.ID == "in-flight" {
+				<-release
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- eventUpdate("in-flight", "sh.keptn.event.echo.triggered")
+	require.Equal(t, "in-flight", <-started)
+
+	reloading := make(chan struct{})
+	finishReload := make(chan struct{})
+	reloaded := make(chan struct{})
+	go func() {
+		defer close(reloaded)
+		controlPlane.ReloadConfig(func() {
+			close(reloading)
+			<-finishReload
+		})
+	}()
+	// the reload waits for the event in flight
+	select {
+	case <-reloading:
+		require.FailNow(t, "reload started while an event was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-reloading
+
+	// no event is dispatched during the reload
+	eventChan <- eventUpdate("during-reload", "sh.keptn.event.echo.triggered")
+	select {
+	case id := <-started:
+		require.FailNow(t, "event dispatched during reload", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(finishReload)
+	<-reloaded
+	require.Equal(t, "during-reload", <-started)
+}
+
+func TestControlPlaneReloadConfigRedeliversInFlightEvents(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithRedeliveryOnReload(), WithRedeliveryDelay(LinearRedeliveryDelay(time.Second, time.Minute)))
+	started := make(chan struct{})
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	registerErr := make(chan error, 1)
+	go func() { registerErr <- controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	acknowledger := &fakeAcknowledger{}
+	event := eventUpdate("in-flight", "sh.keptn.event.echo.triggered")
+	event.Acknowledger = acknowledger
+	eventChan <- event
+	<-started
+
+	reloadCalled := false
+	controlPlane.ReloadConfig(func() { reloadCalled = true })
+	require.True(t, reloadCalled)
+	require.Eventually(t, func() bool {
+		_, nacked := acknowledger.outcomes()
+		return len(nacked) == 1
+	}, time.Second, 10*time.Millisecond)
+	acked, nacked := acknowledger.outcomes()
+	require.Equal(t, 0, acked)
+	// the event is redelivered immediately instead of using the redelivery delay
+	require.Equal(t, []time.Duration{0}, nacked)
+	require.Empty(t, registerErr)
+}
+
+func TestControlPlanePausedDispatchOnUpdate(t *testing.T) {
+	sources := newFakeSources()
+	updated := make(chan []string, 2)
+	sources.esm.OnSubscriptionUpdateFn = func(subjects []string) { updated <- subjects }
+	controlPlane := New(sources.ssm, sources.esm, nil, WithPausedDispatchOnUpdate())
+	started := make(chan string, 1)
+	release := make(chan struct{})
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			started <- ce.ID
+			<-release
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	require.Len(t, <-updated, 1)
+	eventChan <- eventUpdate("in-flight", "sh.keptn.event.echo.triggered")
+	require.Equal(t, "in-flight", <-started)
+
+	subsChan <- []models.EventSubscription{
+		{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"},
+		{ID: "sub-2", Event: "sh.keptn.event.other.triggered"},
+	}
+	// the update waits for the event in flight
+	select {
+	case <-updated:
+		require.FailNow(t, "subscriptions updated while an event was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	require.Len(t, controlPlane.Describe().Subscriptions, 1)
+	close(release)
+	require.Len(t, <-updated, 2)
+	require.Len(t, controlPlane.Describe().Subscriptions, 2)
+}
@@ -0,0 +1,140 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// errEventHeldBack is the handling result of events that did not match any subscription and were held back to be
+// matched again with the next subscription update. They are acknowledged once they were handled or dropped
+var errEventHeldBack = errors.New("event was held back until the next subscription update")
+
+// WithUnmatchedEventBuffer configures the ControlPlane to hold back up to bufferSize events whose subject is not part of
+// any subscription yet, instead of dropping them. With every subscription update, the held back events are matched
+// again and forwarded once a subscription for their subject arrived. Events not being matched within the given ttl
+// are dropped. This avoids losing events that are received before the subscriptions are synchronized at startup
+func WithUnmatchedEventBuffer(bufferSize int, ttl time.Duration) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.unmatchedBufferSize = bufferSize
+		ns.unmatchedTTL = ttl
+	}
+}
+
+type heldBackEvent struct {
+	update types.EventUpdate
+	heldAt time.Time
+}
+
+// unmatchedEvents holds back the events not matching any subscription, oldest first.
+// It is only accessed by the goroutine handling the received events
+type unmatchedEvents struct {
+	clock  clock.Clock
+	size   int
+	ttl    time.Duration
+	events []heldBackEvent
+	// timer expires once the oldest held back event exceeded the ttl
+	timer *clock.Timer
+}
+
+func newUnmatchedEvents(clock clock.Clock, size int, ttl time.Duration) *unmatchedEvents {
+	return &unmatchedEvents{clock: clock, size: size, ttl: ttl}
+}
+
+// add holds back the event and reports whether there was space left to do so
+func (u *unmatchedEvents) add(update types.EventUpdate) bool {
+	if u == nil || len(u.events) >= u.size {
+		return false
+	}
+	u.events = append(u.events, heldBackEvent{update: update, heldAt: u.clock.Now()})
+	if len(u.events) == 1 {
+		u.timer = u.clock.Timer(u.ttl)
+	}
+	return true
+}
+
+// expired returns a channel receiving a value once the oldest held back event exceeded the ttl
+func (u *unmatchedEvents) expired() <-chan time.Time {
+	if u == nil || u.timer == nil {
+		return nil
+	}
+	return u.timer.C
+}
+
+// take removes all held back events
+func (u *unmatchedEvents) take() []heldBackEvent {
+	if u.timer != nil {
+		u.timer.Stop()
+		u.timer = nil
+	}
+	events := u.events
+	u.events = nil
+	return events
+}
+
+// holdBackUnmatched holds back an event not matching any subscription, if enabled via WithUnmatchedEventBuffer,
+// and reports whether the event was held back
+func (cp *ControlPlane) holdBackUnmatched(eventUpdate types.EventUpdate) bool {
+	if !cp.unmatched.add(eventUpdate) {
+		return false
+	}
+	cp.logger.Debugf("Holding back event %s until a subscription for subject %s arrives", eventUpdate.KeptnEvent.ID, eventUpdate.MetaData.Subject)
+	return true
+}
+
+// rematchUnmatched forwards the held back events whose subject is now part of a subscription. Events exceeding the
+// ttl are dropped, the remaining ones are held back until the next subscription update.
+// Only fatal errors are returned
+func (cp *ControlPlane) rematchUnmatched(ctx context.Context, integration Integration, batchIntegration BatchIntegration) error {
+	if cp.unmatched == nil {
+		return nil
+	}
+	now := cp.clock.Now()
+	for _, held := range cp.unmatched.take() {
+		if _, subjectMatched := cp.matchSubscriptions(cp.logger, held.update.MetaData.Subject, held.update.KeptnEvent); subjectMatched {
+			if err := cp.processEvent(ctx, held.update, integration, batchIntegration); err != nil {
+				return err
+			}
+			continue
+		}
+		cp.restoreUnmatched(ctx, held, now)
+	}
+	return nil
+}
+
+// dropExpiredUnmatched drops the held back events exceeding the ttl
+func (cp *ControlPlane) dropExpiredUnmatched(ctx context.Context) {
+	now := cp.clock.Now()
+	for _, held := range cp.unmatched.take() {
+		cp.restoreUnmatched(ctx, held, now)
+	}
+}
+
+// restoreUnmatched holds back the event again, unless it exceeded the ttl. Expired events are dropped
+func (cp *ControlPlane) restoreUnmatched(ctx context.Context, held heldBackEvent, now time.Time) {
+	u := cp.unmatched
+	age := now.Sub(held.heldAt)
+	if age >= u.ttl {
+		cp.logger.Debugf("Dropping event %s: no subscription for subject %s arrived within %s", held.update.KeptnEvent.ID, held.update.MetaData.Subject, u.ttl)
+		cp.dropped(held.update.KeptnEvent, DropReasonNoSubscription)
+		cp.acknowledge(ctx, held.update, nil)
+		return
+	}
+	u.events = append(u.events, held)
+	if len(u.events) == 1 {
+		u.timer = u.clock.Timer(u.ttl - age)
+	}
+}
+
+// abandonUnmatched requests the redelivery of the held back events when the registration stops
+func (cp *ControlPlane) abandonUnmatched(ctx context.Context) {
+	if cp.unmatched == nil {
+		return
+	}
+	for _, held := range cp.unmatched.take() {
+		cp.acknowledge(ctx, held.update, errEventHeldBack)
+	}
+}
@@ -0,0 +1,105 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneRematchesEventReceivedBeforeSubscription(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithUnmatchedEventBuffer(10, time.Minute))
+	handled := make(chan string, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			handled <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	acknowledger := &fakeAcknowledger{}
+	update := eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	update.Acknowledger = acknowledger
+	eventChan <- update
+	// the subscription update is handled after the event, as both are handled by the same goroutine
+	subsChan <- []models.EventSubscription{}
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	select {
+	case id := <-handled:
+		require.Equal(t, "event-id", id)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "held back event was not forwarded")
+	}
+	require.Eventually(t, func() bool {
+		acked, nacked := acknowledger.outcomes()
+		return acked == 1 && len(nacked) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestControlPlaneDropsUnmatchedEventAfterTTL(t *testing.T) {
+	sources := newFakeSources()
+	mockClock := clock.NewMock()
+	reasons := make(chan DropReason, 1)
+	controlPlane := New(sources.ssm, sources.esm, nil, WithClock(mockClock), WithUnmatchedEventBuffer(10, time.Minute), WithDropReasonHandler(func(ce models.KeptnContextExtendedCE, reason DropReason) {
+		reasons <- reason
+	}))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			require.FailNow(t, "unexpected call of OnEvent")
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.other.triggered"}}
+	acknowledger := &fakeAcknowledger{}
+	update := eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	update.Acknowledger = acknowledger
+	eventChan <- update
+
+	var reason DropReason
+	require.Eventually(t, func() bool {
+		mockClock.Add(30 * time.Second)
+		select {
+		case reason = <-reasons:
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, DropReasonNoSubscription, reason)
+	require.Eventually(t, func() bool {
+		acked, _ := acknowledger.outcomes()
+		return acked == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestControlPlaneWithoutUnmatchedEventBufferDropsEvent(t *testing.T) {
+	sources := newFakeSources()
+	reasons := make(chan DropReason, 1)
+	controlPlane := New(sources.ssm, sources.esm, nil, WithDropReasonHandler(func(ce models.KeptnContextExtendedCE, reason DropReason) {
+		reasons <- reason
+	}))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, _ := sources.channels(t)
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	require.Equal(t, DropReasonNoSubscription, <-reasons)
+}
@@ -0,0 +1,73 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+type responseRecorderKeyType struct{}
+
+var responseRecorderKey = responseRecorderKeyType{}
+
+// responseRecorder records the events sent while handling an event whose sender waits for the handling result
+type responseRecorder struct {
+	mtx    sync.Mutex
+	events []models.KeptnContextExtendedCE
+}
+
+func (r *responseRecorder) record(event models.KeptnContextExtendedCE) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *responseRecorder) recorded() []models.KeptnContextExtendedCE {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]models.KeptnContextExtendedCE{}, r.events...)
+}
+
+// withResponseRecorder adds a responseRecorder to the context if the event source waits for the handling result
+func withResponseRecorder(ctx context.Context, eventUpdate types.EventUpdate) context.Context {
+	if eventUpdate.Responder == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, responseRecorderKey, &responseRecorder{})
+}
+
+// recordingSender wraps the sender to record the sent events, if the context holds a responseRecorder
+func recordingSender(ctx context.Context, sender types.EventSender) types.EventSender {
+	recorder, ok := ctx.Value(responseRecorderKey).(*responseRecorder)
+	if !ok {
+		return sender
+	}
+	return func(ce models.KeptnContextExtendedCE) error {
+		if err := sender(ce); err != nil {
+			return err
+		}
+		recorder.record(ce)
+		return nil
+	}
+}
+
+// respond returns the result of handling the event to its sender, if the event source waits for it.
+// Events added to a batch are responded to once the batch was handled
+func (cp *ControlPlane) respond(ctx context.Context, eventUpdate types.EventUpdate, handlingErr error) {
+	if eventUpdate.Responder == nil {
+		return
+	}
+	// the sender receives the error of the integration, not the subscriptions it failed for
+	failure := &subscriptionFailure{}
+	if errors.As(handlingErr, &failure) {
+		handlingErr = failure.err
+	}
+	result := types.HandlingResult{Err: handlingErr}
+	if recorder, ok := ctx.Value(responseRecorderKey).(*responseRecorder); ok {
+		result.Events = recorder.recorded()
+	}
+	eventUpdate.Responder.Respond(result)
+}
@@ -0,0 +1,85 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeResponder is the response mechanism of a synchronous event source
+type fakeResponder chan types.HandlingResult
+
+func (f fakeResponder) Respond(result types.HandlingResult) {
+	f <- result
+}
+
+func TestControlPlaneRespondsWithHandlingResult(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil)
+	handlingErr := errors.New("handling failed")
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			sender := ctx.Value(types.EventSenderKey).(types.EventSender)
+			if err := sender(models.KeptnContextExtendedCE{ID: "response-id", Type: strutils.Stringp("sh.keptn.event.echo.finished")}); err != nil {
+				return err
+			}
+			if ce.ID == "failing-event" {
+				return handlingErr
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	for _, tt := range []struct {
+		eventID string
+		wantErr error
+	}{
+		{eventID: "event-id"},
+		{eventID: "failing-event", wantErr: handlingErr},
+	} {
+		responder := make(fakeResponder, 1)
+		update := eventUpdate(tt.eventID, "sh.keptn.event.echo.triggered")
+		update.Responder = responder
+		eventChan <- update
+		select {
+		case result := <-responder:
+			require.Equal(t, tt.wantErr, result.Err)
+			require.Len(t, result.Events, 1)
+			require.Equal(t, "response-id", result.Events[0].ID)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "handling result was not returned")
+		}
+	}
+	require.Len(t, sources.sentEvents(), 2)
+}
+
+func TestControlPlaneRespondsToDroppedEvent(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, _ := sources.channels(t)
+	responder := make(fakeResponder, 1)
+	update := eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	update.Responder = responder
+	eventChan <- update
+	result := <-responder
+	require.NoError(t, result.Err)
+	require.Empty(t, result.Events)
+}
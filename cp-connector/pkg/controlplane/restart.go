@@ -0,0 +1,46 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// maxRestartBackoff is the upper bound of the backoff RunWithRestart waits before a restart, unless the initial
+// backoff is already larger
+const maxRestartBackoff = 5 * time.Minute
+
+// RunWithRestart registers the integration at the ControlPlane and re-runs the registration whenever it stopped
+// because handling an event failed fatally. Before each restart, RunWithRestart waits for the backoff, which is
+// doubled with every restart up to 5 minutes. Once the integration was restarted maxRestarts times, the last fatal error is returned.
+// Any other result of Register, as well as cancelling the given context, stops RunWithRestart immediately
+func RunWithRestart(ctx context.Context, cp *ControlPlane, integration Integration, maxRestarts int, backoff time.Duration) error {
+	for restarts := 0; ; restarts++ {
+		err := cp.Register(ctx, integration)
+		if !errors.Is(err, ErrEventHandleFatal) || restarts >= maxRestarts {
+			return err
+		}
+		delay := restartBackoff(backoff, restarts)
+		cp.logger.Errorf("Restarting integration in %s after fatal error (restart %d of %d): %v", delay, restarts+1, maxRestarts, err)
+		timer := cp.clock.Timer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// restartBackoff returns the backoff before the restart following the given number of restarts. The backoff is
+// doubled with every restart until it reaches maxRestartBackoff
+func restartBackoff(backoff time.Duration, restarts int) time.Duration {
+	delay := backoff
+	for i := 0; i < restarts && delay > 0 && delay < maxRestartBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRestartBackoff && backoff <= maxRestartBackoff {
+		return maxRestartBackoff
+	}
+	return delay
+}
@@ -0,0 +1,120 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	fake2 "github.com/keptn/keptn/cp-connector/pkg/fake"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+// restartingSources sends a subscription and a matching event each time the sources are started
+func restartingSources() (*fake2.SubscriptionSourceMock, *fake2.EventSourceMock, func() int) {
+	var mtx sync.Mutex
+	registrations := 0
+	var events chan types.EventUpdate
+	esm := &fake2.EventSourceMock{
+		StartFn: func(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, states chan types.ConnectionState, errC chan error) (<-chan struct{}, error) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			events = ces
+			return stopOnCancel(ctx), nil
+		},
+		OnSubscriptionUpdateFn: func(strings []string) {},
+		SenderFn: func() types.EventSender {
+			return func(ce models.KeptnContextExtendedCE) error { return nil }
+		},
+	}
+	ssm := &fake2.SubscriptionSourceMock{
+		RegisterFn: func(integration models.Integration) (string, error) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			registrations++
+			return fmt.Sprintf("id-%d", registrations), nil
+		},
+		StartFn: func(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, errC chan error) (<-chan struct{}, error) {
+			mtx.Lock()
+			ces := events
+			mtx.Unlock()
+			go func() {
+				select {
+				case c <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}:
+				case <-ctx.Done():
+					return
+				}
+				select {
+				case ces <- eventUpdate("event-id", "sh.keptn.event.echo.triggered"):
+				case <-ctx.Done():
+				}
+			}()
+			return stopOnCancel(ctx), nil
+		},
+	}
+	return ssm, esm, func() int {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return registrations
+	}
+}
+
+func TestRunWithRestart(t *testing.T) {
+	ssm, esm, registrations := restartingSources()
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			return fmt.Errorf("broken: %w", ErrEventHandleFatal)
+		},
+	}
+	err := RunWithRestart(context.TODO(), New(ssm, esm, nil), integration, 3, time.Millisecond)
+	require.ErrorIs(t, err, ErrEventHandleFatal)
+	require.Equal(t, 4, registrations())
+}
+
+func TestRunWithRestartRecovers(t *testing.T) {
+	ssm, esm, registrations := restartingSources()
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			if registrations() == 1 {
+				return fmt.Errorf("broken: %w", ErrEventHandleFatal)
+			}
+			// the second registration handles the event successfully and runs until the context is cancelled
+			cancel()
+			return nil
+		},
+	}
+	require.NoError(t, RunWithRestart(ctx, New(ssm, esm, nil), integration, 3, time.Millisecond))
+	require.Equal(t, 2, registrations())
+}
+
+func TestRunWithRestartDoesNotRestartOnOtherErrors(t *testing.T) {
+	ssm, esm, registrations := restartingSources()
+	ssm.RegisterFn = func(integration models.Integration) (string, error) {
+		return "", errors.New("registration failed")
+	}
+	err := RunWithRestart(context.TODO(), New(ssm, esm, nil), ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+	}, 3, time.Millisecond)
+	require.ErrorContains(t, err, "registration failed")
+	require.Equal(t, 0, registrations())
+}
+
+func TestRestartBackoff(t *testing.T) {
+	require.Equal(t, time.Second, restartBackoff(time.Second, 0))
+	require.Equal(t, 8*time.Second, restartBackoff(time.Second, 3))
+	// the backoff does not overflow, but stops growing at the maximum
+	require.Equal(t, maxRestartBackoff, restartBackoff(time.Second, 9))
+	require.Equal(t, maxRestartBackoff, restartBackoff(time.Second, 100))
+	require.Equal(t, maxRestartBackoff, restartBackoff(time.Second, 1<<30))
+	// an initial backoff above the maximum is kept
+	require.Equal(t, time.Hour, restartBackoff(time.Hour, 5))
+	require.Equal(t, time.Duration(0), restartBackoff(0, 1<<30))
+}
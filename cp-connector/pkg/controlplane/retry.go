@@ -0,0 +1,51 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+)
+
+// RetryBackoffFn computes the delay before the given retry of a failed handling, starting with 1 for the first retry
+type RetryBackoffFn func(retry int) time.Duration
+
+// WithHandlerRetry configures the ControlPlane to call OnEvent again if the handling of an event failed with a
+// non-fatal error, until the event was handled or the given number of attempts is exhausted. Only then the
+// handling is treated as failed, e.g. the event is redelivered by the broker. The given backoff computes the delay
+// before each retry, if it is nil the handler is retried immediately.
+// Events whose context is cancelled, e.g. due to a shutdown or a configuration reload, are not retried
+func WithHandlerRetry(maxAttempts int, backoff RetryBackoffFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.handlerAttempts = maxAttempts
+		ns.handlerRetryBackoff = backoff
+	}
+}
+
+// callIntegrationWithRetry calls the integration until the handling succeeded, failed with a fatal error
+// or the configured number of attempts is exhausted. The error of the last attempt is returned
+func (cp *ControlPlane) callIntegrationWithRetry(ctx context.Context, integration Integration, event models.KeptnContextExtendedCE) error {
+	err := cp.callIntegration(ctx, integration, event)
+	for retry := 1; retry < cp.handlerAttempts && err != nil; retry++ {
+		if errors.Is(err, ErrEventHandleFatal) || ctx.Err() != nil {
+			return err
+		}
+		var delay time.Duration
+		if cp.handlerRetryBackoff != nil {
+			delay = cp.handlerRetryBackoff(retry)
+		}
+		cp.eventLogger(ctx).Debugf("Handling of event %s failed, retrying in %s: %v", event.ID, delay, err)
+		if delay > 0 {
+			timer := cp.clock.Timer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			}
+		}
+		err = cp.callIntegration(ctx, integration, event)
+	}
+	return err
+}
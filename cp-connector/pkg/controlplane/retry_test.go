@@ -0,0 +1,109 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneHandlerRetrySucceeds(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithHandlerRetry(3, nil))
+	var mtx sync.Mutex
+	attempts := 0
+	handled := 0
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			attempts++
+			if attempts <= 2 {
+				return errors.New("temporarily unavailable")
+			}
+			handled++
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	acknowledger := &fakeAcknowledger{}
+	update := eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	update.Acknowledger = acknowledger
+	eventChan <- update
+
+	require.Eventually(t, func() bool {
+		acked, _ := acknowledger.outcomes()
+		return acked == 1
+	}, time.Second, 10*time.Millisecond)
+	_, nacked := acknowledger.outcomes()
+	require.Empty(t, nacked)
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Equal(t, 3, attempts)
+	require.Equal(t, 1, handled)
+}
+
+func TestControlPlaneHandlerRetryExhausted(t *testing.T) {
+	sources := newFakeSources()
+	var mtx sync.Mutex
+	var retries []int
+	attempts := 0
+	controlPlane := New(sources.ssm, sources.esm, nil, WithHandlerRetry(3, func(retry int) time.Duration {
+		mtx.Lock()
+		defer mtx.Unlock()
+		retries = append(retries, retry)
+		return time.Millisecond
+	}))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			attempts++
+			if ce.ID == "fatal" {
+				return ErrEventHandleFatal
+			}
+			return errors.New("handling failed")
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	acknowledger := &fakeAcknowledger{}
+	update := eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	update.Acknowledger = acknowledger
+	eventChan <- update
+
+	require.Eventually(t, func() bool {
+		_, nacked := acknowledger.outcomes()
+		return len(nacked) == 1
+	}, time.Second, 10*time.Millisecond)
+	acked, _ := acknowledger.outcomes()
+	require.Equal(t, 0, acked)
+	mtx.Lock()
+	require.Equal(t, 3, attempts)
+	require.Equal(t, []int{1, 2}, retries)
+	attempts = 0
+	mtx.Unlock()
+
+	// fatal errors are not retried
+	eventChan <- eventUpdate("fatal", "sh.keptn.event.echo.triggered")
+	require.Eventually(t, func() bool { return !controlPlane.IsRegistered() }, time.Second, 10*time.Millisecond)
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Equal(t, 1, attempts)
+}
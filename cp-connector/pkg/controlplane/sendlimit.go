@@ -0,0 +1,28 @@
+package controlplane
+
+import (
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// WithMaxConcurrentSends limits the number of events that are sent at the same time by all senders passed to the
+// integration, e.g. to avoid overwhelming the Keptn API when many handlers send their .started and .finished events
+// at once. Further sends block until one of the running sends returned. Other than a rate limit, the limit does not
+// delay sends as long as fewer sends are running. A limit <= 0 disables the limit, which is the default
+func WithMaxConcurrentSends(n int) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.maxConcurrentSends = n
+	}
+}
+
+// limitSends wraps the sender so that it waits for a free slot of the global send limit, if one is configured
+func (cp *ControlPlane) limitSends(sender types.EventSender) types.EventSender {
+	if cp.sendSlots == nil {
+		return sender
+	}
+	return func(ce models.KeptnContextExtendedCE) error {
+		cp.sendSlots <- struct{}{}
+		defer func() { <-cp.sendSlots }()
+		return sender(ce)
+	}
+}
@@ -0,0 +1,78 @@
+package controlplane
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneMaxConcurrentSends(t *testing.T) {
+	const handlers, limit = 8, 2
+	var running, maxRunning int32
+	sources := newFakeSources()
+	sources.esm.SenderFn = func() types.EventSender {
+		return func(ce models.KeptnContextExtendedCE) error {
+			current := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		}
+	}
+	// every subscription is handled by its own worker, so the handlers send concurrently
+	var subjects []string
+	var subscriptions []models.EventSubscription
+	for i := 0; i < handlers; i++ {
+		subject := fmt.Sprintf("sh.keptn.event.task-%d.triggered", i)
+		subjects = append(subjects, subject)
+		subscriptions = append(subscriptions, models.EventSubscription{ID: fmt.Sprintf("sub-%d", i), Event: subject})
+	}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithPerSubscriptionOrdering(subjects...), WithMaxConcurrentSends(limit))
+
+	var sent sync.WaitGroup
+	sent.Add(handlers * 2)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			sender := ctx.Value(types.EventSenderKey).(types.EventSender)
+			for _, suffix := range []string{"started", "finished"} {
+				_ = sender(models.KeptnContextExtendedCE{Type: strutils.Stringp("sh.keptn.event.task." + suffix)})
+				sent.Done()
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- subscriptions
+	for i, subject := range subjects {
+		eventChan <- eventUpdate(fmt.Sprintf("event-%d", i), subject)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		sent.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "events were not sent")
+	}
+	require.Equal(t, int32(limit), atomic.LoadInt32(&maxRunning))
+}
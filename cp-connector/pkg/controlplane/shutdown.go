@@ -0,0 +1,41 @@
+package controlplane
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// RunUntilSignal registers the integration at the ControlPlane and blocks until SIGINT or SIGTERM
+// is received or the given context is cancelled.
+// On shutdown, the ControlPlane stops receiving events, waits for the event and subscription sources to stop
+// and removes the registration of the integration from the Keptn control plane.
+// An error is returned if the registration fails, handling an event fails fatally or the integration
+// cannot be unregistered
+func RunUntilSignal(ctx context.Context, cp *ControlPlane, integration Integration) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	return runUntilSignal(ctx, cp, integration, signals)
+}
+
+func runUntilSignal(ctx context.Context, cp *ControlPlane, integration Integration, signals <-chan os.Signal) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case sig := <-signals:
+			cp.logger.Infof("Received signal %v, shutting down", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// Register only returns without an error once the context is cancelled and all sources have stopped
+	if err := cp.Register(ctx, integration); err != nil {
+		return err
+	}
+	// the context is cancelled at this point, but the registration must still be removed
+	return cp.Deregister(context.Background())
+}
@@ -0,0 +1,109 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunUntilSignal(t *testing.T) {
+	sources := newFakeSources()
+	var mtx sync.Mutex
+	var unregistered []string
+	sources.ssm.UnregisterFn = func(integrationID string) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		unregistered = append(unregistered, integrationID)
+		return nil
+	}
+	controlPlane := New(sources.ssm, sources.esm, nil)
+
+	received := make(chan string, 1)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			received <- ce.ID
+			return nil
+		},
+	}
+	signals := make(chan os.Signal, 1)
+	runErr := make(chan error)
+	go func() { runErr <- runUntilSignal(context.TODO(), controlPlane, integration, signals) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- types.EventUpdate{
+		KeptnEvent: models.KeptnContextExtendedCE{ID: "event-id", Type: strutils.Stringp("sh.keptn.event.echo.triggered")},
+		MetaData:   types.EventUpdateMetaData{Subject: "sh.keptn.event.echo.triggered"},
+	}
+	require.Equal(t, "event-id", <-received)
+
+	signals <- syscall.SIGTERM
+	select {
+	case err := <-runErr:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "control plane did not stop after signal")
+	}
+	require.False(t, controlPlane.IsRegistered())
+	require.Equal(t, []string{"some-id"}, unregistered)
+}
+
+func TestRunUntilSignalUnregisterFails(t *testing.T) {
+	sources := newFakeSources()
+	sources.ssm.UnregisterFn = func(integrationID string) error {
+		return errors.New("api unavailable")
+	}
+	controlPlane := New(sources.ssm, sources.esm, nil)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+	}
+	signals := make(chan os.Signal, 1)
+	runErr := make(chan error)
+	go func() { runErr <- runUntilSignal(context.TODO(), controlPlane, integration, signals) }()
+	sources.channels(t)
+
+	signals <- syscall.SIGINT
+	require.ErrorContains(t, <-runErr, "api unavailable")
+}
+
+func TestRunUntilSignalRegistrationFails(t *testing.T) {
+	sources := newFakeSources()
+	sources.ssm.RegisterFn = func(integration models.Integration) (string, error) {
+		return "", errors.New("registration failed")
+	}
+	sources.ssm.UnregisterFn = func(integrationID string) error {
+		require.FailNow(t, "unexpected call of Unregister")
+		return nil
+	}
+	controlPlane := New(sources.ssm, sources.esm, nil)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+	}
+	err := runUntilSignal(context.TODO(), controlPlane, integration, make(chan os.Signal))
+	require.ErrorContains(t, err, "registration failed")
+}
+
+func TestRunUntilSignalWithCancelledContext(t *testing.T) {
+	sources := newFakeSources()
+	sources.ssm.RegisterFn = func(integration models.Integration) (string, error) {
+		require.FailNow(t, "unexpected call of Register")
+		return "", nil
+	}
+	sources.ssm.UnregisterFn = func(integrationID string) error {
+		require.FailNow(t, "unexpected call of Unregister")
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	err := runUntilSignal(ctx, New(sources.ssm, sources.esm, nil), ExampleIntegration{}, make(chan os.Signal))
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,67 @@
+package controlplane
+
+import (
+	"sync/atomic"
+)
+
+// Stats holds cumulative counters of the events seen by the ControlPlane since it was created
+type Stats struct {
+	// Received is the number of events received from the event source
+	Received uint64 `json:"received"`
+	// Matched is the number of received events matching at least one subscription
+	Matched uint64 `json:"matched"`
+	// Forwarded is the number of events the integration handled successfully, counted once per matched subscription
+	Forwarded uint64 `json:"forwarded"`
+	// Failed is the number of events the integration failed to handle, counted once per matched subscription
+	Failed uint64 `json:"failed"`
+	// Dropped is the number of events that were not forwarded to the integration, including duplicates
+	Dropped uint64 `json:"dropped"`
+	// Duplicates is the number of events dropped because they were already handled
+	Duplicates uint64 `json:"duplicates"`
+}
+
+// stats holds the counters returned by Stats, which are updated atomically
+type stats struct {
+	received   uint64
+	matched    uint64
+	forwarded  uint64
+	failed     uint64
+	dropped    uint64
+	duplicates uint64
+}
+
+// Stats returns the current counters of the ControlPlane. In contrast to WithMetrics, no prometheus
+// registry is needed, e.g. to show the counters on a simple in-process dashboard
+func (cp *ControlPlane) Stats() Stats {
+	return Stats{
+		Received:   atomic.LoadUint64(&cp.stats.received),
+		Matched:    atomic.LoadUint64(&cp.stats.matched),
+		Forwarded:  atomic.LoadUint64(&cp.stats.forwarded),
+		Failed:     atomic.LoadUint64(&cp.stats.failed),
+		Dropped:    atomic.LoadUint64(&cp.stats.dropped),
+		Duplicates: atomic.LoadUint64(&cp.stats.duplicates),
+	}
+}
+
+func (s *stats) observeReceived() {
+	atomic.AddUint64(&s.received, 1)
+}
+
+func (s *stats) observeMatched() {
+	atomic.AddUint64(&s.matched, 1)
+}
+
+func (s *stats) observeHandled(err error) {
+	if err != nil {
+		atomic.AddUint64(&s.failed, 1)
+		return
+	}
+	atomic.AddUint64(&s.forwarded, 1)
+}
+
+func (s *stats) observeDropped(reason DropReason) {
+	atomic.AddUint64(&s.dropped, 1)
+	if reason == DropReasonDuplicate {
+		atomic.AddUint64(&s.duplicates, 1)
+	}
+}
@@ -0,0 +1,39 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneStats(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithIdempotencyStore(NewInMemoryIdempotencyStore(10)))
+	require.Equal(t, Stats{}, controlPlane.Stats())
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			if ce.ID == "failing-event" {
+				return errors.New("could not handle event")
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- eventUpdate("event-1", "sh.keptn.event.echo.triggered")
+	eventChan <- eventUpdate("failing-event", "sh.keptn.event.echo.triggered")
+	eventChan <- eventUpdate("event-1", "sh.keptn.event.echo.triggered")
+	eventChan <- eventUpdate("event-2", "sh.keptn.event.other.triggered")
+
+	want := Stats{Received: 4, Matched: 2, Forwarded: 1, Failed: 1, Dropped: 2, Duplicates: 1}
+	require.Eventually(t, func() bool { return controlPlane.Stats() == want }, 5*time.Second, 10*time.Millisecond)
+}
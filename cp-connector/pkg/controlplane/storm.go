@@ -0,0 +1,95 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// errShedDuringStorm is the handling result of events rejected because of an event storm
+var errShedDuringStorm = errors.New("event rejected during event storm")
+
+// stormDetector counts the received events in consecutive windows to detect receive rates
+// exceeding the threshold configured via WithStormProtection
+type stormDetector struct {
+	// limit is the number of events per window that can be received without exceeding the threshold
+	limit       int
+	window      time.Duration
+	windowStart time.Time
+	received    int
+	storm       bool
+}
+
+func newStormDetector(ratePerSec float64, window time.Duration) *stormDetector {
+	if ratePerSec <= 0 || window <= 0 {
+		return nil
+	}
+	limit := int(ratePerSec * window.Seconds())
+	if limit < 1 {
+		limit = 1
+	}
+	return &stormDetector{limit: limit, window: window}
+}
+
+// WithStormProtection configures the ControlPlane to detect event storms, e.g. caused by an upstream
+// misconfiguration producing events in a loop. A storm is detected once more events than the given rate are
+// received on average over the given window, and it is over once a window stays below the rate.
+// Storms are logged and counted by the metrics. Use WithStormLoadShedding to also reject events during a storm
+func WithStormProtection(ratePerSec float64, window time.Duration) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.stormRate = ratePerSec
+		ns.stormWindow = window
+	}
+}
+
+// WithStormLoadShedding configures the ControlPlane to reject the events exceeding the rate configured via
+// WithStormProtection while an event storm is ongoing. Rejected events are redelivered after the given delay.
+// If the event source does not support acknowledging events, rejected events are lost
+func WithStormLoadShedding(redeliveryDelay time.Duration) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.stormShedding = true
+		ns.stormRedeliveryDelay = redeliveryDelay
+	}
+}
+
+// observe counts a received event and returns whether it exceeds the threshold, as well as whether
+// an event storm started or ended with the event
+func (d *stormDetector) observe(now time.Time) (exceeded bool, started bool, ended bool) {
+	if now.Sub(d.windowStart) >= d.window {
+		if d.storm && d.received <= d.limit {
+			d.storm, ended = false, true
+		}
+		d.windowStart, d.received = now, 0
+	}
+	d.received++
+	exceeded = d.received > d.limit
+	if exceeded && !d.storm {
+		d.storm, started = true, true
+	}
+	return exceeded, started, ended
+}
+
+// shedDuringStorm observes the received event and rejects it if it exceeds the threshold during an event storm
+// and load shedding is enabled. It returns true if the event was rejected
+func (cp *ControlPlane) shedDuringStorm(ctx context.Context, event types.EventUpdate) bool {
+	if cp.storm == nil {
+		return false
+	}
+	exceeded, started, ended := cp.storm.observe(cp.clock.Now())
+	if ended {
+		cp.logger.Info("Event storm is over, receive rate is back below the threshold")
+	}
+	if started {
+		cp.logger.Warnf("Event storm detected: received more than %d events within %s", cp.storm.limit, cp.storm.window)
+		cp.metrics.observeEventStorm()
+	}
+	if !exceeded || !cp.stormShedding {
+		return false
+	}
+	cp.logger.Debugf("Rejecting event %s during event storm", event.KeptnEvent.ID)
+	cp.metrics.observeShedEvent()
+	cp.acknowledge(ctx, event, errShedDuringStorm)
+	return true
+}
@@ -0,0 +1,120 @@
+package controlplane
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/keptn/go-utils/pkg/api/models"
+	fake2 "github.com/keptn/keptn/cp-connector/pkg/fake"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneStormProtection(t *testing.T) {
+	sources := newFakeSources()
+	mockClock := clock.NewMock()
+	log := &fake2.LoggerMock{}
+	controlPlane := New(sources.ssm, sources.esm, nil,
+		WithClock(mockClock),
+		WithLogger(log),
+		WithMetrics(prometheus.NewRegistry()),
+		WithStormProtection(5, time.Second),
+		WithStormLoadShedding(10*time.Second),
+	)
+	var mtx sync.Mutex
+	var handled []string
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			handled = append(handled, ce.ID)
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	// a burst of 7 events within the window of 1 second exceeds the rate of 5 events per second
+	acknowledger := &fakeAcknowledger{}
+	for i := 0; i < 7; i++ {
+		update := eventUpdate(fmt.Sprintf("burst-%d", i), "sh.keptn.event.echo.triggered")
+		update.Acknowledger = acknowledger
+		eventChan <- update
+	}
+	require.Eventually(t, func() bool {
+		acked, nacked := acknowledger.outcomes()
+		return acked == 5 && len(nacked) == 2
+	}, time.Second, 10*time.Millisecond)
+	_, nacked := acknowledger.outcomes()
+	require.Equal(t, []time.Duration{10 * time.Second, 10 * time.Second}, nacked)
+	require.True(t, log.Contains("Event storm detected: received more than 5 events within 1s"))
+	require.Equal(t, float64(1), testutil.ToFloat64(controlPlane.metrics.eventStorms))
+	require.Equal(t, float64(2), testutil.ToFloat64(controlPlane.metrics.shedEvents))
+
+	// events are handled again once the next window starts
+	mockClock.Add(time.Second)
+	eventChan <- eventUpdate("after-storm", "sh.keptn.event.echo.triggered")
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(handled) == 6
+	}, time.Second, 10*time.Millisecond)
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.Equal(t, []string{"burst-0", "burst-1", "burst-2", "burst-3", "burst-4", "after-storm"}, handled)
+}
+
+func TestControlPlaneStormProtectionWithoutLoadShedding(t *testing.T) {
+	sources := newFakeSources()
+	log := &fake2.LoggerMock{}
+	controlPlane := New(sources.ssm, sources.esm, nil, WithClock(clock.NewMock()), WithLogger(log), WithStormProtection(1, time.Second))
+	handled := make(chan string)
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			handled <- ce.ID
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprintf("event-%d", i)
+		eventChan <- eventUpdate(id, "sh.keptn.event.echo.triggered")
+		require.Equal(t, id, <-handled)
+	}
+	require.True(t, log.Contains("Event storm detected"))
+}
+
+func TestStormDetector(t *testing.T) {
+	detector := newStormDetector(2, time.Second)
+	start := time.Unix(0, 0)
+	observe := func(offset time.Duration) [3]bool {
+		exceeded, started, ended := detector.observe(start.Add(offset))
+		return [3]bool{exceeded, started, ended}
+	}
+	require.Equal(t, [3]bool{false, false, false}, observe(0))
+	require.Equal(t, [3]bool{false, false, false}, observe(100*time.Millisecond))
+	require.Equal(t, [3]bool{true, true, false}, observe(200*time.Millisecond))
+	require.Equal(t, [3]bool{true, false, false}, observe(300*time.Millisecond))
+	// the storm lasts as long as windows exceed the threshold
+	require.Equal(t, [3]bool{false, false, false}, observe(time.Second))
+	require.Equal(t, [3]bool{false, false, false}, observe(1100*time.Millisecond))
+	require.Equal(t, [3]bool{false, false, true}, observe(2*time.Second))
+
+	require.Nil(t, newStormDetector(0, time.Second))
+}
@@ -0,0 +1,43 @@
+package controlplane
+
+import (
+	"reflect"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+)
+
+// SubscriptionDiffHandlerFn is called with the subscriptions added and removed by a subscription update
+type SubscriptionDiffHandlerFn func(added []models.EventSubscription, removed []models.EventSubscription)
+
+// WithSubscriptionDiffHandler sets a function called for every subscription update that changes the subscriptions,
+// e.g. to subscribe to or unsubscribe from the affected subjects only. A subscription whose ID is kept but
+// whose content changed is reported as removed and added again
+func WithSubscriptionDiffHandler(handler SubscriptionDiffHandlerFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.subscriptionDiffFn = handler
+	}
+}
+
+// diffSubscriptions returns the subscriptions contained in next but not in previous, and vice versa.
+// Subscriptions are identified by their ID, the order of the subscriptions is kept
+func diffSubscriptions(previous []models.EventSubscription, next []models.EventSubscription) (added []models.EventSubscription, removed []models.EventSubscription) {
+	previousByID := make(map[string]models.EventSubscription, len(previous))
+	for _, s := range previous {
+		previousByID[s.ID] = s
+	}
+	nextByID := make(map[string]models.EventSubscription, len(next))
+	for _, s := range next {
+		nextByID[s.ID] = s
+	}
+	for _, s := range previous {
+		if n, ok := nextByID[s.ID]; !ok || !reflect.DeepEqual(s, n) {
+			removed = append(removed, s)
+		}
+	}
+	for _, s := range next {
+		if p, ok := previousByID[s.ID]; !ok || !reflect.DeepEqual(s, p) {
+			added = append(added, s)
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,60 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+type subscriptionDiff struct {
+	added   []models.EventSubscription
+	removed []models.EventSubscription
+}
+
+func TestControlPlaneSubscriptionDiffHandler(t *testing.T) {
+	sources := newFakeSources()
+	diffs := make(chan subscriptionDiff, 1)
+	controlPlane := New(sources.ssm, sources.esm, nil, WithSubscriptionDiffHandler(func(added []models.EventSubscription, removed []models.EventSubscription) {
+		diffs <- subscriptionDiff{added: added, removed: removed}
+	}))
+	integration := ExampleIntegration{RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} }}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	_, subsChan := sources.channels(t)
+
+	deployment := models.EventSubscription{ID: "sub-1", Event: "sh.keptn.event.deployment.triggered"}
+	test := models.EventSubscription{ID: "sub-2", Event: "sh.keptn.event.test.triggered"}
+	evaluation := models.EventSubscription{ID: "sub-3", Event: "sh.keptn.event.evaluation.triggered"}
+	subsChan <- []models.EventSubscription{deployment, test}
+	require.Equal(t, subscriptionDiff{added: []models.EventSubscription{deployment, test}}, <-diffs)
+
+	subsChan <- []models.EventSubscription{test, evaluation}
+	require.Equal(t, subscriptionDiff{added: []models.EventSubscription{evaluation}, removed: []models.EventSubscription{deployment}}, <-diffs)
+
+	// an unchanged update is not reported, the next diff is the one of the following update
+	subsChan <- []models.EventSubscription{test, evaluation}
+	subsChan <- []models.EventSubscription{evaluation}
+	require.Equal(t, subscriptionDiff{removed: []models.EventSubscription{test}}, <-diffs)
+}
+
+func TestDiffSubscriptions(t *testing.T) {
+	previous := []models.EventSubscription{
+		{ID: "sub-1", Event: "sh.keptn.event.deployment.triggered"},
+		{ID: "sub-2", Event: "sh.keptn.event.test.triggered"},
+	}
+	next := []models.EventSubscription{
+		{ID: "sub-2", Event: "sh.keptn.event.test.triggered", Filter: models.EventSubscriptionFilter{Projects: []string{"podtato"}}},
+		{ID: "sub-1", Event: "sh.keptn.event.deployment.triggered"},
+	}
+	added, removed := diffSubscriptions(previous, next)
+	require.Equal(t, []models.EventSubscription{next[0]}, added)
+	require.Equal(t, []models.EventSubscription{previous[1]}, removed)
+
+	added, removed = diffSubscriptions(next, next)
+	require.Empty(t, added)
+	require.Empty(t, removed)
+}
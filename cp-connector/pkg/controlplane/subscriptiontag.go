@@ -0,0 +1,31 @@
+package controlplane
+
+import (
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// SubscriptionIDExtension is the extension holding the ID of the subscription an event was forwarded for on the
+// events the integration sends while handling it
+const SubscriptionIDExtension = "subscriptionid"
+
+// WithSubscriptionIDTagging configures whether the ControlPlane adds the ID of the matched subscription as
+// SubscriptionIDExtension to the events the integration sends while handling an event, e.g. to correlate
+// the .finished event with the subscription that triggered the work. Tagging is enabled by default.
+// An extension already set by the integration is kept
+func WithSubscriptionIDTagging(enabled bool) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.subscriptionTagging = enabled
+	}
+}
+
+// tagSubscription wraps the sender to add the given subscription ID to the sent events, if tagging is enabled
+func (cp *ControlPlane) tagSubscription(sender types.EventSender, subscriptionID string) types.EventSender {
+	if !cp.subscriptionTagging || subscriptionID == "" {
+		return sender
+	}
+	extensions := map[string]interface{}{SubscriptionIDExtension: subscriptionID}
+	return func(ce models.KeptnContextExtendedCE) error {
+		return sender(cp.mergeExtensions(ce, extensions))
+	}
+}
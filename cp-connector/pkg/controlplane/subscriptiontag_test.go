@@ -0,0 +1,67 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneSubscriptionIDTagging(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []func(*ControlPlane)
+		extensions interface{}
+		want       interface{}
+	}{
+		{
+			name: "tagged by default",
+			want: map[string]interface{}{SubscriptionIDExtension: "sub-2"},
+		},
+		{
+			name:       "own extensions are kept",
+			extensions: map[string]interface{}{"traceid": "trace-1", SubscriptionIDExtension: "own-id"},
+			want:       map[string]interface{}{"traceid": "trace-1", SubscriptionIDExtension: "own-id"},
+		},
+		{
+			name: "disabled",
+			opts: []func(*ControlPlane){WithSubscriptionIDTagging(false)},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := newFakeSources()
+			controlPlane := New(sources.ssm, sources.esm, nil, tt.opts...)
+			integration := ExampleIntegration{
+				RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+				OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+					sender := ctx.Value(types.EventSenderKey).(types.EventSender)
+					return sender(models.KeptnContextExtendedCE{
+						ID:         "finished-id",
+						Type:       strutils.Stringp("sh.keptn.event.echo.finished"),
+						Extensions: tt.extensions,
+					})
+				},
+			}
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			go func() { _ = controlPlane.Register(ctx, integration) }()
+			eventChan, subsChan := sources.channels(t)
+			subsChan <- []models.EventSubscription{
+				{ID: "sub-1", Event: "sh.keptn.event.other.triggered"},
+				{ID: "sub-2", Event: "sh.keptn.event.echo.triggered"},
+			}
+			eventChan <- eventUpdate("triggered-id", "sh.keptn.event.echo.triggered")
+
+			require.Eventually(t, func() bool { return len(sources.sentEvents()) == 1 }, time.Second, 10*time.Millisecond)
+			sent := sources.sentEvents()[0]
+			require.Equal(t, "finished-id", sent.ID)
+			require.Equal(t, tt.want, sent.Extensions)
+		})
+	}
+}
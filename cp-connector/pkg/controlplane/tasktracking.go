@@ -0,0 +1,149 @@
+package controlplane
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/keptn/go-utils/pkg/api/models"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// OutstandingTask is a .triggered event forwarded to the integration for which no .finished event was sent yet
+type OutstandingTask struct {
+	KeptnContext string
+	// TriggeredID is the ID of the .triggered event
+	TriggeredID string
+	Type        string
+	// TriggeredAt is the time the .triggered event was forwarded to the integration
+	TriggeredAt time.Time
+}
+
+// StuckTaskFn is called with a task for which no .finished event was sent within the configured timeout
+type StuckTaskFn func(task OutstandingTask)
+
+// WithTaskTracking configures the ControlPlane to track the .triggered events forwarded to the integration until
+// the corresponding .finished event is sent, matched by their keptn context and triggered ID. The given function
+// is called once for every task that is still outstanding after the given timeout, e.g. to detect stuck tasks.
+// The outstanding tasks are available via OutstandingTasks and are forgotten once the registration stopped.
+// A timeout <= 0 disables the task tracking
+func WithTaskTracking(timeout time.Duration, onStuck StuckTaskFn) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.taskTimeout = timeout
+		ns.stuckTaskFn = onStuck
+	}
+}
+
+// OutstandingTasks returns the tasks awaiting a .finished event, oldest first.
+// It returns nil if task tracking is not enabled via WithTaskTracking
+func (cp *ControlPlane) OutstandingTasks() []OutstandingTask {
+	return cp.tasks.outstanding()
+}
+
+type taskKey struct {
+	keptnContext string
+	triggeredID  string
+}
+
+type trackedTask struct {
+	task  OutstandingTask
+	timer *clock.Timer
+}
+
+// taskTracker holds the outstanding tasks. It is accessed concurrently by the goroutine handling the received
+// events, the senders of the integration and the timers of the tasks
+type taskTracker struct {
+	clock   clock.Clock
+	timeout time.Duration
+	onStuck StuckTaskFn
+	mtx     sync.Mutex
+	tasks   map[taskKey]*trackedTask
+}
+
+func newTaskTracker(clock clock.Clock, timeout time.Duration, onStuck StuckTaskFn) *taskTracker {
+	return &taskTracker{clock: clock, timeout: timeout, onStuck: onStuck, tasks: map[taskKey]*trackedTask{}}
+}
+
+// triggered starts tracking the task of a .triggered event. Other events and tasks already being tracked are ignored
+func (t *taskTracker) triggered(event models.KeptnContextExtendedCE) {
+	if t == nil || event.Type == nil || !keptnv2.IsTriggeredEventType(*event.Type) {
+		return
+	}
+	key := taskKey{keptnContext: event.Shkeptncontext, triggeredID: event.ID}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if _, ok := t.tasks[key]; ok {
+		return
+	}
+	tracked := &trackedTask{task: OutstandingTask{
+		KeptnContext: event.Shkeptncontext,
+		TriggeredID:  event.ID,
+		Type:         *event.Type,
+		TriggeredAt:  t.clock.Now(),
+	}}
+	if t.onStuck != nil {
+		tracked.timer = t.clock.AfterFunc(t.timeout, func() { t.onStuck(tracked.task) })
+	}
+	t.tasks[key] = tracked
+}
+
+// finished stops tracking the task completed by the given .finished event. Other events are ignored
+func (t *taskTracker) finished(event models.KeptnContextExtendedCE) {
+	if t == nil || event.Type == nil || !keptnv2.IsFinishedEventType(*event.Type) {
+		return
+	}
+	key := taskKey{keptnContext: event.Shkeptncontext, triggeredID: event.Triggeredid}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if tracked, ok := t.tasks[key]; ok {
+		if tracked.timer != nil {
+			tracked.timer.Stop()
+		}
+		delete(t.tasks, key)
+	}
+}
+
+func (t *taskTracker) outstanding() []OutstandingTask {
+	if t == nil {
+		return nil
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	tasks := make([]OutstandingTask, 0, len(t.tasks))
+	for _, tracked := range t.tasks {
+		tasks = append(tasks, tracked.task)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].TriggeredAt.Before(tasks[j].TriggeredAt) })
+	return tasks
+}
+
+// reset forgets all outstanding tasks
+func (t *taskTracker) reset() {
+	if t == nil {
+		return
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	for key, tracked := range t.tasks {
+		if tracked.timer != nil {
+			tracked.timer.Stop()
+		}
+		delete(t.tasks, key)
+	}
+}
+
+// trackingSender wraps the sender to stop tracking the tasks completed by the sent .finished events
+func (t *taskTracker) trackingSender(sender types.EventSender) types.EventSender {
+	if t == nil {
+		return sender
+	}
+	return func(ce models.KeptnContextExtendedCE) error {
+		if err := sender(ce); err != nil {
+			return err
+		}
+		t.finished(ce)
+		return nil
+	}
+}
@@ -0,0 +1,84 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlPlaneReportsStuckTask(t *testing.T) {
+	sources := newFakeSources()
+	mockClock := clock.NewMock()
+	stuck := make(chan OutstandingTask, 1)
+	controlPlane := New(sources.ssm, sources.esm, nil, WithClock(mockClock), WithTaskTracking(time.Minute, func(task OutstandingTask) {
+		stuck <- task
+	}))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		// the task is never finished
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error { return nil },
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	update := eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	update.KeptnEvent.Shkeptncontext = "keptn-context"
+	eventChan <- update
+
+	require.Eventually(t, func() bool { return len(controlPlane.OutstandingTasks()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	want := OutstandingTask{KeptnContext: "keptn-context", TriggeredID: "event-id", Type: "sh.keptn.event.echo.triggered", TriggeredAt: mockClock.Now()}
+	require.Equal(t, []OutstandingTask{want}, controlPlane.OutstandingTasks())
+
+	mockClock.Add(59 * time.Second)
+	require.Empty(t, stuck)
+	mockClock.Add(time.Second)
+	select {
+	case task := <-stuck:
+		require.Equal(t, want, task)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "stuck task was not reported")
+	}
+	require.Len(t, controlPlane.OutstandingTasks(), 1)
+}
+
+func TestControlPlaneStopsTrackingFinishedTask(t *testing.T) {
+	sources := newFakeSources()
+	mockClock := clock.NewMock()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithClock(mockClock), WithTaskTracking(time.Minute, func(task OutstandingTask) {
+		require.FailNow(t, "unexpected stuck task", task.TriggeredID)
+	}))
+	integration := ExampleIntegration{
+		RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+		OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+			sender := ctx.Value(types.EventSenderKey).(types.EventSender)
+			return sender(models.KeptnContextExtendedCE{
+				ID:             "finished-id",
+				Type:           strutils.Stringp("sh.keptn.event.echo.finished"),
+				Shkeptncontext: ce.Shkeptncontext,
+				Triggeredid:    ce.ID,
+			})
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	eventChan <- eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+
+	require.Eventually(t, func() bool { return len(sources.sentEvents()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.Empty(t, controlPlane.OutstandingTasks())
+	mockClock.Add(time.Minute)
+}
+
+func TestControlPlaneWithoutTaskTracking(t *testing.T) {
+	require.Nil(t, New(nil, nil, nil).OutstandingTasks())
+}
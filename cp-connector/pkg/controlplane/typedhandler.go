@@ -0,0 +1,38 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+// ErrEventDataDecode is returned by typed handlers if the data of the received event
+// cannot be decoded into the type expected by the handler
+var ErrEventDataDecode = errors.New("could not decode event data")
+
+// TypedHandlerFunc is a function that handles a single Keptn event together with its decoded event data
+type TypedHandlerFunc[T any] func(context.Context, models.KeptnContextExtendedCE, T) error
+
+// Typed converts a TypedHandlerFunc into a HandlerFunc that decodes the event data into a value of type T
+// before calling the handler. If decoding fails, the handler is not called and an error wrapping
+// ErrEventDataDecode is returned
+func Typed[T any](handler TypedHandlerFunc[T]) HandlerFunc {
+	return func(ctx context.Context, event models.KeptnContextExtendedCE) error {
+		var data T
+		if err := keptnv2.EventDataAs(event, &data); err != nil {
+			return fmt.Errorf("unable to handle event %s of type %s: %v: %w", event.ID, eventType(event), err, ErrEventDataDecode)
+		}
+		return handler(ctx, event, data)
+	}
+}
+
+// HandleTyped registers a handler for the given subject or subject pattern at the Mux, whose event data
+// is decoded into a value of type T before the handler is called, e.g.
+//
+//	HandleTyped(mux, "sh.keptn.event.deployment.triggered", func(ctx context.Context, event models.KeptnContextExtendedCE, data keptnv2.DeploymentTriggeredEventData) error {...})
+func HandleTyped[T any](m *Mux, subject string, handler TypedHandlerFunc[T]) {
+	m.Handle(subject, Typed(handler))
+}
@@ -0,0 +1,52 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleTypedDecodesEventData(t *testing.T) {
+	var received keptnv2.DeploymentTriggeredEventData
+	mux := NewMux(types.RegistrationData{})
+	HandleTyped(mux, "sh.keptn.event.deployment.triggered", func(ctx context.Context, event models.KeptnContextExtendedCE, data keptnv2.DeploymentTriggeredEventData) error {
+		received = data
+		return nil
+	})
+
+	event := models.KeptnContextExtendedCE{
+		ID:   "event-id",
+		Type: strutils.Stringp("sh.keptn.event.deployment.triggered"),
+		Data: keptnv2.DeploymentTriggeredEventData{
+			EventData:  keptnv2.EventData{Project: "my-project", Stage: "dev", Service: "svc"},
+			Deployment: keptnv2.DeploymentTriggeredData{DeploymentStrategy: "direct"},
+		},
+	}
+	require.Nil(t, mux.OnEvent(context.TODO(), event))
+	require.Equal(t, "my-project", received.Project)
+	require.Equal(t, "dev", received.Stage)
+	require.Equal(t, "svc", received.Service)
+	require.Equal(t, "direct", received.Deployment.DeploymentStrategy)
+}
+
+func TestHandleTypedDecodeFailure(t *testing.T) {
+	mux := NewMux(types.RegistrationData{})
+	HandleTyped(mux, "sh.keptn.event.deployment.triggered", func(ctx context.Context, event models.KeptnContextExtendedCE, data keptnv2.DeploymentTriggeredEventData) error {
+		require.FailNow(t, "unexpected call of typed handler")
+		return nil
+	})
+
+	event := models.KeptnContextExtendedCE{
+		ID:   "event-id",
+		Type: strutils.Stringp("sh.keptn.event.deployment.triggered"),
+		Data: map[string]interface{}{"project": 42},
+	}
+	err := mux.OnEvent(context.TODO(), event)
+	require.ErrorIs(t, err, ErrEventDataDecode)
+	require.NotErrorIs(t, err, ErrEventHandleFatal)
+}
@@ -0,0 +1,92 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+// errIntegrationNotReady is the handling result of events that could not be buffered while the integration
+// was warming up, or whose buffer was abandoned before the integration became ready
+var errIntegrationNotReady = errors.New("integration is not ready yet")
+
+// WarmupIntegration can be implemented by integrations that need to build up internal state, e.g. caches,
+// before they can handle events correctly. Warmups are only used if enabled via WithWarmup
+type WarmupIntegration interface {
+	Integration
+	// Warmup is called once the integration was registered. The integration calls signalReady as soon as
+	// it is able to handle events. The given context is cancelled once the registration stopped
+	Warmup(ctx context.Context, signalReady func())
+}
+
+// WithWarmup configures the ControlPlane to hold back events from integrations implementing WarmupIntegration
+// until they signaled to be ready. Up to bufferSize events are buffered and forwarded in the order they were
+// received once the integration is ready. Further events are redelivered, if supported by the event source
+func WithWarmup(bufferSize int) func(plane *ControlPlane) {
+	return func(ns *ControlPlane) {
+		ns.warmupBufferSize = bufferSize
+	}
+}
+
+// warmup buffers the events received before the integration signaled to be ready.
+// Apart from signalReady, it is only accessed by the goroutine handling the received events
+type warmup struct {
+	size     int
+	readyCh  chan struct{}
+	once     sync.Once
+	buffered []types.EventUpdate
+	released bool
+}
+
+func newWarmup(size int) *warmup {
+	return &warmup{size: size, readyCh: make(chan struct{})}
+}
+
+func (w *warmup) signalReady() {
+	w.once.Do(func() { close(w.readyCh) })
+}
+
+// ready returns a channel that is closed once the integration signaled to be ready.
+// After the buffered events were released, the returned channel is nil
+func (w *warmup) ready() <-chan struct{} {
+	if w == nil || w.released {
+		return nil
+	}
+	return w.readyCh
+}
+
+// release returns the buffered events and ends the warmup
+func (w *warmup) release() []types.EventUpdate {
+	buffered := w.buffered
+	w.buffered = nil
+	w.released = true
+	return buffered
+}
+
+// bufferDuringWarmup buffers the event if the integration is still warming up and reports whether the event was
+// held back. Events not fitting into the buffer are redelivered
+func (cp *ControlPlane) bufferDuringWarmup(ctx context.Context, event types.EventUpdate) bool {
+	w := cp.warmup
+	if w == nil || w.released {
+		return false
+	}
+	if len(w.buffered) >= w.size {
+		cp.logger.Warnf("Warmup buffer is full, requesting redelivery of event %s", event.KeptnEvent.ID)
+		cp.acknowledge(ctx, event, errIntegrationNotReady)
+		return true
+	}
+	w.buffered = append(w.buffered, event)
+	return true
+}
+
+// abandonWarmup requests the redelivery of the events buffered while the integration did not become ready
+func (cp *ControlPlane) abandonWarmup(ctx context.Context) {
+	if cp.warmup == nil || cp.warmup.released {
+		return
+	}
+	for _, event := range cp.warmup.release() {
+		cp.acknowledge(ctx, event, errIntegrationNotReady)
+	}
+}
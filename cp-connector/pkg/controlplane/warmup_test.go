@@ -0,0 +1,94 @@
+package controlplane
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+type warmupIntegration struct {
+	ExampleIntegration
+	signalReady chan func()
+}
+
+func (w warmupIntegration) Warmup(ctx context.Context, signalReady func()) {
+	w.signalReady <- signalReady
+}
+
+func TestControlPlaneWarmup(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithWarmup(3))
+	handled := make(chan string, 10)
+	integration := warmupIntegration{
+		ExampleIntegration: ExampleIntegration{
+			RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+			OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+				handled <- ce.ID
+				return nil
+			},
+		},
+		signalReady: make(chan func(), 1),
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go func() { _ = controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	signalReady := <-integration.signalReady
+
+	for i := 0; i < 3; i++ {
+		eventChan <- eventUpdate(fmt.Sprintf("event-%d", i), "sh.keptn.event.echo.triggered")
+	}
+	// the buffer is full, the event is redelivered later on
+	acknowledger := &fakeAcknowledger{}
+	overflow := eventUpdate("overflow", "sh.keptn.event.echo.triggered")
+	overflow.Acknowledger = acknowledger
+	eventChan <- overflow
+	require.Eventually(t, func() bool {
+		_, nacked := acknowledger.outcomes()
+		return len(nacked) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Empty(t, handled)
+
+	signalReady()
+	eventChan <- eventUpdate("after-ready", "sh.keptn.event.echo.triggered")
+	var order []string
+	for i := 0; i < 4; i++ {
+		order = append(order, <-handled)
+	}
+	require.Equal(t, []string{"event-0", "event-1", "event-2", "after-ready"}, order)
+}
+
+func TestControlPlaneWarmupAbandoned(t *testing.T) {
+	sources := newFakeSources()
+	controlPlane := New(sources.ssm, sources.esm, nil, WithWarmup(3))
+	integration := warmupIntegration{
+		ExampleIntegration: ExampleIntegration{
+			RegistrationDataFn: func() types.RegistrationData { return types.RegistrationData{} },
+			OnEventFn: func(ctx context.Context, ce models.KeptnContextExtendedCE) error {
+				require.FailNow(t, "unexpected call of OnEvent")
+				return nil
+			},
+		},
+		signalReady: make(chan func(), 1),
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	registerErr := make(chan error)
+	go func() { registerErr <- controlPlane.Register(ctx, integration) }()
+	eventChan, subsChan := sources.channels(t)
+	subsChan <- []models.EventSubscription{{ID: "sub-1", Event: "sh.keptn.event.echo.triggered"}}
+	acknowledger := &fakeAcknowledger{}
+	update := eventUpdate("event-id", "sh.keptn.event.echo.triggered")
+	update.Acknowledger = acknowledger
+	eventChan <- update
+
+	cancel()
+	require.NoError(t, <-registerErr)
+	_, nacked := acknowledger.outcomes()
+	require.Len(t, nacked, 1)
+}
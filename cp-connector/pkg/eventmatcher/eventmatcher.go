@@ -14,16 +14,42 @@ type EventMatcher struct {
 	Project string
 	Stage   string
 	Service string
+	// Labels are the labels an event must carry in its data to be matched
+	Labels map[string]string
+}
+
+// WithLabelSelector configures the EventMatcher to only match events whose labels
+// contain all the given labels with the same values
+func WithLabelSelector(labels map[string]string) func(matcher *EventMatcher) {
+	return func(matcher *EventMatcher) {
+		matcher.Labels = labels
+	}
 }
 
 // New creates a new EventMatcher that is configured
 // with information about project, stage and service filter contained in an event subscription
-func New(subscription models.EventSubscription) *EventMatcher {
-	return &EventMatcher{
-		Project: strings.Join(subscription.Filter.Projects, ","),
-		Stage:   strings.Join(subscription.Filter.Stages, ","),
-		Service: strings.Join(subscription.Filter.Services, ","),
+func New(subscription models.EventSubscription, options ...func(matcher *EventMatcher)) *EventMatcher {
+	return NewEventMatcher(subscription.Filter.Projects, subscription.Filter.Stages, subscription.Filter.Services, options...)
+}
+
+// NewEventMatcherFromSubscription creates a new EventMatcher using the filter of the given event subscription.
+// It is equivalent to New and can be used to test the filters of subscriptions
+func NewEventMatcherFromSubscription(subscription models.EventSubscription, options ...func(matcher *EventMatcher)) *EventMatcher {
+	return New(subscription, options...)
+}
+
+// NewEventMatcher creates a new EventMatcher matching events of any of the given projects, stages and services.
+// An empty list matches events of every project, stage or service respectively
+func NewEventMatcher(projects []string, stages []string, services []string, options ...func(matcher *EventMatcher)) *EventMatcher {
+	matcher := &EventMatcher{
+		Project: strings.Join(projects, ","),
+		Stage:   strings.Join(stages, ","),
+		Service: strings.Join(services, ","),
 	}
+	for _, o := range options {
+		o(matcher)
+	}
+	return matcher
 }
 
 // Matches checks whether a Keptn event matches the information of the currently configured
@@ -39,5 +65,10 @@ func (ef EventMatcher) Matches(e models.KeptnContextExtendedCE) bool {
 		ef.Service != "" && !sliceutils.ContainsStr(strings.Split(ef.Service, ","), generalEventData.Service) {
 		return false
 	}
+	for key, value := range ef.Labels {
+		if label, ok := generalEventData.Labels[key]; !ok || label != value {
+			return false
+		}
+	}
 	return true
 }
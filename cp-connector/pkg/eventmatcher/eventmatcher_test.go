@@ -264,3 +264,103 @@ func TestEventMatcher_Matches(t *testing.T) {
 		})
 	}
 }
+
+func TestEventMatcher_MatchesLabels(t *testing.T) {
+	event := models.KeptnContextExtendedCE{Data: v0_2_0.EventData{
+		Project: "pr1",
+		Labels:  map[string]string{"team": "a", "tier": "backend"},
+	}}
+	tests := []struct {
+		name     string
+		selector map[string]string
+		event    models.KeptnContextExtendedCE
+		want     bool
+	}{
+		{
+			name:     "empty selector",
+			selector: map[string]string{},
+			event:    event,
+			want:     true,
+		},
+		{
+			name:     "nil selector without labels",
+			selector: nil,
+			event:    models.KeptnContextExtendedCE{Data: v0_2_0.EventData{Project: "pr1"}},
+			want:     true,
+		},
+		{
+			name:     "full match",
+			selector: map[string]string{"team": "a", "tier": "backend"},
+			event:    event,
+			want:     true,
+		},
+		{
+			name:     "subset of labels",
+			selector: map[string]string{"team": "a"},
+			event:    event,
+			want:     true,
+		},
+		{
+			name:     "partial match",
+			selector: map[string]string{"team": "a", "tier": "frontend"},
+			event:    event,
+			want:     false,
+		},
+		{
+			name:     "missing label",
+			selector: map[string]string{"team": "a", "region": "eu"},
+			event:    event,
+			want:     false,
+		},
+		{
+			name:     "event without labels",
+			selector: map[string]string{"team": "a"},
+			event:    models.KeptnContextExtendedCE{Data: v0_2_0.EventData{Project: "pr1"}},
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher := New(models.EventSubscription{Filter: models.EventSubscriptionFilter{Projects: []string{"pr1"}}}, WithLabelSelector(tt.selector))
+			require.Equal(t, tt.want, matcher.Matches(tt.event))
+		})
+	}
+}
+
+func TestNewEventMatcher(t *testing.T) {
+	event := models.KeptnContextExtendedCE{Data: v0_2_0.EventData{
+		Project: "pr1",
+		Stage:   "st1",
+		Service: "sv1",
+	}}
+	tests := []struct {
+		name     string
+		projects []string
+		stages   []string
+		services []string
+		want     bool
+	}{
+		{name: "no filter", want: true},
+		{name: "matching project", projects: []string{"pr1"}, want: true},
+		{name: "other project", projects: []string{"pr2"}, want: false},
+		{name: "one of multiple projects", projects: []string{"pr2", "pr1"}, want: true},
+		{name: "matching project and stage", projects: []string{"pr1"}, stages: []string{"st1"}, want: true},
+		{name: "matching project and other stage", projects: []string{"pr1"}, stages: []string{"st2"}, want: false},
+		{name: "matching stage and service", stages: []string{"st1"}, services: []string{"sv1"}, want: true},
+		{name: "matching stage and other service", stages: []string{"st1"}, services: []string{"sv2"}, want: false},
+		{name: "all fields matching", projects: []string{"pr1"}, stages: []string{"st1", "st2"}, services: []string{"sv1"}, want: true},
+		{name: "all fields but service matching", projects: []string{"pr1"}, stages: []string{"st1"}, services: []string{"sv2", "sv3"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, NewEventMatcher(tt.projects, tt.stages, tt.services).Matches(event))
+
+			subscription := models.EventSubscription{Filter: models.EventSubscriptionFilter{
+				Projects: tt.projects,
+				Stages:   tt.stages,
+				Services: tt.services,
+			}}
+			require.Equal(t, tt.want, NewEventMatcherFromSubscription(subscription).Matches(event))
+		})
+	}
+}
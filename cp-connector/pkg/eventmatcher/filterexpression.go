@@ -0,0 +1,341 @@
+package eventmatcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+)
+
+// FilterExpression is a boolean expression evaluated against the fields of an event, e.g.
+//
+//	data.service == "checkout" && type endswith ".triggered"
+//
+// Fields are referenced by their JSON path within the event, e.g. "type", "source" or "data.project". Fields missing
+// in the event evaluate to the empty string. Fields and string literals can be compared using the operators "==",
+// "!=", "startswith", "endswith" and "contains", where numbers and booleans are compared by their string representation.
+// Comparisons can be combined using "&&", "||", "!" and parentheses
+type FilterExpression struct {
+	expression string
+	root       exprNode
+}
+
+// ParseFilterExpression parses the given filter expression. An error is returned if the syntax of the
+// expression is invalid
+func ParseFilterExpression(expression string) (*FilterExpression, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter expression %q: %w", expression, err)
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %s at position %d", p.tokens[p.pos].text, p.tokens[p.pos].offset)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter expression %q: %w", expression, err)
+	}
+	return &FilterExpression{expression: expression, root: root}, nil
+}
+
+// Matches evaluates the filter expression against the given event
+func (e *FilterExpression) Matches(event models.KeptnContextExtendedCE) bool {
+	serialized, err := json.Marshal(event)
+	if err != nil {
+		return false
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(serialized, &fields); err != nil {
+		return false
+	}
+	return e.root.eval(fields)
+}
+
+func (e *FilterExpression) String() string {
+	return e.expression
+}
+
+type tokenKind int
+
+const (
+	tokenString tokenKind = iota
+	tokenField
+	tokenOperator
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpenParen
+	tokenCloseParen
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+var keywordOperators = map[string]struct{}{"startswith": {}, "endswith": {}, "contains": {}}
+
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpenParen, text: "(", offset: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenCloseParen, text: ")", offset: i})
+			i++
+		case strings.HasPrefix(expression[i:], "&&"):
+			tokens = append(tokens, token{kind: tokenAnd, text: "&&", offset: i})
+			i += 2
+		case strings.HasPrefix(expression[i:], "||"):
+			tokens = append(tokens, token{kind: tokenOr, text: "||", offset: i})
+			i += 2
+		case strings.HasPrefix(expression[i:], "=="), strings.HasPrefix(expression[i:], "!="):
+			tokens = append(tokens, token{kind: tokenOperator, text: expression[i : i+2], offset: i})
+			i += 2
+		case c == '!':
+			tokens = append(tokens, token{kind: tokenNot, text: "!", offset: i})
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expression) && expression[end] != '"' {
+				if expression[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expression) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			value, err := strconv.Unquote(expression[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %w", i, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: value, offset: i})
+			i = end + 1
+		case isFieldChar(rune(c)):
+			end := i
+			for end < len(expression) && isFieldChar(rune(expression[end])) {
+				end++
+			}
+			word := expression[i:end]
+			kind := tokenField
+			if _, ok := keywordOperators[word]; ok {
+				kind = tokenOperator
+			} else if isLiteral(word) {
+				kind = tokenString
+			}
+			tokens = append(tokens, token{kind: kind, text: word, offset: i})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	return tokens, nil
+}
+
+// isLiteral checks whether the unquoted word is a number or boolean, which is compared like a string
+func isLiteral(word string) bool {
+	if word == "true" || word == "false" {
+		return true
+	}
+	_, err := strconv.ParseFloat(word, 64)
+	return err == nil
+}
+
+func isFieldChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+}
+
+// exprParser is a recursive descent parser for filter expressions. "&&" binds stronger than "||"
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *exprParser) next() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, true
+}
+
+func (p *exprParser) peek(kind tokenKind) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == kind
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek(tokenOr) {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek(tokenAnd) {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.peek(tokenNot) {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	if p.peek(tokenOpenParen) {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := p.next(); !ok || t.kind != tokenCloseParen {
+			return nil, p.unexpected(t, ok, "closing parenthesis")
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	t, ok := p.next()
+	if !ok || t.kind != tokenOperator {
+		return nil, p.unexpected(t, ok, "comparison operator")
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return comparisonNode{left: left, operator: t.text, right: right}, nil
+}
+
+func (p *exprParser) parseOperand() (operand, error) {
+	t, ok := p.next()
+	if !ok || (t.kind != tokenField && t.kind != tokenString) {
+		return operand{}, p.unexpected(t, ok, "field or string")
+	}
+	if t.kind == tokenString {
+		return operand{literal: t.text}, nil
+	}
+	return operand{path: strings.Split(t.text, ".")}, nil
+}
+
+func (p *exprParser) unexpected(t token, ok bool, expected string) error {
+	if !ok {
+		return fmt.Errorf("expected %s at end of expression", expected)
+	}
+	return fmt.Errorf("expected %s at position %d, got %s", expected, t.offset, t.text)
+}
+
+type exprNode interface {
+	eval(fields map[string]interface{}) bool
+}
+
+type andNode struct{ left, right exprNode }
+
+func (n andNode) eval(fields map[string]interface{}) bool {
+	return n.left.eval(fields) && n.right.eval(fields)
+}
+
+type orNode struct{ left, right exprNode }
+
+func (n orNode) eval(fields map[string]interface{}) bool {
+	return n.left.eval(fields) || n.right.eval(fields)
+}
+
+type notNode struct{ operand exprNode }
+
+func (n notNode) eval(fields map[string]interface{}) bool {
+	return !n.operand.eval(fields)
+}
+
+type comparisonNode struct {
+	left     operand
+	operator string
+	right    operand
+}
+
+func (n comparisonNode) eval(fields map[string]interface{}) bool {
+	left, right := n.left.value(fields), n.right.value(fields)
+	switch n.operator {
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	case "startswith":
+		return strings.HasPrefix(left, right)
+	case "endswith":
+		return strings.HasSuffix(left, right)
+	case "contains":
+		return strings.Contains(left, right)
+	default:
+		return false
+	}
+}
+
+// operand is either a string literal or the path of a field
+type operand struct {
+	literal string
+	path    []string
+}
+
+func (o operand) value(fields map[string]interface{}) string {
+	if o.path == nil {
+		return o.literal
+	}
+	var current interface{} = fields
+	for _, key := range o.path {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		current = object[key]
+	}
+	switch v := current.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		serialized, _ := json.Marshal(v)
+		return string(serialized)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,63 @@
+package eventmatcher
+
+import (
+	"testing"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterExpressionMatches(t *testing.T) {
+	event := models.KeptnContextExtendedCE{
+		ID:     "event-id",
+		Type:   strutils.Stringp("sh.keptn.event.deployment.triggered"),
+		Source: strutils.Stringp("shipyard-controller"),
+		Data: map[string]interface{}{
+			"project": "sockshop",
+			"service": "checkout",
+			"retries": 3,
+			"labels":  map[string]string{"team": "payments"},
+		},
+	}
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{expression: `data.service == "checkout" && type endswith ".triggered"`, want: true},
+		{expression: `data.service == "carts" && type endswith ".triggered"`, want: false},
+		{expression: `data.service == "carts" || source startswith "shipyard"`, want: true},
+		{expression: `!(type contains ".deployment.")`, want: false},
+		{expression: `data.labels.team != "payments"`, want: false},
+		{expression: `data.retries == 3`, want: true},
+		{expression: `data.missing == ""`, want: true},
+		{expression: `data.project == "sockshop" && (data.service == "carts" || data.service == "checkout")`, want: true},
+		{expression: `data.service == "carts" && data.project == "sockshop" || id == "event-id"`, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			expression, err := ParseFilterExpression(tt.expression)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, expression.Matches(event))
+		})
+	}
+}
+
+func TestParseFilterExpressionInvalidSyntax(t *testing.T) {
+	for _, expression := range []string{
+		``,
+		`data.service ==`,
+		`data.service "checkout"`,
+		`(data.service == "checkout"`,
+		`data.service == "checkout")`,
+		`data.service == "checkout`,
+		`data.service == "checkout" &&`,
+		`data.service = "checkout"`,
+		`data.service matches "checkout"`,
+	} {
+		t.Run(expression, func(t *testing.T) {
+			_, err := ParseFilterExpression(expression)
+			require.ErrorContains(t, err, "invalid filter expression")
+		})
+	}
+}
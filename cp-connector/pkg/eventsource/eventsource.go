@@ -7,7 +7,8 @@ import (
 	"github.com/keptn/keptn/cp-connector/pkg/types"
 	"reflect"
 	"sort"
-	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/keptn/go-utils/pkg/api/models"
 	"github.com/keptn/keptn/cp-connector/pkg/logger"
@@ -18,8 +19,13 @@ import (
 // EventSource is anything that can be used
 // to get events from the Keptn Control Plane
 type EventSource interface {
-	// Start triggers the execution of the EventSource
-	Start(context.Context, types.RegistrationData, chan types.EventUpdate, *sync.WaitGroup) error
+	// Start triggers the execution of the EventSource.
+	// Once the given context is cancelled, the EventSource stops sending on the given channel and
+	// closes the returned channel as soon as all of its goroutines have stopped.
+	// Transitions of the connection to the message broker are sent on the given connection state channel, if it is not nil.
+	// Errors receiving events, e.g. malformed messages, are sent on the given error channel, if it is not nil.
+	// Event sources of request/response transports set the Responder of the sent updates to receive the handling results
+	Start(context.Context, types.RegistrationData, chan types.EventUpdate, chan types.ConnectionState, chan error) (<-chan struct{}, error)
 	// OnSubscriptionUpdate can be called to tell the EventSource that
 	// the current subscriptions have been changed
 	OnSubscriptionUpdate([]string)
@@ -38,8 +44,17 @@ type NATSEventSource struct {
 	eventProcessFn  natseventsource.ProcessEventFn
 	queueGroup      string
 	logger          logger.Logger
+	prefetch        int
+	// rawMessageObserver observes the received messages before they are decoded
+	rawMessageObserver RawMessageObserverFn
+	// activeSubscriptions is the number of broker subscriptions currently held
+	activeSubscriptions int32
+	// activeRoutines is the number of goroutines currently started by the event source
+	activeRoutines int32
 }
 
+var _ types.ResourceReporter = (*NATSEventSource)(nil)
+
 // New creates a new NATSEventSource
 func New(natsConnector natseventsource.NATS, opts ...func(source *NATSEventSource)) *NATSEventSource {
 	e := &NATSEventSource{
@@ -61,33 +76,79 @@ func WithLogger(logger logger.Logger) func(*NATSEventSource) {
 	}
 }
 
-func (n *NATSEventSource) Start(ctx context.Context, registrationData types.RegistrationData, eventChannel chan types.EventUpdate, wg *sync.WaitGroup) error {
+func (n *NATSEventSource) Start(ctx context.Context, registrationData types.RegistrationData, eventChannel chan types.EventUpdate, connectionStates chan types.ConnectionState, receiveErrors chan error) (<-chan struct{}, error) {
 	n.queueGroup = registrationData.Name
+	var prefetchSlots chan struct{}
+	if n.prefetch > 0 {
+		prefetchSlots = make(chan struct{}, n.prefetch)
+	}
 	n.eventProcessFn = func(event *nats.Msg) error {
+		n.observeRawMessage(event)
 		keptnEvent := models.KeptnContextExtendedCE{}
 		if err := json.Unmarshal(event.Data, &keptnEvent); err != nil {
-			return fmt.Errorf("could not unmarshal message: %w", err)
+			err = fmt.Errorf("could not unmarshal message received on subject %s: %w", event.Subject, err)
+			if receiveErrors != nil {
+				select {
+				case receiveErrors <- err:
+				case <-ctx.Done():
+				}
+			}
+			return err
 		}
-		eventChannel <- types.EventUpdate{
+		update := types.EventUpdate{
 			KeptnEvent: keptnEvent,
-			MetaData:   types.EventUpdateMetaData{event.Sub.Subject},
+			MetaData:   types.EventUpdateMetaData{Subject: event.Sub.Subject, Timestamp: time.Now().UTC(), Size: len(event.Data)},
+		}
+		if prefetchSlots != nil {
+			// wait until one of the previously passed events was acknowledged
+			select {
+			case prefetchSlots <- struct{}{}:
+			case <-ctx.Done():
+				return fmt.Errorf("dropping event %s: event source is shutting down", keptnEvent.ID)
+			}
+			update.Acknowledger = &prefetchSlot{slots: prefetchSlots}
+		}
+		select {
+		case eventChannel <- update:
+		case <-ctx.Done():
+			if slot, ok := update.Acknowledger.(*prefetchSlot); ok {
+				slot.Release()
+			}
+			return fmt.Errorf("dropping event %s: event source is shutting down", keptnEvent.ID)
 		}
 		return nil
 	}
 
+	if connectionStates != nil {
+		n.connector.OnConnectionStateChange(func(state types.ConnectionState) {
+			select {
+			case connectionStates <- state:
+			case <-ctx.Done():
+			}
+		})
+	}
+
 	if err := n.connector.QueueSubscribeMultiple(n.currentSubjects, n.queueGroup, n.eventProcessFn); err != nil {
-		return fmt.Errorf("could not start NATS event source: %w", err)
+		return nil, fmt.Errorf("could not start NATS event source: %w", err)
 	}
+	atomic.StoreInt32(&n.activeSubscriptions, int32(len(n.currentSubjects)))
+	done := make(chan struct{})
+	atomic.AddInt32(&n.activeRoutines, 1)
 	go func() {
-		defer wg.Done()
+		defer close(done)
+		defer atomic.AddInt32(&n.activeRoutines, -1)
 		<-ctx.Done()
+		if connectionStates != nil {
+			n.connector.OnConnectionStateChange(nil)
+		}
 		if err := n.connector.UnsubscribeAll(); err != nil {
 			n.logger.Errorf("Unable to unsubscribe from NATS: %v", err)
 			return
 		}
+		atomic.StoreInt32(&n.activeSubscriptions, 0)
 		n.logger.Debug("Unsubscribed from NATS")
 	}()
-	return nil
+	return done, nil
 }
 
 func (n *NATSEventSource) OnSubscriptionUpdate(subjects []string) {
@@ -101,11 +162,13 @@ func (n *NATSEventSource) OnSubscriptionUpdate(subjects []string) {
 			n.logger.Errorf("Could not handle subscription update: %v", err)
 			return
 		}
+		atomic.StoreInt32(&n.activeSubscriptions, 0)
 		n.logger.Debugf("Subscribing to %d topics", len(s))
 		if err := n.connector.QueueSubscribeMultiple(s, n.queueGroup, n.eventProcessFn); err != nil {
 			n.logger.Errorf("Could not handle subscription update: %v", err)
 			return
 		}
+		atomic.StoreInt32(&n.activeSubscriptions, int32(len(s)))
 		n.currentSubjects = s
 		n.logger.Debugf("Subscription to %d topics successful", len(s))
 	}
@@ -115,6 +178,11 @@ func (n *NATSEventSource) Sender() types.EventSender {
 	return n.connector.Publish
 }
 
+// ActiveResources returns the number of NATS subscriptions and goroutines currently owned by the event source
+func (n *NATSEventSource) ActiveResources() int {
+	return int(atomic.LoadInt32(&n.activeSubscriptions) + atomic.LoadInt32(&n.activeRoutines))
+}
+
 func (n *NATSEventSource) Stop() error {
 	return n.connector.Disconnect()
 }
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"github.com/keptn/keptn/cp-connector/pkg/types"
-	"sync"
 	"testing"
 	"time"
 
@@ -13,6 +12,7 @@ import (
 	nats2 "github.com/keptn/keptn/cp-connector/pkg/nats"
 	"github.com/nats-io/nats.go"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
 )
 
 type NATSConnectorMock struct {
@@ -27,10 +27,19 @@ type NATSConnectorMock struct {
 	DisconnectCalls             int
 	UnsubscribeAllFn            func() error
 	UnsubscribeAllCalls         int
+	OnConnectionStateChangeFn   func(nats2.ConnectionStateFn)
 	QueueGroup                  string
 	ProcessEventFn              nats2.ProcessEventFn
 }
 
+func (ncm *NATSConnectorMock) OnConnectionStateChange(fn nats2.ConnectionStateFn) {
+	if ncm.OnConnectionStateChangeFn != nil {
+		ncm.OnConnectionStateChangeFn(fn)
+		return
+	}
+	panic("implement me")
+}
+
 func (ncm *NATSConnectorMock) Subscribe(subject string, fn nats2.ProcessEventFn) error {
 	if ncm.SubscribeFn != nil {
 		return ncm.SubscribeFn(subject, fn)
@@ -93,10 +102,8 @@ func TestEventSourceForwardsEventToChannel(t *testing.T) {
 	}
 	eventChannel := make(chan types.EventUpdate)
 	eventSource := New(natsConnectorMock)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	eventSource.Start(context.TODO(), types.RegistrationData{}, eventChannel, wg)
+	_, _ = eventSource.Start(context.TODO(), types.RegistrationData{}, eventChannel, nil, nil)
 	eventSource.OnSubscriptionUpdate([]string{"a"})
 	event := models.KeptnContextExtendedCE{ID: "id"}
 	jsonEvent, _ := event.ToJSON()
@@ -106,44 +113,67 @@ func TestEventSourceForwardsEventToChannel(t *testing.T) {
 	require.Equal(t, eventFromChan.KeptnEvent, event)
 }
 
+func TestEventSourceReportsMalformedMessages(t *testing.T) {
+	natsConnectorMock := &NATSConnectorMock{
+		QueueSubscribeMultipleFn: func(subjects []string, queueGroup string, fn nats2.ProcessEventFn) error { return nil },
+		UnsubscribeAllFn:         func() error { return nil },
+	}
+	eventChannel := make(chan types.EventUpdate)
+	receiveErrors := make(chan error)
+	eventSource := New(natsConnectorMock)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	_, _ = eventSource.Start(ctx, types.RegistrationData{}, eventChannel, nil, receiveErrors)
+	eventSource.OnSubscriptionUpdate([]string{"a"})
+
+	processErr := make(chan error, 1)
+	go func() {
+		processErr <- natsConnectorMock.ProcessEventFn(&nats.Msg{Subject: "a", Data: []byte("{malformed"), Sub: &nats.Subscription{Subject: "a"}})
+	}()
+	require.ErrorContains(t, <-receiveErrors, "could not unmarshal message received on subject a")
+	require.Error(t, <-processErr)
+
+	// further messages are still forwarded
+	event := models.KeptnContextExtendedCE{ID: "id"}
+	jsonEvent, _ := event.ToJSON()
+	go natsConnectorMock.ProcessEventFn(&nats.Msg{Subject: "a", Data: jsonEvent, Sub: &nats.Subscription{Subject: "a"}})
+	require.Equal(t, "id", (<-eventChannel).KeptnEvent.ID)
+}
+
 func TestEventSourceCancelDisconnectsFromBroker(t *testing.T) {
 	natsConnectorMock := &NATSConnectorMock{
 		QueueSubscribeMultipleFn: func(subjects []string, queueGroup string, fn nats2.ProcessEventFn) error { return nil },
 		UnsubscribeAllFn:         func() error { return nil },
 	}
 	ctx, cancel := context.WithCancel(context.TODO())
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	New(natsConnectorMock).Start(ctx, types.RegistrationData{}, make(chan types.EventUpdate), wg)
+	_, _ = New(natsConnectorMock).Start(ctx, types.RegistrationData{}, make(chan types.EventUpdate), nil, nil)
 	cancel()
 	require.Eventually(t, func() bool { return natsConnectorMock.UnsubscribeAllCalls == 1 }, 2*time.Second, 100*time.Millisecond)
 }
 
-func TestEventSourceCallsWaitGroupDuringCancellation(t *testing.T) {
-	t.Run("WaitGroup called", func(t *testing.T) {
+func TestEventSourceClosesDoneChannelDuringCancellation(t *testing.T) {
+	t.Run("done channel closed", func(t *testing.T) {
 		natsConnectorMock := &NATSConnectorMock{
 			QueueSubscribeMultipleFn: func(subjects []string, queueGroup string, fn nats2.ProcessEventFn) error { return nil },
 			UnsubscribeAllFn:         func() error { return nil },
 		}
 		ctx, cancel := context.WithCancel(context.TODO())
-		wg := &sync.WaitGroup{}
-		wg.Add(1)
-		New(natsConnectorMock).Start(ctx, types.RegistrationData{}, make(chan types.EventUpdate), wg)
+		done, err := New(natsConnectorMock).Start(ctx, types.RegistrationData{}, make(chan types.EventUpdate), nil, nil)
+		require.NoError(t, err)
 		cancel()
-		wg.Wait()
+		<-done
 	})
-	t.Run("WaitGroup called - error in shutdown logic", func(t *testing.T) {
+	t.Run("done channel closed - error in shutdown logic", func(t *testing.T) {
 		natsConnectorMock := &NATSConnectorMock{
 			QueueSubscribeMultipleFn: func(subjects []string, queueGroup string, fn nats2.ProcessEventFn) error { return nil },
 			UnsubscribeAllFn:         func() error { return fmt.Errorf("ohoh") },
 		}
 		ctx, cancel := context.WithCancel(context.TODO())
-		wg := &sync.WaitGroup{}
-		wg.Add(1)
-		New(natsConnectorMock).Start(ctx, types.RegistrationData{}, make(chan types.EventUpdate), wg)
+		done, err := New(natsConnectorMock).Start(ctx, types.RegistrationData{}, make(chan types.EventUpdate), nil, nil)
+		require.NoError(t, err)
 		cancel()
-		wg.Wait()
+		<-done
 	})
 }
 
@@ -153,9 +183,7 @@ func TestEventSourceCancelDisconnectFromBrokerFails(t *testing.T) {
 		UnsubscribeAllFn:         func() error { return fmt.Errorf("error occured") },
 	}
 	ctx, cancel := context.WithCancel(context.TODO())
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	New(natsConnectorMock).Start(ctx, types.RegistrationData{}, make(chan types.EventUpdate), wg)
+	_, _ = New(natsConnectorMock).Start(ctx, types.RegistrationData{}, make(chan types.EventUpdate), nil, nil)
 	cancel()
 	require.Eventually(t, func() bool { return natsConnectorMock.UnsubscribeAllCalls == 1 }, 2*time.Second, 100*time.Millisecond)
 }
@@ -167,10 +195,8 @@ func TestEventSourceQueueSubscribeFails(t *testing.T) {
 		},
 	}
 	eventSource := New(natsConnectorMock)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	err := eventSource.Start(context.TODO(), types.RegistrationData{}, make(chan types.EventUpdate), wg)
+	_, err := eventSource.Start(context.TODO(), types.RegistrationData{}, make(chan types.EventUpdate), nil, nil)
 	require.Error(t, err)
 }
 
@@ -180,10 +206,8 @@ func TestEventSourceOnSubscriptionUpdate(t *testing.T) {
 		UnsubscribeAllFn:         func() error { return nil },
 	}
 	eventSource := New(natsConnectorMock)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	err := eventSource.Start(context.TODO(), types.RegistrationData{}, make(chan types.EventUpdate), wg)
+	_, err := eventSource.Start(context.TODO(), types.RegistrationData{}, make(chan types.EventUpdate), nil, nil)
 	require.NoError(t, err)
 	require.Equal(t, 1, natsConnectorMock.QueueSubscribeMultipleCalls)
 	eventSource.OnSubscriptionUpdate([]string{"a"})
@@ -201,7 +225,7 @@ func TestEventSourceOnSubscriptionupdateWithDuplicatedSubjects(t *testing.T) {
 		UnsubscribeAllFn: func() error { return nil },
 	}
 	eventSource := New(natsConnectorMock)
-	err := eventSource.Start(context.TODO(), types.RegistrationData{}, make(chan types.EventUpdate), &sync.WaitGroup{})
+	_, err := eventSource.Start(context.TODO(), types.RegistrationData{}, make(chan types.EventUpdate), nil, nil)
 	require.NoError(t, err)
 	require.Equal(t, 1, natsConnectorMock.QueueSubscribeMultipleCalls)
 	eventSource.OnSubscriptionUpdate([]string{"a", "a"})
@@ -216,10 +240,8 @@ func TestEventSourceOnSubscriptiOnUpdateUnsubscribeAllFails(t *testing.T) {
 		UnsubscribeAllFn:         func() error { return fmt.Errorf("error occured") },
 	}
 	eventSource := New(natsConnectorMock)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	err := eventSource.Start(context.TODO(), types.RegistrationData{}, make(chan types.EventUpdate), wg)
+	_, err := eventSource.Start(context.TODO(), types.RegistrationData{}, make(chan types.EventUpdate), nil, nil)
 	require.NoError(t, err)
 	require.Equal(t, 1, natsConnectorMock.QueueSubscribeMultipleCalls)
 	eventSource.OnSubscriptionUpdate([]string{"a"})
@@ -233,10 +255,8 @@ func TestEventSourceOnSubscriptionUpdateQueueSubscribeMultipleFails(t *testing.T
 		UnsubscribeAllFn:         func() error { return nil },
 	}
 	eventSource := New(natsConnectorMock)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	err := eventSource.Start(context.TODO(), types.RegistrationData{}, make(chan types.EventUpdate), wg)
+	_, err := eventSource.Start(context.TODO(), types.RegistrationData{}, make(chan types.EventUpdate), nil, nil)
 	require.NoError(t, err)
 	require.Equal(t, 1, natsConnectorMock.QueueSubscribeMultipleCalls)
 	natsConnectorMock.QueueSubscribeMultipleFn = func(subjects []string, queueGroup string, fn nats2.ProcessEventFn) error {
@@ -298,3 +318,211 @@ func TestEventSourceStopFails(t *testing.T) {
 	require.Error(t, err)
 	require.Equal(t, 1, natsConnectorMock.DisconnectCalls)
 }
+
+func TestEventSourceStopsWithoutGoroutineLeaks(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+	natsConnectorMock := &NATSConnectorMock{
+		QueueSubscribeMultipleFn: func(subjects []string, queueGroup string, fn nats2.ProcessEventFn) error { return nil },
+		UnsubscribeAllFn:         func() error { return nil },
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	done, err := New(natsConnectorMock).Start(ctx, types.RegistrationData{}, make(chan types.EventUpdate), nil, nil)
+	require.NoError(t, err)
+
+	// nobody is receiving from the event channel, so processing blocks until the source is cancelled
+	event := models.KeptnContextExtendedCE{ID: "id"}
+	jsonEvent, _ := event.ToJSON()
+	processed := make(chan error)
+	go func() {
+		processed <- natsConnectorMock.ProcessEventFn(&nats.Msg{Data: jsonEvent, Sub: &nats.Subscription{Subject: "subscription"}})
+	}()
+
+	cancel()
+	<-done
+	require.Error(t, <-processed)
+}
+
+func TestEventSourceActiveResourcesStayBoundedOnResubscribe(t *testing.T) {
+	natsConnectorMock := &NATSConnectorMock{
+		QueueSubscribeMultipleFn: func(subjects []string, queueGroup string, fn nats2.ProcessEventFn) error { return nil },
+		UnsubscribeAllFn:         func() error { return nil },
+	}
+	eventSource := New(natsConnectorMock)
+	require.Equal(t, 0, eventSource.ActiveResources())
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	done, err := eventSource.Start(ctx, types.RegistrationData{}, make(chan types.EventUpdate), nil, nil)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		eventSource.OnSubscriptionUpdate([]string{"a", fmt.Sprintf("b-%d", i)})
+		// two subscriptions plus the goroutine waiting for cancellation
+		require.Equal(t, 3, eventSource.ActiveResources())
+	}
+	cancel()
+	<-done
+	require.Equal(t, 0, eventSource.ActiveResources())
+}
+
+func TestEventSourceForwardsConnectionStates(t *testing.T) {
+	var stateFn nats2.ConnectionStateFn
+	natsConnectorMock := &NATSConnectorMock{
+		QueueSubscribeMultipleFn: func(subjects []string, queueGroup string, fn nats2.ProcessEventFn) error { return nil },
+		UnsubscribeAllFn:         func() error { return nil },
+		OnConnectionStateChangeFn: func(fn nats2.ConnectionStateFn) {
+			stateFn = fn
+		},
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	states := make(chan types.ConnectionState, 2)
+	done, err := New(natsConnectorMock).Start(ctx, types.RegistrationData{}, make(chan types.EventUpdate), states, nil)
+	require.NoError(t, err)
+	require.NotNil(t, stateFn)
+
+	stateFn(types.ConnectionStateDisconnected)
+	stateFn(types.ConnectionStateConnected)
+	require.Equal(t, types.ConnectionStateDisconnected, <-states)
+	require.Equal(t, types.ConnectionStateConnected, <-states)
+
+	cancel()
+	<-done
+	require.Nil(t, stateFn)
+}
+
+func TestMemoryEventSource(t *testing.T) {
+	source := NewMemoryEventSource()
+	ctx, cancel := context.WithCancel(context.TODO())
+	eventChannel := make(chan types.EventUpdate, 1)
+	done, err := source.Start(ctx, types.RegistrationData{}, eventChannel, nil, nil)
+	require.NoError(t, err)
+
+	// events are not pushed before the first subscription update was received
+	pushCtx, pushCancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer pushCancel()
+	require.Error(t, source.Push(pushCtx, models.KeptnContextExtendedCE{ID: "too-early"}))
+
+	source.OnSubscriptionUpdate([]string{"sh.keptn.event.echo.triggered"})
+	require.Equal(t, []string{"sh.keptn.event.echo.triggered"}, source.Subjects())
+	require.NoError(t, source.Push(context.TODO(), models.KeptnContextExtendedCE{ID: "event-id", Type: strutils.Stringp("sh.keptn.event.echo.triggered")}))
+	update := <-eventChannel
+	require.Equal(t, "event-id", update.KeptnEvent.ID)
+	require.Equal(t, "sh.keptn.event.echo.triggered", update.MetaData.Subject)
+
+	require.NoError(t, source.Sender()(models.KeptnContextExtendedCE{ID: "sent-id"}))
+	require.Len(t, source.Sent(), 1)
+	require.Equal(t, "sent-id", source.Sent()[0].ID)
+
+	cancel()
+	<-done
+}
+
+func TestEventSourceWithPrefetch(t *testing.T) {
+	natsConnectorMock := &NATSConnectorMock{
+		QueueSubscribeMultipleFn: func(subjects []string, queueGroup string, fn nats2.ProcessEventFn) error { return nil },
+		UnsubscribeAllFn:         func() error { return nil },
+	}
+	eventChannel := make(chan types.EventUpdate, 10)
+	eventSource := New(natsConnectorMock, WithPrefetch(2))
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	_, _ = eventSource.Start(ctx, types.RegistrationData{}, eventChannel, nil, nil)
+	eventSource.OnSubscriptionUpdate([]string{"a"})
+
+	for i := 0; i < 4; i++ {
+		event := models.KeptnContextExtendedCE{ID: fmt.Sprintf("id-%d", i)}
+		jsonEvent, _ := event.ToJSON()
+		go natsConnectorMock.ProcessEventFn(&nats.Msg{Subject: "a", Data: jsonEvent, Sub: &nats.Subscription{Subject: "a"}})
+	}
+	received := []types.EventUpdate{<-eventChannel, <-eventChannel}
+	// no more than the prefetch count is passed on before the events are acknowledged
+	require.Never(t, func() bool { return len(eventChannel) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+
+	require.NoError(t, received[0].Acknowledger.Ack())
+	received = append(received, <-eventChannel)
+	require.Never(t, func() bool { return len(eventChannel) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+
+	require.ErrorIs(t, received[1].Acknowledger.Nack(time.Second), ErrRedeliveryNotSupported)
+	received = append(received, <-eventChannel)
+	ids := map[string]struct{}{}
+	for _, update := range received {
+		ids[update.KeptnEvent.ID] = struct{}{}
+	}
+	require.Len(t, ids, 4)
+}
+
+func TestEventSourcePrefetchSlotReleasedWithoutSettling(t *testing.T) {
+	natsConnectorMock := &NATSConnectorMock{
+		QueueSubscribeMultipleFn: func(subjects []string, queueGroup string, fn nats2.ProcessEventFn) error { return nil },
+		UnsubscribeAllFn:         func() error { return nil },
+	}
+	eventChannel := make(chan types.EventUpdate, 10)
+	eventSource := New(natsConnectorMock, WithPrefetch(1))
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	_, _ = eventSource.Start(ctx, types.RegistrationData{}, eventChannel, nil, nil)
+	eventSource.OnSubscriptionUpdate([]string{"a"})
+
+	for i := 0; i < 2; i++ {
+		event := models.KeptnContextExtendedCE{ID: fmt.Sprintf("id-%d", i)}
+		jsonEvent, _ := event.ToJSON()
+		go natsConnectorMock.ProcessEventFn(&nats.Msg{Subject: "a", Data: jsonEvent, Sub: &nats.Subscription{Subject: "a"}})
+	}
+	first := <-eventChannel
+	require.Never(t, func() bool { return len(eventChannel) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+
+	releaser, ok := first.Acknowledger.(types.Releaser)
+	require.True(t, ok)
+	releaser.Release()
+	second := <-eventChannel
+	require.NotEqual(t, first.KeptnEvent.ID, second.KeptnEvent.ID)
+}
+
+func TestEventSourceWithoutPrefetch(t *testing.T) {
+	natsConnectorMock := &NATSConnectorMock{
+		QueueSubscribeMultipleFn: func(subjects []string, queueGroup string, fn nats2.ProcessEventFn) error { return nil },
+		UnsubscribeAllFn:         func() error { return nil },
+	}
+	eventChannel := make(chan types.EventUpdate)
+	eventSource := New(natsConnectorMock)
+	_, _ = eventSource.Start(context.TODO(), types.RegistrationData{}, eventChannel, nil, nil)
+	eventSource.OnSubscriptionUpdate([]string{"a"})
+	event := models.KeptnContextExtendedCE{ID: "id"}
+	jsonEvent, _ := event.ToJSON()
+	go natsConnectorMock.ProcessEventFn(&nats.Msg{Subject: "a", Data: jsonEvent, Sub: &nats.Subscription{Subject: "a"}})
+	require.Nil(t, (<-eventChannel).Acknowledger)
+}
+
+func TestEventSourceWithRawMessageObserver(t *testing.T) {
+	natsConnectorMock := &NATSConnectorMock{
+		QueueSubscribeMultipleFn: func(subjects []string, queueGroup string, fn nats2.ProcessEventFn) error { return nil },
+		UnsubscribeAllFn:         func() error { return nil },
+	}
+	type rawMessage struct {
+		data    string
+		headers map[string]string
+	}
+	observed := make(chan rawMessage, 2)
+	eventSource := New(natsConnectorMock, WithRawMessageObserver(func(data []byte, headers map[string]string) {
+		observed <- rawMessage{data: string(data), headers: headers}
+	}))
+	receiveErrors := make(chan error, 1)
+	eventChannel := make(chan types.EventUpdate, 1)
+	_, _ = eventSource.Start(context.TODO(), types.RegistrationData{}, eventChannel, nil, receiveErrors)
+	eventSource.OnSubscriptionUpdate([]string{"a"})
+
+	// malformed messages are observed, although they are never passed on as event
+	err := natsConnectorMock.ProcessEventFn(&nats.Msg{
+		Subject: "a",
+		Data:    []byte("not-json"),
+		Header:  nats.Header{"Content-Type": []string{"text/plain"}, "X-Trace": []string{"a", "b"}},
+		Sub:     &nats.Subscription{Subject: "a"},
+	})
+	require.Error(t, err)
+	require.Equal(t, rawMessage{data: "not-json", headers: map[string]string{"Content-Type": "text/plain", "X-Trace": "a,b"}}, <-observed)
+	require.Error(t, <-receiveErrors)
+
+	event := models.KeptnContextExtendedCE{ID: "id"}
+	jsonEvent, _ := event.ToJSON()
+	require.NoError(t, natsConnectorMock.ProcessEventFn(&nats.Msg{Subject: "a", Data: jsonEvent, Sub: &nats.Subscription{Subject: "a"}}))
+	require.Equal(t, rawMessage{data: string(jsonEvent), headers: map[string]string{}}, <-observed)
+	require.Equal(t, "id", (<-eventChannel).KeptnEvent.ID)
+}
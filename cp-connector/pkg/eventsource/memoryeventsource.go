@@ -0,0 +1,114 @@
+package eventsource
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+)
+
+var _ EventSource = (*MemoryEventSource)(nil)
+
+// MemoryEventSource is an EventSource passing the events pushed to it within the same process.
+// It does not connect to any message broker and is useful for tests and for replaying recorded events.
+// Events sent by the integration are recorded and can be retrieved via Sent
+type MemoryEventSource struct {
+	mtx          sync.Mutex
+	ctx          context.Context
+	eventChannel chan types.EventUpdate
+	started      chan struct{}
+	ready        chan struct{}
+	readyOnce    sync.Once
+	subjects     []string
+	sent         []models.KeptnContextExtendedCE
+}
+
+// NewMemoryEventSource creates a new MemoryEventSource
+func NewMemoryEventSource() *MemoryEventSource {
+	return &MemoryEventSource{
+		started: make(chan struct{}),
+		ready:   make(chan struct{}),
+	}
+}
+
+func (m *MemoryEventSource) Start(ctx context.Context, data types.RegistrationData, eventChannel chan types.EventUpdate, connectionStates chan types.ConnectionState, receiveErrors chan error) (<-chan struct{}, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	select {
+	case <-m.started:
+		return nil, fmt.Errorf("could not start memory event source: already started")
+	default:
+	}
+	m.ctx = ctx
+	m.eventChannel = eventChannel
+	close(m.started)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+	}()
+	return done, nil
+}
+
+// Push passes the event to the ControlPlane, using the event type as subject.
+// It blocks until the event source was started and received its first subscription update, so that the event
+// can be matched against the subscriptions, and until the ControlPlane received the event
+func (m *MemoryEventSource) Push(ctx context.Context, event models.KeptnContextExtendedCE) error {
+	select {
+	case <-m.ready:
+	case <-ctx.Done():
+		return fmt.Errorf("could not push event %s: event source did not receive subscriptions: %w", event.ID, ctx.Err())
+	}
+	m.mtx.Lock()
+	sourceCtx, eventChannel := m.ctx, m.eventChannel
+	m.mtx.Unlock()
+
+	subject := ""
+	if event.Type != nil {
+		subject = *event.Type
+	}
+	select {
+	case eventChannel <- types.EventUpdate{KeptnEvent: event, MetaData: types.EventUpdateMetaData{Subject: subject}}:
+		return nil
+	case <-sourceCtx.Done():
+		return fmt.Errorf("could not push event %s: event source is shutting down", event.ID)
+	case <-ctx.Done():
+		return fmt.Errorf("could not push event %s: %w", event.ID, ctx.Err())
+	}
+}
+
+func (m *MemoryEventSource) OnSubscriptionUpdate(subjects []string) {
+	m.mtx.Lock()
+	m.subjects = subjects
+	m.mtx.Unlock()
+	m.readyOnce.Do(func() { close(m.ready) })
+}
+
+// Subjects returns the subjects of the latest subscription update
+func (m *MemoryEventSource) Subjects() []string {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return append([]string{}, m.subjects...)
+}
+
+func (m *MemoryEventSource) Sender() types.EventSender {
+	return func(ce models.KeptnContextExtendedCE) error {
+		m.mtx.Lock()
+		defer m.mtx.Unlock()
+		m.sent = append(m.sent, ce)
+		return nil
+	}
+}
+
+// Sent returns the events sent by the integration so far
+func (m *MemoryEventSource) Sent() []models.KeptnContextExtendedCE {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return append([]models.KeptnContextExtendedCE{}, m.sent...)
+}
+
+func (m *MemoryEventSource) Stop() error {
+	return nil
+}
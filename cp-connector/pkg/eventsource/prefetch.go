@@ -0,0 +1,48 @@
+package eventsource
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRedeliveryNotSupported is returned when requesting the redelivery of an event received via NATS core,
+// which does not support redelivering messages
+var ErrRedeliveryNotSupported = errors.New("NATS does not support redelivering events")
+
+// WithPrefetch limits the number of received events the NATSEventSource passes to the ControlPlane before they
+// are acknowledged to the given count, e.g. to bound the memory used for events waiting to be handled.
+// A count <= 0 disables the limit, which is the default.
+//
+// The prefetch count is distinct from the handling of the events: the ControlPlane handles one event at a time
+// (events of subscriptions with strict ordering are handled concurrently per subscription) and holds the events
+// waiting to be handled in its receive buffer (see controlplane.WithReceiveBuffer). The prefetch count limits all
+// of these events together, so a receive buffer larger than the prefetch count is never filled. Further messages
+// are held back by the NATS client, which counts them as pending messages of their broker subscription
+func WithPrefetch(count int) func(*NATSEventSource) {
+	return func(ns *NATSEventSource) {
+		ns.prefetch = count
+	}
+}
+
+// prefetchSlot is the Acknowledger of an event counting against the prefetch count.
+// Acknowledging or releasing the event frees its slot for the next event
+type prefetchSlot struct {
+	slots chan struct{}
+	once  sync.Once
+}
+
+// Release frees the slot without settling the event
+func (p *prefetchSlot) Release() {
+	p.once.Do(func() { <-p.slots })
+}
+
+func (p *prefetchSlot) Ack() error {
+	p.Release()
+	return nil
+}
+
+func (p *prefetchSlot) Nack(time.Duration) error {
+	p.Release()
+	return ErrRedeliveryNotSupported
+}
@@ -0,0 +1,32 @@
+package eventsource
+
+import (
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// RawMessageObserverFn is called with the payload and the headers of every message received by the event source,
+// before the message is decoded
+type RawMessageObserverFn func(data []byte, headers map[string]string)
+
+// WithRawMessageObserver sets a function that observes the messages received by the NATSEventSource before they
+// are decoded, e.g. to debug malformed messages that never become an EventUpdate. Headers with multiple values
+// are passed as a comma separated list. The observer is called synchronously and must not modify the data
+func WithRawMessageObserver(observer RawMessageObserverFn) func(*NATSEventSource) {
+	return func(ns *NATSEventSource) {
+		ns.rawMessageObserver = observer
+	}
+}
+
+// observeRawMessage passes the received message to the configured RawMessageObserverFn, if any
+func (n *NATSEventSource) observeRawMessage(msg *nats.Msg) {
+	if n.rawMessageObserver == nil {
+		return
+	}
+	headers := make(map[string]string, len(msg.Header))
+	for key, values := range msg.Header {
+		headers[key] = strings.Join(values, ",")
+	}
+	n.rawMessageObserver(msg.Data, headers)
+}
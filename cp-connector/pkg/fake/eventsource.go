@@ -3,19 +3,18 @@ package fake
 import (
 	"context"
 	"github.com/keptn/keptn/cp-connector/pkg/types"
-	"sync"
 )
 
 type EventSourceMock struct {
-	StartFn                func(context.Context, types.RegistrationData, chan types.EventUpdate, *sync.WaitGroup) error
+	StartFn                func(context.Context, types.RegistrationData, chan types.EventUpdate, chan types.ConnectionState, chan error) (<-chan struct{}, error)
 	OnSubscriptionUpdateFn func([]string)
 	SenderFn               func() types.EventSender
 	StopFn                 func() error
 }
 
-func (e *EventSourceMock) Start(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, wg *sync.WaitGroup) error {
+func (e *EventSourceMock) Start(ctx context.Context, data types.RegistrationData, ces chan types.EventUpdate, states chan types.ConnectionState, errC chan error) (<-chan struct{}, error) {
 	if e.StartFn != nil {
-		return e.StartFn(ctx, data, ces, wg)
+		return e.StartFn(ctx, data, ces, states, errC)
 	}
 	panic("implement me")
 }
@@ -0,0 +1,66 @@
+package fake
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// LoggerMock is a logger recording all logged messages
+type LoggerMock struct {
+	mtx      sync.Mutex
+	messages []string
+}
+
+func (l *LoggerMock) record(level string, msg string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.messages = append(l.messages, level+": "+strings.TrimSuffix(msg, "\n"))
+}
+
+// Messages returns all messages logged so far, prefixed with their log level
+func (l *LoggerMock) Messages() []string {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return append([]string{}, l.messages...)
+}
+
+// Contains checks whether any logged message contains the given string
+func (l *LoggerMock) Contains(s string) bool {
+	for _, m := range l.Messages() {
+		if strings.Contains(m, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *LoggerMock) Debug(v ...interface{}) { l.record("debug", fmt.Sprint(v...)) }
+
+func (l *LoggerMock) Debugf(format string, v ...interface{}) {
+	l.record("debug", fmt.Sprintf(format, v...))
+}
+
+func (l *LoggerMock) Info(v ...interface{}) { l.record("info", fmt.Sprint(v...)) }
+
+func (l *LoggerMock) Infof(format string, v ...interface{}) {
+	l.record("info", fmt.Sprintf(format, v...))
+}
+
+func (l *LoggerMock) Warn(v ...interface{}) { l.record("warn", fmt.Sprint(v...)) }
+
+func (l *LoggerMock) Warnf(format string, v ...interface{}) {
+	l.record("warn", fmt.Sprintf(format, v...))
+}
+
+func (l *LoggerMock) Error(v ...interface{}) { l.record("error", fmt.Sprint(v...)) }
+
+func (l *LoggerMock) Errorf(format string, v ...interface{}) {
+	l.record("error", fmt.Sprintf(format, v...))
+}
+
+func (l *LoggerMock) Fatal(v ...interface{}) { l.record("fatal", fmt.Sprint(v...)) }
+
+func (l *LoggerMock) Fatalf(format string, v ...interface{}) {
+	l.record("fatal", fmt.Sprintf(format, v...))
+}
@@ -0,0 +1,38 @@
+package fake
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// RoundTripperMock is a http.RoundTripper recording all requests.
+// Requests are answered by RoundTripFn or, if it is not set, with an empty JSON object
+type RoundTripperMock struct {
+	RoundTripFn func(*http.Request) (*http.Response, error)
+	mtx         sync.Mutex
+	requests    []*http.Request
+}
+
+func (r *RoundTripperMock) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mtx.Lock()
+	r.requests = append(r.requests, req)
+	r.mtx.Unlock()
+	if r.RoundTripFn != nil {
+		return r.RoundTripFn(req)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader("{}")),
+		Request:    req,
+	}, nil
+}
+
+// Requests returns all requests recorded so far
+func (r *RoundTripperMock) Requests() []*http.Request {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]*http.Request{}, r.requests...)
+}
@@ -4,17 +4,18 @@ import (
 	"context"
 	"github.com/keptn/go-utils/pkg/api/models"
 	"github.com/keptn/keptn/cp-connector/pkg/types"
-	"sync"
 )
 
 type SubscriptionSourceMock struct {
-	StartFn    func(context.Context, types.RegistrationData, chan []models.EventSubscription, *sync.WaitGroup) error
-	RegisterFn func(integration models.Integration) (string, error)
+	StartFn      func(context.Context, types.RegistrationData, chan []models.EventSubscription, chan error) (<-chan struct{}, error)
+	RegisterFn   func(integration models.Integration) (string, error)
+	UnregisterFn func(integrationID string) error
+	FetchFn      func(ctx context.Context) error
 }
 
-func (u *SubscriptionSourceMock) Start(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, wg *sync.WaitGroup) error {
+func (u *SubscriptionSourceMock) Start(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, errC chan error) (<-chan struct{}, error) {
 	if u.StartFn != nil {
-		return u.StartFn(ctx, data, c, wg)
+		return u.StartFn(ctx, data, c, errC)
 	}
 	panic("implement me")
 }
@@ -25,3 +26,17 @@ func (u *SubscriptionSourceMock) Register(integration models.Integration) (strin
 	}
 	panic("implement me")
 }
+
+func (u *SubscriptionSourceMock) Unregister(integrationID string) error {
+	if u.UnregisterFn != nil {
+		return u.UnregisterFn(integrationID)
+	}
+	panic("implement me")
+}
+
+func (u *SubscriptionSourceMock) Fetch(ctx context.Context) error {
+	if u.FetchFn != nil {
+		return u.FetchFn(ctx)
+	}
+	panic("implement me")
+}
@@ -3,8 +3,10 @@ package fake
 import "github.com/keptn/go-utils/pkg/api/models"
 
 type UniformAPIMock struct {
-	RegisterIntegrationFn func(models.Integration) (string, error)
-	PingFn                func(string) (*models.Integration, error)
+	RegisterIntegrationFn   func(models.Integration) (string, error)
+	PingFn                  func(string) (*models.Integration, error)
+	UnregisterIntegrationFn func(string) error
+	GetRegistrationsFn      func() ([]*models.Integration, error)
 }
 
 func (m *UniformAPIMock) Ping(integrationID string) (*models.Integration, error) {
@@ -25,9 +27,15 @@ func (m *UniformAPIMock) CreateSubscription(integrationID string, subscription m
 }
 
 func (m *UniformAPIMock) UnregisterIntegration(integrationID string) error {
-	panic("implement me")
+	if m.UnregisterIntegrationFn != nil {
+		return m.UnregisterIntegrationFn(integrationID)
+	}
+	panic("UnregisterIntegration() not implemented")
 }
 
 func (m *UniformAPIMock) GetRegistrations() ([]*models.Integration, error) {
-	panic("implement me")
+	if m.GetRegistrationsFn != nil {
+		return m.GetRegistrationsFn()
+	}
+	panic("GetRegistrations() not implemented")
 }
@@ -0,0 +1,76 @@
+package logforwarder
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	api "github.com/keptn/go-utils/pkg/api/utils"
+)
+
+type coalesceKey struct {
+	keptnContext  string
+	integrationID string
+}
+
+// errorCoalescer collects log entries of the same keptn context and integration
+// and forwards them as a single log entry once the coalescing window expired
+type errorCoalescer struct {
+	logApi  api.LogsV1Interface
+	window  time.Duration
+	mtx     sync.Mutex
+	pending map[coalesceKey][]models.LogEntry
+	metrics *metrics
+}
+
+func newErrorCoalescer(logApi api.LogsV1Interface, window time.Duration) *errorCoalescer {
+	return &errorCoalescer{
+		logApi:  logApi,
+		window:  window,
+		pending: map[coalesceKey][]models.LogEntry{},
+	}
+}
+
+// add queues the log entry. The first entry of a keptn context starts the coalescing window
+func (c *errorCoalescer) add(entry models.LogEntry) {
+	key := coalesceKey{keptnContext: entry.KeptnContext, integrationID: entry.IntegrationID}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if _, ok := c.pending[key]; !ok {
+		time.AfterFunc(c.window, func() { c.forward(key) })
+	}
+	c.pending[key] = append(c.pending[key], entry)
+}
+
+func (c *errorCoalescer) forward(key coalesceKey) {
+	c.mtx.Lock()
+	entries := c.pending[key]
+	delete(c.pending, key)
+	c.mtx.Unlock()
+	if len(entries) == 0 {
+		return
+	}
+	c.metrics.observeCoalesced(len(entries) - 1)
+	// there is no caller the error could be returned to, it is counted by the metrics
+	_ = send(c.logApi, c.metrics, []models.LogEntry{coalesce(entries)})
+}
+
+// coalesce combines the given entries into one entry summarizing the messages of all entries.
+// Triggered ID and integration ID are taken from the first entry
+func coalesce(entries []models.LogEntry) models.LogEntry {
+	if len(entries) == 1 {
+		return entries[0]
+	}
+	result := entries[0]
+	var tasks []string
+	var messages []string
+	for _, e := range entries {
+		tasks = append(tasks, e.Task)
+		messages = append(messages, fmt.Sprintf("%s: %s", e.Task, e.Message))
+	}
+	result.Task = strings.Join(tasks, ",")
+	result.Message = fmt.Sprintf("%d tasks failed: %s", len(entries), strings.Join(messages, "; "))
+	return result
+}
@@ -1,13 +1,17 @@
 package logforwarder
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/keptn/go-utils/pkg/api/models"
 	api "github.com/keptn/go-utils/pkg/api/utils"
 	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
 	"github.com/keptn/keptn/cp-connector/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 //go:generate moq -pkg fake -skip-ensure -out ./fake/logapi.go . logAPI:LogAPIMock
@@ -20,8 +24,35 @@ type LogForwarder interface {
 var _ LogForwarder = LogForwardingHandler{}
 
 type LogForwardingHandler struct {
-	logApi api.LogsV1Interface
-	logger logger.Logger
+	logApi              api.LogsV1Interface
+	logger              logger.Logger
+	includeEventContext bool
+	coalescer           *errorCoalescer
+	metrics             *metrics
+	undecodablePolicy   UndecodableDataPolicy
+}
+
+// UndecodableDataPolicy decides what the LogForwardingHandler does with events whose data cannot be decoded
+type UndecodableDataPolicy int
+
+const (
+	// UndecodableDataReturnError returns the decoding error from Forward
+	UndecodableDataReturnError UndecodableDataPolicy = iota
+	// UndecodableDataSkip logs the decoding error and skips the event
+	UndecodableDataSkip
+	// UndecodableDataForwardRaw forwards a log entry containing the decoding error and the raw event data
+	UndecodableDataForwardRaw
+)
+
+// NewLogAPI creates a client for the log ingestion API of the Keptn control plane at the given base URL
+// that sends all requests using the given HTTP client, e.g. to route them through a proxy or to use custom timeouts.
+// Further options (e.g. the auth token) are applied to the underlying api.APISet
+func NewLogAPI(baseURL string, httpClient *http.Client, options ...func(*api.APISet)) (api.LogsV1Interface, error) {
+	apiSet, err := api.New(baseURL, append([]func(*api.APISet){api.WithHTTPClient(httpClient)}, options...)...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create log API: %w", err)
+	}
+	return apiSet.LogsV1(), nil
 }
 
 func New(logApi api.LogsV1Interface, opts ...func(handler *LogForwardingHandler)) *LogForwardingHandler {
@@ -32,6 +63,9 @@ func New(logApi api.LogsV1Interface, opts ...func(handler *LogForwardingHandler)
 	for _, o := range opts {
 		o(l)
 	}
+	if l.coalescer != nil {
+		l.coalescer.metrics = l.metrics
+	}
 	return l
 }
 
@@ -42,32 +76,77 @@ func WithLogger(logger logger.Logger) func(*LogForwardingHandler) {
 	}
 }
 
+// WithEventContext configures the LogForwardingHandler to prefix the forwarded log messages with the
+// project, stage and service contained in the data of the event, e.g. "[project=p stage=s service=svc] message"
+func WithEventContext(include bool) func(*LogForwardingHandler) {
+	return func(lfh *LogForwardingHandler) {
+		lfh.includeEventContext = include
+	}
+}
+
+// WithErrorCoalescing configures the LogForwardingHandler to combine the errors of all errored .finished events
+// of the same keptn context received within the given window into a single log entry.
+// The log entry is forwarded once the window started by the first error of the keptn context expired
+func WithErrorCoalescing(window time.Duration) func(*LogForwardingHandler) {
+	return func(lfh *LogForwardingHandler) {
+		lfh.coalescer = newErrorCoalescer(lfh.logApi, window)
+	}
+}
+
+// WithUndecodableDataPolicy sets what the LogForwardingHandler does with .finished and log.error events whose
+// data cannot be decoded. By default, the decoding error is returned from Forward
+func WithUndecodableDataPolicy(policy UndecodableDataPolicy) func(*LogForwardingHandler) {
+	return func(lfh *LogForwardingHandler) {
+		lfh.undecodablePolicy = policy
+	}
+}
+
+// WithLogMetrics registers prometheus counters for the forwarded log entries and the failed forwards
+// at the given registerer. If error coalescing is enabled, the coalesced log entries are counted as well
+func WithLogMetrics(registerer prometheus.Registerer) func(*LogForwardingHandler) {
+	return func(lfh *LogForwardingHandler) {
+		lfh.metrics = newMetrics(registerer)
+	}
+}
+
 func (l LogForwardingHandler) Forward(keptnEvent models.KeptnContextExtendedCE, integrationID string) error {
+	if err := l.forward(keptnEvent, integrationID); err != nil {
+		l.metrics.observeFailed()
+		return err
+	}
+	return nil
+}
+
+func (l LogForwardingHandler) forward(keptnEvent models.KeptnContextExtendedCE, integrationID string) error {
 	if integrationID == "" {
 		return nil
 	}
 	l.logger.Infof("Forwarding logs for service with integrationID `%s`", integrationID)
 	if strings.HasSuffix(*keptnEvent.Type, ".finished") {
-		eventData := &keptnv2.EventData{}
-		if err := keptnv2.EventDataAs(keptnEvent, eventData); err != nil {
-			return fmt.Errorf("could not decode Keptn event data: %w", err)
-		}
-
 		taskName, _, err := keptnv2.ParseTaskEventType(*keptnEvent.Type)
 		if err != nil {
 			return fmt.Errorf("could not parse Keptn event type: %w", err)
 		}
 
+		eventData := &keptnv2.EventData{}
+		if err := keptnv2.EventDataAs(keptnEvent, eventData); err != nil {
+			return l.undecodable(keptnEvent, integrationID, taskName, fmt.Errorf("could not decode Keptn event data: %w", err))
+		}
+
 		if eventData.Status == keptnv2.StatusErrored {
 			l.logger.Info("Received '.finished' event with status 'errored'. Forwarding log message to log ingestion API")
-			l.logApi.Log([]models.LogEntry{{
+			entry := models.LogEntry{
 				IntegrationID: integrationID,
-				Message:       eventData.Message,
+				Message:       l.message(*eventData, eventData.Message),
 				KeptnContext:  keptnEvent.Shkeptncontext,
 				Task:          taskName,
 				TriggeredID:   keptnEvent.Triggeredid,
-			}})
-			l.logApi.Flush()
+			}
+			if l.coalescer != nil {
+				l.coalescer.add(entry)
+				return nil
+			}
+			l.send([]models.LogEntry{entry})
 		}
 		return nil
 	} else if *keptnEvent.Type == keptnv2.ErrorLogEventName {
@@ -75,21 +154,93 @@ func (l LogForwardingHandler) Forward(keptnEvent models.KeptnContextExtendedCE,
 
 		eventData := &keptnv2.ErrorLogEvent{}
 		if err := keptnv2.EventDataAs(keptnEvent, eventData); err != nil {
-			return fmt.Errorf("unable decode Keptn event data: %w", err)
+			return l.undecodable(keptnEvent, integrationID, "", fmt.Errorf("unable decode Keptn event data: %w", err))
+		}
+
+		message := eventData.Message
+		if l.includeEventContext {
+			// the context of the event is not part of the error log event data, so it is decoded separately
+			contextData := keptnv2.EventData{}
+			if err := keptnv2.EventDataAs(keptnEvent, &contextData); err == nil {
+				message = l.message(contextData, message)
+			}
 		}
 
 		if eventData.IntegrationID != "" {
 			// overwrite default integrationID if it has been set in the event
 			integrationID = eventData.IntegrationID
 		}
-		l.logApi.Log([]models.LogEntry{{
+		l.send([]models.LogEntry{{
 			IntegrationID: integrationID,
-			Message:       eventData.Message,
+			Message:       message,
 			KeptnContext:  keptnEvent.Shkeptncontext,
 			Task:          eventData.Task,
 			TriggeredID:   keptnEvent.Triggeredid,
 		}})
-		l.logApi.Flush()
 	}
 	return nil
 }
+
+// undecodable handles an event whose data could not be decoded according to the configured UndecodableDataPolicy
+func (l LogForwardingHandler) undecodable(keptnEvent models.KeptnContextExtendedCE, integrationID string, task string, decodeErr error) error {
+	switch l.undecodablePolicy {
+	case UndecodableDataSkip:
+		l.logger.Warnf("Skipping forwarding of logs of event %s: %v", keptnEvent.ID, decodeErr)
+		return nil
+	case UndecodableDataForwardRaw:
+		l.logger.Warnf("Forwarding raw data of event %s: %v", keptnEvent.ID, decodeErr)
+		message := decodeErr.Error()
+		if rawData, err := json.Marshal(keptnEvent.Data); err == nil {
+			message += "; raw data: " + string(rawData)
+		}
+		l.send([]models.LogEntry{{
+			IntegrationID: integrationID,
+			Message:       message,
+			KeptnContext:  keptnEvent.Shkeptncontext,
+			Task:          task,
+			TriggeredID:   keptnEvent.Triggeredid,
+		}})
+		return nil
+	default:
+		return decodeErr
+	}
+}
+
+func (l LogForwardingHandler) send(entries []models.LogEntry) {
+	if err := send(l.logApi, l.metrics, entries); err != nil {
+		l.logger.Warnf("Could not forward log entries: %v", err)
+	}
+}
+
+// send passes the entries to the log API and flushes them
+func send(logApi api.LogsV1Interface, m *metrics, entries []models.LogEntry) error {
+	logApi.Log(entries)
+	if err := logApi.Flush(); err != nil {
+		m.observeFailed()
+		return err
+	}
+	m.observeForwarded(len(entries))
+	return nil
+}
+
+// message prefixes the given message with the project, stage and service of the event data,
+// if the LogForwardingHandler is configured to include the event context
+func (l LogForwardingHandler) message(eventData keptnv2.EventData, message string) string {
+	if !l.includeEventContext {
+		return message
+	}
+	var eventContext []string
+	for _, field := range []struct{ name, value string }{
+		{name: "project", value: eventData.Project},
+		{name: "stage", value: eventData.Stage},
+		{name: "service", value: eventData.Service},
+	} {
+		if field.value != "" {
+			eventContext = append(eventContext, field.name+"="+field.value)
+		}
+	}
+	if len(eventContext) == 0 {
+		return message
+	}
+	return "[" + strings.Join(eventContext, " ") + "] " + message
+}
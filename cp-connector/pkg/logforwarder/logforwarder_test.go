@@ -1,12 +1,17 @@
 package logforwarder
 
 import (
+	"errors"
 	"github.com/keptn/keptn/cp-connector/pkg/fake"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/keptn/go-utils/pkg/api/models"
 	"github.com/keptn/go-utils/pkg/common/strutils"
 	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -91,3 +96,187 @@ func TestLogForwarderErrorEventIntegrationID(t *testing.T) {
 	require.Len(t, logHandler.LogCalls(), 1)
 	require.Equal(t, logHandler.LogCalls()[0].Logs[0].IntegrationID, "some-new-id")
 }
+
+func TestNewLogAPIUsesHTTPClient(t *testing.T) {
+	transport := &fake.RoundTripperMock{}
+	logAPI, err := NewLogAPI("http://keptn.example.com/api", &http.Client{Transport: transport})
+	require.Nil(t, err)
+
+	logForwarder := New(logAPI)
+	keptnEvent := models.KeptnContextExtendedCE{
+		ID:   "some-id",
+		Type: strutils.Stringp("sh.keptn.event.echo.finished"),
+		Data: keptnv2.EventData{Status: keptnv2.StatusErrored, Message: "some message"},
+	}
+	require.Nil(t, logForwarder.Forward(keptnEvent, "some-other-id"))
+	requests := transport.Requests()
+	require.Len(t, requests, 1)
+	require.Equal(t, "keptn.example.com", requests[0].URL.Host)
+	require.Equal(t, http.MethodPost, requests[0].Method)
+}
+
+func TestLogForwarderFinishedForwardWithEventContext(t *testing.T) {
+	logHandler := &fake.LogAPIMock{
+		LogFunc:   func(logs []models.LogEntry) {},
+		FlushFunc: func() error { return nil },
+	}
+	logForwarder := New(logHandler, WithEventContext(true))
+	keptnEvent := models.KeptnContextExtendedCE{ID: "some-id", Type: strutils.Stringp("sh.keptn.event.echo.finished"), Data: keptnv2.EventData{
+		Project: "my-project",
+		Stage:   "my-stage",
+		Service: "my-service",
+		Status:  keptnv2.StatusErrored,
+		Message: "some error",
+	}}
+	err := logForwarder.Forward(keptnEvent, "some-other-id")
+	require.Nil(t, err)
+	require.Len(t, logHandler.LogCalls(), 1)
+	require.Equal(t, "[project=my-project stage=my-stage service=my-service] some error", logHandler.LogCalls()[0].Logs[0].Message)
+}
+
+func TestLogForwarderErrorForwardWithEventContext(t *testing.T) {
+	logHandler := &fake.LogAPIMock{
+		LogFunc:   func(logs []models.LogEntry) {},
+		FlushFunc: func() error { return nil },
+	}
+	logForwarder := New(logHandler, WithEventContext(true))
+	keptnEvent := models.KeptnContextExtendedCE{ID: "some-id", Type: strutils.Stringp("sh.keptn.log.error"), Data: map[string]interface{}{
+		"project": "my-project",
+		"stage":   "my-stage",
+		"message": "some error",
+	}}
+	err := logForwarder.Forward(keptnEvent, "some-other-id")
+	require.Nil(t, err)
+	require.Len(t, logHandler.LogCalls(), 1)
+	require.Equal(t, "[project=my-project stage=my-stage] some error", logHandler.LogCalls()[0].Logs[0].Message)
+}
+
+func TestLogForwarderWithoutEventContext(t *testing.T) {
+	logHandler := &fake.LogAPIMock{
+		LogFunc:   func(logs []models.LogEntry) {},
+		FlushFunc: func() error { return nil },
+	}
+	logForwarder := New(logHandler)
+	keptnEvent := models.KeptnContextExtendedCE{ID: "some-id", Type: strutils.Stringp("sh.keptn.event.echo.finished"), Data: keptnv2.EventData{
+		Project: "my-project",
+		Status:  keptnv2.StatusErrored,
+		Message: "some error",
+	}}
+	err := logForwarder.Forward(keptnEvent, "some-other-id")
+	require.Nil(t, err)
+	require.Equal(t, "some error", logHandler.LogCalls()[0].Logs[0].Message)
+}
+
+func TestLogForwarderErrorCoalescing(t *testing.T) {
+	logHandler := &fake.LogAPIMock{
+		LogFunc:   func(logs []models.LogEntry) {},
+		FlushFunc: func() error { return nil },
+	}
+	logForwarder := New(logHandler, WithErrorCoalescing(50*time.Millisecond))
+	for _, task := range []string{"deployment", "test"} {
+		keptnEvent := models.KeptnContextExtendedCE{
+			ID:             task + "-id",
+			Shkeptncontext: "keptn-context",
+			Triggeredid:    task + "-triggered-id",
+			Type:           strutils.Stringp("sh.keptn.event." + task + ".finished"),
+			Data:           keptnv2.EventData{Status: keptnv2.StatusErrored, Message: task + " failed"},
+		}
+		require.Nil(t, logForwarder.Forward(keptnEvent, "some-other-id"))
+	}
+	otherContext := models.KeptnContextExtendedCE{
+		ID:             "other-id",
+		Shkeptncontext: "other-keptn-context",
+		Type:           strutils.Stringp("sh.keptn.event.echo.finished"),
+		Data:           keptnv2.EventData{Status: keptnv2.StatusErrored, Message: "echo failed"},
+	}
+	require.Nil(t, logForwarder.Forward(otherContext, "some-other-id"))
+	require.Len(t, logHandler.LogCalls(), 0)
+
+	require.Eventually(t, func() bool {
+		return len(logHandler.LogCalls()) == 2
+	}, time.Second, 10*time.Millisecond)
+	entries := map[string]models.LogEntry{}
+	for _, call := range logHandler.LogCalls() {
+		require.Len(t, call.Logs, 1)
+		entries[call.Logs[0].KeptnContext] = call.Logs[0]
+	}
+	require.Equal(t, models.LogEntry{
+		IntegrationID: "some-other-id",
+		Message:       "2 tasks failed: deployment: deployment failed; test: test failed",
+		KeptnContext:  "keptn-context",
+		Task:          "deployment,test",
+		TriggeredID:   "deployment-triggered-id",
+	}, entries["keptn-context"])
+	require.Equal(t, "echo failed", entries["other-keptn-context"].Message)
+	require.Equal(t, "echo", entries["other-keptn-context"].Task)
+}
+
+func TestLogForwarderMetrics(t *testing.T) {
+	logHandler := &fake.LogAPIMock{
+		LogFunc:   func(logs []models.LogEntry) {},
+		FlushFunc: func() error { return nil },
+	}
+	registry := prometheus.NewRegistry()
+	logForwarder := New(logHandler, WithLogMetrics(registry))
+	errored := models.KeptnContextExtendedCE{ID: "some-id", Type: strutils.Stringp("sh.keptn.event.echo.finished"), Data: keptnv2.EventData{Status: keptnv2.StatusErrored}}
+	require.Nil(t, logForwarder.Forward(errored, "some-other-id"))
+	require.Nil(t, logForwarder.Forward(errored, "some-other-id"))
+	invalid := models.KeptnContextExtendedCE{ID: "some-id", Type: strutils.Stringp("sh.keptn.event.echo.finished"), Data: "some invalid data"}
+	require.NotNil(t, logForwarder.Forward(invalid, "some-other-id"))
+
+	logHandler.FlushFunc = func() error { return errors.New("log ingestion unavailable") }
+	require.Nil(t, logForwarder.Forward(errored, "some-other-id"))
+
+	require.Equal(t, float64(2), testutil.ToFloat64(logForwarder.metrics.forwarded))
+	require.Equal(t, float64(2), testutil.ToFloat64(logForwarder.metrics.failed))
+	require.Equal(t, float64(0), testutil.ToFloat64(logForwarder.metrics.coalesced))
+}
+
+func TestLogForwarderMetricsWithErrorCoalescing(t *testing.T) {
+	logHandler := &fake.LogAPIMock{
+		LogFunc:   func(logs []models.LogEntry) {},
+		FlushFunc: func() error { return nil },
+	}
+	registry := prometheus.NewRegistry()
+	logForwarder := New(logHandler, WithErrorCoalescing(10*time.Millisecond), WithLogMetrics(registry))
+	for _, task := range []string{"deployment", "test", "evaluation"} {
+		keptnEvent := models.KeptnContextExtendedCE{
+			Shkeptncontext: "keptn-context",
+			Type:           strutils.Stringp("sh.keptn.event." + task + ".finished"),
+			Data:           keptnv2.EventData{Status: keptnv2.StatusErrored},
+		}
+		require.Nil(t, logForwarder.Forward(keptnEvent, "some-other-id"))
+	}
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(logForwarder.metrics.forwarded) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, float64(2), testutil.ToFloat64(logForwarder.metrics.coalesced))
+}
+
+func TestLogForwarderUndecodableDataPolicy(t *testing.T) {
+	for _, eventType := range []string{"sh.keptn.event.echo.finished", "sh.keptn.log.error"} {
+		t.Run(eventType, func(t *testing.T) {
+			keptnEvent := models.KeptnContextExtendedCE{ID: "some-id", Shkeptncontext: "some-context", Triggeredid: "triggered-id", Type: strutils.Stringp(eventType), Data: "some invalid data"}
+
+			logHandler := &fake.LogAPIMock{}
+			err := New(logHandler, WithUndecodableDataPolicy(UndecodableDataSkip)).Forward(keptnEvent, "some-other-id")
+			require.Nil(t, err)
+			require.Len(t, logHandler.LogCalls(), 0)
+
+			logHandler = &fake.LogAPIMock{
+				LogFunc:   func(logs []models.LogEntry) {},
+				FlushFunc: func() error { return nil },
+			}
+			err = New(logHandler, WithUndecodableDataPolicy(UndecodableDataForwardRaw)).Forward(keptnEvent, "some-other-id")
+			require.Nil(t, err)
+			require.Len(t, logHandler.LogCalls(), 1)
+			entries := logHandler.LogCalls()[0].Logs
+			require.Len(t, entries, 1)
+			require.Equal(t, "some-other-id", entries[0].IntegrationID)
+			require.Equal(t, "some-context", entries[0].KeptnContext)
+			require.Equal(t, "triggered-id", entries[0].TriggeredID)
+			require.Contains(t, entries[0].Message, "decode Keptn event data")
+			require.Contains(t, entries[0].Message, `raw data: "some invalid data"`)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package logforwarder
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "keptn_cp_connector"
+	metricsSubsystem = "log_forwarder"
+)
+
+// metrics holds the prometheus collectors maintained by the LogForwardingHandler
+type metrics struct {
+	forwarded prometheus.Counter
+	failed    prometheus.Counter
+	coalesced prometheus.Counter
+}
+
+func newMetrics(registerer prometheus.Registerer) *metrics {
+	m := &metrics{
+		forwarded: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "forwarded_entries_total",
+			Help:      "Number of log entries forwarded to the log ingestion API",
+		}),
+		failed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "failed_forwards_total",
+			Help:      "Number of events whose log entries could not be forwarded",
+		}),
+		coalesced: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "coalesced_entries_total",
+			Help:      "Number of log entries not forwarded individually because they were combined with other entries of the same keptn context",
+		}),
+	}
+	m.forwarded = registerCollector(registerer, m.forwarded).(prometheus.Counter)
+	m.failed = registerCollector(registerer, m.failed).(prometheus.Counter)
+	m.coalesced = registerCollector(registerer, m.coalesced).(prometheus.Counter)
+	return m
+}
+
+// registerCollector registers the collector at the given registerer. If an equal collector
+// was already registered (e.g. by a previously created LogForwardingHandler), the existing one is returned
+func registerCollector(registerer prometheus.Registerer, collector prometheus.Collector) prometheus.Collector {
+	if err := registerer.Register(collector); err != nil {
+		are := prometheus.AlreadyRegisteredError{}
+		if errors.As(err, &are) {
+			return are.ExistingCollector
+		}
+	}
+	return collector
+}
+
+func (m *metrics) observeForwarded(count int) {
+	if m == nil {
+		return
+	}
+	m.forwarded.Add(float64(count))
+}
+
+func (m *metrics) observeFailed() {
+	if m == nil {
+		return
+	}
+	m.failed.Inc()
+}
+
+func (m *metrics) observeCoalesced(count int) {
+	if m == nil {
+		return
+	}
+	m.coalesced.Add(float64(count))
+}
@@ -0,0 +1,64 @@
+package logforwarder
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+)
+
+var _ LogForwarder = MultiLogForwarder{}
+
+// MultiLogForwarder is a LogForwarder passing every event on to several LogForwarders,
+// e.g. to forward error logs to the Keptn log API as well as to an external system
+type MultiLogForwarder struct {
+	forwarders []LogForwarder
+}
+
+// NewMultiLogForwarder creates a new MultiLogForwarder passing the events on to the given forwarders.
+// Nil forwarders are skipped
+func NewMultiLogForwarder(forwarders ...LogForwarder) MultiLogForwarder {
+	m := MultiLogForwarder{}
+	for _, f := range forwarders {
+		if f != nil {
+			m.forwarders = append(m.forwarders, f)
+		}
+	}
+	return m
+}
+
+// Forward passes the event on to all forwarders, regardless of whether one of them fails.
+// The errors of the failing forwarders are returned as a ForwardErrors
+func (m MultiLogForwarder) Forward(keptnEvent models.KeptnContextExtendedCE, integrationID string) error {
+	var errs ForwardErrors
+	for _, f := range m.forwarders {
+		if err := f.Forward(keptnEvent, integrationID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// ForwardErrors holds the errors of the forwarders of a MultiLogForwarder that failed to forward an event
+type ForwardErrors []error
+
+func (e ForwardErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return "could not forward log: " + strings.Join(messages, "; ")
+}
+
+// Is checks whether one of the errors matches the target
+func (e ForwardErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
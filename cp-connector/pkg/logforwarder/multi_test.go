@@ -0,0 +1,61 @@
+package logforwarder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/go-utils/pkg/common/strutils"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+	"github.com/keptn/keptn/cp-connector/pkg/fake"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingForwarder struct {
+	forwarded []string
+	err       error
+}
+
+func (r *recordingForwarder) Forward(keptnEvent models.KeptnContextExtendedCE, integrationID string) error {
+	r.forwarded = append(r.forwarded, keptnEvent.ID+"@"+integrationID)
+	return r.err
+}
+
+func TestMultiLogForwarder(t *testing.T) {
+	first, second := &recordingForwarder{}, &recordingForwarder{}
+	forwarder := NewMultiLogForwarder(first, nil, second)
+
+	require.NoError(t, forwarder.Forward(models.KeptnContextExtendedCE{ID: "event-id"}, "integration-id"))
+	require.Equal(t, []string{"event-id@integration-id"}, first.forwarded)
+	require.Equal(t, []string{"event-id@integration-id"}, second.forwarded)
+}
+
+func TestMultiLogForwarderAggregatesErrors(t *testing.T) {
+	errUnavailable := errors.New("log API unavailable")
+	failing, succeeding := &recordingForwarder{err: errUnavailable}, &recordingForwarder{}
+	forwarder := NewMultiLogForwarder(failing, succeeding)
+
+	err := forwarder.Forward(models.KeptnContextExtendedCE{ID: "event-id"}, "integration-id")
+	require.ErrorIs(t, err, errUnavailable)
+	require.EqualError(t, err, "could not forward log: log API unavailable")
+	// the failure of one forwarder does not keep the others from forwarding the event
+	require.Len(t, succeeding.forwarded, 1)
+
+	var errs ForwardErrors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 1)
+}
+
+func TestMultiLogForwarderWithLogForwardingHandler(t *testing.T) {
+	logHandler := &fake.LogAPIMock{
+		LogFunc:   func(logs []models.LogEntry) {},
+		FlushFunc: func() error { return nil },
+	}
+	recording := &recordingForwarder{}
+	forwarder := NewMultiLogForwarder(New(logHandler), recording)
+
+	keptnEvent := models.KeptnContextExtendedCE{ID: "event-id", Type: strutils.Stringp("sh.keptn.event.echo.finished"), Data: keptnv2.EventData{Status: keptnv2.StatusErrored}}
+	require.NoError(t, forwarder.Forward(keptnEvent, "integration-id"))
+	require.Len(t, logHandler.LogCalls(), 1)
+	require.Equal(t, []string{"event-id@integration-id"}, recording.forwarded)
+}
@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is the minimum severity of the entries written by a LevelLogger
+type Level int32
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseLevel returns the Level with the given name, e.g. "debug"
+func ParseLevel(name string) (Level, error) {
+	for l := DebugLevel; l <= ErrorLevel; l++ {
+		if strings.EqualFold(name, l.String()) {
+			return l, nil
+		}
+	}
+	return InfoLevel, fmt.Errorf("unknown log level %q", name)
+}
+
+// LevelSetter is implemented by loggers whose level can be changed at runtime
+type LevelSetter interface {
+	SetLevel(level Level)
+}
+
+// LevelLogger is a Logger that passes on the entries of at least the current level to the wrapped Logger.
+// Fatal entries are always passed on
+type LevelLogger struct {
+	logger Logger
+	level  int32
+}
+
+// NewLevelLogger creates a new LevelLogger wrapping the given logger
+func NewLevelLogger(logger Logger, level Level) *LevelLogger {
+	return &LevelLogger{logger: logger, level: int32(level)}
+}
+
+// SetLevel changes the level of the LevelLogger, it is safe to be called concurrently to logging
+func (l *LevelLogger) SetLevel(level Level) {
+	atomic.StoreInt32(&l.level, int32(level))
+}
+
+// Level returns the current level of the LevelLogger
+func (l *LevelLogger) Level() Level {
+	return Level(atomic.LoadInt32(&l.level))
+}
+
+func (l *LevelLogger) enabled(level Level) bool {
+	return level >= l.Level()
+}
+
+func (l *LevelLogger) Debug(v ...interface{}) {
+	if l.enabled(DebugLevel) {
+		l.logger.Debug(v...)
+	}
+}
+
+func (l *LevelLogger) Debugf(format string, v ...interface{}) {
+	if l.enabled(DebugLevel) {
+		l.logger.Debugf(format, v...)
+	}
+}
+
+func (l *LevelLogger) Info(v ...interface{}) {
+	if l.enabled(InfoLevel) {
+		l.logger.Info(v...)
+	}
+}
+
+func (l *LevelLogger) Infof(format string, v ...interface{}) {
+	if l.enabled(InfoLevel) {
+		l.logger.Infof(format, v...)
+	}
+}
+
+func (l *LevelLogger) Warn(v ...interface{}) {
+	if l.enabled(WarnLevel) {
+		l.logger.Warn(v...)
+	}
+}
+
+func (l *LevelLogger) Warnf(format string, v ...interface{}) {
+	if l.enabled(WarnLevel) {
+		l.logger.Warnf(format, v...)
+	}
+}
+
+func (l *LevelLogger) Error(v ...interface{}) {
+	if l.enabled(ErrorLevel) {
+		l.logger.Error(v...)
+	}
+}
+
+func (l *LevelLogger) Errorf(format string, v ...interface{}) {
+	if l.enabled(ErrorLevel) {
+		l.logger.Errorf(format, v...)
+	}
+}
+
+func (l *LevelLogger) Fatal(v ...interface{}) {
+	l.logger.Fatal(v...)
+}
+
+func (l *LevelLogger) Fatalf(format string, v ...interface{}) {
+	l.logger.Fatalf(format, v...)
+}
@@ -1,8 +1,10 @@
 package logger
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strings"
 )
 
 // Logger interface used by the go sdk
@@ -68,3 +70,60 @@ func (d DefaultLogger) Fatal(v ...interface{}) {
 func (d DefaultLogger) Fatalf(format string, v ...interface{}) {
 	d.logger.Fatalf(format, v...)
 }
+
+// PrefixedLogger is a Logger that prepends a fixed prefix to every log entry
+// before passing it to the wrapped Logger
+type PrefixedLogger struct {
+	logger Logger
+	prefix string
+}
+
+// NewPrefixedLogger creates a new PrefixedLogger wrapping the given logger
+func NewPrefixedLogger(logger Logger, prefix string) *PrefixedLogger {
+	return &PrefixedLogger{logger: logger, prefix: prefix}
+}
+
+func (p PrefixedLogger) Debug(v ...interface{}) {
+	p.logger.Debug(p.prepend(v)...)
+}
+
+func (p PrefixedLogger) Debugf(format string, v ...interface{}) {
+	p.logger.Debugf("%s "+format, append([]interface{}{p.prefix}, v...)...)
+}
+
+func (p PrefixedLogger) Info(v ...interface{}) {
+	p.logger.Info(p.prepend(v)...)
+}
+
+func (p PrefixedLogger) Infof(format string, v ...interface{}) {
+	p.logger.Infof("%s "+format, append([]interface{}{p.prefix}, v...)...)
+}
+
+func (p PrefixedLogger) Warn(v ...interface{}) {
+	p.logger.Warn(p.prepend(v)...)
+}
+
+func (p PrefixedLogger) Warnf(format string, v ...interface{}) {
+	p.logger.Warnf("%s "+format, append([]interface{}{p.prefix}, v...)...)
+}
+
+func (p PrefixedLogger) Error(v ...interface{}) {
+	p.logger.Error(p.prepend(v)...)
+}
+
+func (p PrefixedLogger) Errorf(format string, v ...interface{}) {
+	p.logger.Errorf("%s "+format, append([]interface{}{p.prefix}, v...)...)
+}
+
+func (p PrefixedLogger) Fatal(v ...interface{}) {
+	p.logger.Fatal(p.prepend(v)...)
+}
+
+func (p PrefixedLogger) Fatalf(format string, v ...interface{}) {
+	p.logger.Fatalf("%s "+format, append([]interface{}{p.prefix}, v...)...)
+}
+
+// prepend joins the prefix and the operands with spaces, independent of how the wrapped logger joins its operands
+func (p PrefixedLogger) prepend(v []interface{}) []interface{} {
+	return []interface{}{p.prefix + " " + strings.TrimSuffix(fmt.Sprintln(v...), "\n")}
+}
@@ -0,0 +1,23 @@
+package logger
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixedLoggerWithFormatVerbsInPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	prefixed := NewPrefixedLogger(&DefaultLogger{logger: log.New(&buf, "", 0)}, "[correlation-id=id-%d-%s]")
+
+	prefixed.Debugf("Received an event of type: %s", "sh.keptn.event.echo.triggered")
+	prefixed.Infof("Handled %d events", 2)
+	prefixed.Warnf("no operands")
+	prefixed.Error("Handling failed:", "timeout")
+	require.Equal(t, "[correlation-id=id-%d-%s] Received an event of type: sh.keptn.event.echo.triggered\n"+
+		"[correlation-id=id-%d-%s] Handled 2 events\n"+
+		"[correlation-id=id-%d-%s] no operands\n"+
+		"[correlation-id=id-%d-%s] Handling failed: timeout\n", buf.String())
+}
@@ -7,8 +7,10 @@ import (
 	"github.com/google/uuid"
 	"github.com/keptn/go-utils/pkg/api/models"
 	"github.com/keptn/keptn/cp-connector/pkg/logger"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
 	"github.com/nats-io/nats.go"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -28,6 +30,7 @@ type NATS interface {
 	Publish(event models.KeptnContextExtendedCE) error
 	Disconnect() error
 	UnsubscribeAll() error
+	OnConnectionStateChange(fn ConnectionStateFn)
 }
 
 var (
@@ -40,6 +43,9 @@ var (
 // ProcessEventFn is used to process a received keptn event
 type ProcessEventFn func(msg *nats.Msg) error
 
+// ConnectionStateFn is called whenever the connection to NATS is lost or re-established
+type ConnectionStateFn func(state types.ConnectionState)
+
 // NatsConnector can be used to subscribe to certain events
 // on the NATS event system
 type NatsConnector struct {
@@ -47,6 +53,8 @@ type NatsConnector struct {
 	connectURL    string
 	subscriptions map[string]*nats.Subscription
 	logger        logger.Logger
+	stateFn       ConnectionStateFn
+	stateMtx      sync.RWMutex
 }
 
 // WithLogger sets the logger to use
@@ -89,7 +97,17 @@ func (nc *NatsConnector) ensureConnection() (*nats.Conn, error) {
 
 	if !nc.connection.IsConnected() {
 		var err error
-		nc.connection, err = nats.Connect(nc.connectURL, nats.MaxReconnects(-1))
+		nc.connection, err = nats.Connect(nc.connectURL,
+			nats.MaxReconnects(-1),
+			nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+				nc.logger.Warnf("Lost connection to NATS: %v", err)
+				nc.notifyConnectionState(types.ConnectionStateDisconnected)
+			}),
+			nats.ReconnectHandler(func(_ *nats.Conn) {
+				nc.logger.Info("Reconnected to NATS")
+				nc.notifyConnectionState(types.ConnectionStateConnected)
+			}),
+		)
 
 		if err != nil {
 			return nil, fmt.Errorf("could not connect to NATS: %w", err)
@@ -99,6 +117,23 @@ func (nc *NatsConnector) ensureConnection() (*nats.Conn, error) {
 	return nc.connection, nil
 }
 
+// OnConnectionStateChange sets the function that is called whenever the connection to NATS
+// is lost or re-established. Passing nil removes a previously set function
+func (nc *NatsConnector) OnConnectionStateChange(fn ConnectionStateFn) {
+	nc.stateMtx.Lock()
+	defer nc.stateMtx.Unlock()
+	nc.stateFn = fn
+}
+
+func (nc *NatsConnector) notifyConnectionState(state types.ConnectionState) {
+	nc.stateMtx.RLock()
+	fn := nc.stateFn
+	nc.stateMtx.RUnlock()
+	if fn != nil {
+		fn(state)
+	}
+}
+
 // UnsubscribeAll deletes all current subscriptions
 func (nc *NatsConnector) UnsubscribeAll() error {
 	for _, s := range nc.subscriptions {
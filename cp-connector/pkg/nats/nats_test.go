@@ -6,10 +6,12 @@ import (
 	"github.com/keptn/go-utils/pkg/common/strutils"
 	"github.com/keptn/go-utils/pkg/lib/v0_2_0"
 	nats2 "github.com/keptn/keptn/cp-connector/pkg/nats"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
 	"github.com/nats-io/nats-server/v2/server"
 	natstest "github.com/nats-io/nats-server/v2/test"
 	"github.com/nats-io/nats.go"
 	"github.com/stretchr/testify/require"
+	"net"
 	"os"
 	"testing"
 	"time"
@@ -250,6 +252,31 @@ func TestPublishEventMissingType(t *testing.T) {
 
 }
 
+func TestConnectionStateChanges(t *testing.T) {
+	svr, shutdown := runNATSServer()
+	defer shutdown()
+	nc := nats2.New(svr.ClientURL())
+	states := make(chan types.ConnectionState, 2)
+	nc.OnConnectionStateChange(func(state types.ConnectionState) { states <- state })
+	require.NoError(t, nc.Subscribe("subj", func(msg *nats.Msg) error { return nil }))
+
+	opts := natstest.DefaultTestOptions
+	opts.Port = svr.Addr().(*net.TCPAddr).Port
+	svr.Shutdown()
+	require.Equal(t, types.ConnectionStateDisconnected, <-states)
+
+	restarted := natstest.RunServer(&opts)
+	defer restarted.Shutdown()
+	select {
+	case state := <-states:
+		require.Equal(t, types.ConnectionStateConnected, state)
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "did not reconnect to NATS")
+	}
+	nc.OnConnectionStateChange(nil)
+	require.NoError(t, nc.Disconnect())
+}
+
 func runNATSServer() (*server.Server, func()) {
 	svr := natstest.RunRandClientPortServer()
 	return svr, func() { svr.Shutdown() }
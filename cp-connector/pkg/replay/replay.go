@@ -0,0 +1,111 @@
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/eventsource"
+	"github.com/keptn/keptn/cp-connector/pkg/logger"
+)
+
+// maxLineSize is the maximum size of a single recorded event
+const maxLineSize = 10 * 1024 * 1024
+
+// Replayer feeds recorded events to a ControlPlane via a MemoryEventSource.
+// This turns captured traffic into a deterministic test, e.g. to reproduce production incidents
+type Replayer struct {
+	source         *eventsource.MemoryEventSource
+	preserveTiming bool
+	logger         logger.Logger
+}
+
+// WithTiming configures the Replayer to preserve the relative timing of the recorded events,
+// based on the time of the events
+func WithTiming(preserve bool) func(*Replayer) {
+	return func(r *Replayer) {
+		r.preserveTiming = preserve
+	}
+}
+
+// WithLogger sets the logger to use
+func WithLogger(logger logger.Logger) func(*Replayer) {
+	return func(r *Replayer) {
+		r.logger = logger
+	}
+}
+
+// New creates a new Replayer pushing the recorded events to the given event source
+func New(source *eventsource.MemoryEventSource, opts ...func(*Replayer)) *Replayer {
+	r := &Replayer{
+		source: source,
+		logger: logger.NewDefaultLogger(),
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// ReplayFile replays the events recorded in the file at the given path
+func (r *Replayer) ReplayFile(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open recording: %w", err)
+	}
+	defer f.Close()
+	return r.Replay(ctx, f)
+}
+
+// Replay reads newline-delimited JSON cloudevents from the given reader and pushes them to the event source
+// in the order they were recorded. Empty lines are skipped.
+// Replay returns as soon as the last event was received by the ControlPlane
+func (r *Replayer) Replay(ctx context.Context, recording io.Reader) error {
+	scanner := bufio.NewScanner(recording)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	var previous time.Time
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		event := models.KeptnContextExtendedCE{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("could not decode recorded event in line %d: %w", line, err)
+		}
+		if r.preserveTiming {
+			if err := wait(ctx, previous, event.Time); err != nil {
+				return err
+			}
+			previous = event.Time
+		}
+		r.logger.Debugf("Replaying event %s", event.ID)
+		if err := r.source.Push(ctx, event); err != nil {
+			return fmt.Errorf("could not replay event in line %d: %w", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read recording: %w", err)
+	}
+	return nil
+}
+
+// wait waits for the time that passed between the previous and the current event
+func wait(ctx context.Context, previous time.Time, current time.Time) error {
+	if previous.IsZero() || !current.After(previous) {
+		return nil
+	}
+	timer := time.NewTimer(current.Sub(previous))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,97 @@
+package replay
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/controlplane"
+	"github.com/keptn/keptn/cp-connector/pkg/eventsource"
+	"github.com/keptn/keptn/cp-connector/pkg/subscriptionsource"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+const recording = `{"id":"event-1","type":"sh.keptn.event.deployment.triggered","shkeptncontext":"ctx-1","time":"2022-06-01T12:00:00.000Z","data":{}}
+{"id":"event-2","type":"sh.keptn.event.test.triggered","shkeptncontext":"ctx-1","time":"2022-06-01T12:00:00.100Z","data":{}}
+
+{"id":"event-3","type":"sh.keptn.event.deployment.triggered","shkeptncontext":"ctx-2","time":"2022-06-01T12:00:00.200Z","data":{}}
+`
+
+type recordingIntegration struct {
+	mtx      sync.Mutex
+	received []string
+}
+
+func (r *recordingIntegration) OnEvent(ctx context.Context, event models.KeptnContextExtendedCE) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.received = append(r.received, event.ID)
+	return nil
+}
+
+func (r *recordingIntegration) RegistrationData() types.RegistrationData {
+	return types.RegistrationData{Name: "replay"}
+}
+
+func (r *recordingIntegration) events() []string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]string{}, r.received...)
+}
+
+func startControlPlane(t *testing.T) (*eventsource.MemoryEventSource, *recordingIntegration) {
+	source := eventsource.NewMemoryEventSource()
+	subscriptions := subscriptionsource.NewFixedSubscriptionSource(subscriptionsource.WithFixedSubscriptions(
+		models.EventSubscription{ID: "sub-1", Event: "sh.keptn.event.deployment.triggered"},
+		models.EventSubscription{ID: "sub-2", Event: "sh.keptn.event.test.triggered"},
+	))
+	integration := &recordingIntegration{}
+	ctx, cancel := context.WithCancel(context.TODO())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = controlplane.New(subscriptions, source, nil).Register(ctx, integration)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return source, integration
+}
+
+func TestReplayFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(recording), 0600))
+	source, integration := startControlPlane(t)
+
+	require.NoError(t, New(source).ReplayFile(context.TODO(), path))
+	require.Eventually(t, func() bool { return len(integration.events()) == 3 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"event-1", "event-2", "event-3"}, integration.events())
+}
+
+func TestReplayPreservesTiming(t *testing.T) {
+	source, integration := startControlPlane(t)
+
+	start := time.Now()
+	require.NoError(t, New(source, WithTiming(true)).Replay(context.TODO(), strings.NewReader(recording)))
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	require.Eventually(t, func() bool { return len(integration.events()) == 3 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"event-1", "event-2", "event-3"}, integration.events())
+}
+
+func TestReplayInvalidRecording(t *testing.T) {
+	source, _ := startControlPlane(t)
+	err := New(source).Replay(context.TODO(), strings.NewReader("{\"id\":\"event-1\"}\nnot json\n"))
+	require.ErrorContains(t, err, "line 2")
+}
+
+func TestReplayMissingFile(t *testing.T) {
+	err := New(eventsource.NewMemoryEventSource()).ReplayFile(context.TODO(), filepath.Join(t.TempDir(), "missing.jsonl"))
+	require.Error(t, err)
+}
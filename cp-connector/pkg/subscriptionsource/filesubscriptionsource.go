@@ -0,0 +1,151 @@
+package subscriptionsource
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/logger"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+var _ SubscriptionSource = (*FileSubscriptionSource)(nil)
+var _ types.ResourceReporter = (*FileSubscriptionSource)(nil)
+
+// FileSubscriptionSource reads the subscriptions from a YAML or JSON file containing a list of subscriptions
+// instead of consulting the Keptn API. The file is watched for changes and an update is sent
+// every time the file content results in a different list of subscriptions.
+// Like the FixedSubscriptionSource, it does NOT register the integration to the control plane
+type FileSubscriptionSource struct {
+	path   string
+	logger logger.Logger
+	// activeRoutines is the number of goroutines currently started by the subscription source
+	activeRoutines int32
+}
+
+// WithFileSourceLogger sets the logger to use
+func WithFileSourceLogger(logger logger.Logger) func(s *FileSubscriptionSource) {
+	return func(s *FileSubscriptionSource) {
+		s.logger = logger
+	}
+}
+
+// NewFileSubscriptionSource creates a new FileSubscriptionSource reading the subscriptions from the given file
+func NewFileSubscriptionSource(path string, options ...func(source *FileSubscriptionSource)) *FileSubscriptionSource {
+	s := &FileSubscriptionSource{path: filepath.Clean(path), logger: logger.NewDefaultLogger()}
+	for _, o := range options {
+		o(s)
+	}
+	return s
+}
+
+// Start reads the subscriptions from the file and starts watching the file for changes.
+// An error is returned if the file cannot be read initially. Errors while reading a changed file are reported
+// on the error channel and the previously read subscriptions are kept
+func (s *FileSubscriptionSource) Start(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, errC chan error) (<-chan struct{}, error) {
+	subscriptions, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("could not create file watcher: %w", err)
+	}
+	// the directory is watched, as editors and config map mounts usually replace the file instead of writing to it
+	if err := watcher.Add(filepath.Dir(s.path)); err != nil {
+		_ = watcher.Close()
+		return nil, fmt.Errorf("could not watch subscription file %s: %w", s.path, err)
+	}
+
+	done := make(chan struct{})
+	atomic.AddInt32(&s.activeRoutines, 1)
+	go func() {
+		defer close(done)
+		defer atomic.AddInt32(&s.activeRoutines, -1)
+		defer watcher.Close()
+		if !s.send(ctx, c, subscriptions) {
+			return
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != s.path || event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				updated, err := s.read()
+				if err != nil {
+					s.logger.Warnf("FileSubscriptionSource: Keeping previous subscriptions: %v", err)
+					reportError(ctx, errC, err)
+					continue
+				}
+				if reflect.DeepEqual(updated, subscriptions) {
+					continue
+				}
+				subscriptions = updated
+				s.logger.Debugf("FileSubscriptionSource: Read %d subscriptions from %s", len(subscriptions), s.path)
+				if !s.send(ctx, c, subscriptions) {
+					return
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				s.logger.Errorf("FileSubscriptionSource: Error while watching %s: %v", s.path, err)
+				reportError(ctx, errC, fmt.Errorf("could not watch subscription file %s: %w", s.path, err))
+			}
+		}
+	}()
+	return done, nil
+}
+
+// Register does not register anything and returns an empty integration ID
+func (s *FileSubscriptionSource) Register(integration models.Integration) (string, error) {
+	return "", nil
+}
+
+// Unregister does nothing, as the FileSubscriptionSource does not register the integration
+func (s *FileSubscriptionSource) Unregister(integrationID string) error {
+	return nil
+}
+
+// Fetch does nothing, as changes of the file are sent as soon as they are written
+func (s *FileSubscriptionSource) Fetch(ctx context.Context) error {
+	return nil
+}
+
+// ActiveResources returns the number of goroutines currently owned by the subscription source
+func (s *FileSubscriptionSource) ActiveResources() int {
+	return int(atomic.LoadInt32(&s.activeRoutines))
+}
+
+func (s *FileSubscriptionSource) read() ([]models.EventSubscription, error) {
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read subscription file %s: %w", s.path, err)
+	}
+	subscriptions := []models.EventSubscription{}
+	if err := yaml.Unmarshal(content, &subscriptions); err != nil {
+		return nil, fmt.Errorf("could not parse subscription file %s: %w", s.path, err)
+	}
+	return subscriptions, nil
+}
+
+func (s *FileSubscriptionSource) send(ctx context.Context, c chan []models.EventSubscription, subscriptions []models.EventSubscription) bool {
+	select {
+	case c <- subscriptions:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,113 @@
+package subscriptionsource
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func writeSubscriptionFile(t *testing.T, path string, content string) {
+	require.Nil(t, os.WriteFile(path, []byte(content), 0600))
+}
+
+// waitForSubscriptions receives subscription updates until the expected subscriptions are received.
+// Writing a file is not atomic, so intermediate updates (e.g. of the truncated file) are skipped
+func waitForSubscriptions(t *testing.T, c chan []models.EventSubscription, expected []models.EventSubscription) {
+	timeout := time.After(5 * time.Second)
+	var received []models.EventSubscription
+	for {
+		select {
+		case received = <-c:
+			if reflect.DeepEqual(expected, received) {
+				return
+			}
+		case <-timeout:
+			require.FailNow(t, "did not receive expected subscriptions", "last received: %v", received)
+		}
+	}
+}
+
+func TestFileSubscriptionSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscriptions.yaml")
+	writeSubscriptionFile(t, path, `
+- id: sub-1
+  event: sh.keptn.event.echo.triggered
+  filter:
+    projects: [my-project]
+`)
+	fss := NewFileSubscriptionSource(path)
+	subscriptionUpdates := make(chan []models.EventSubscription)
+	ctx, cancel := context.WithCancel(context.TODO())
+	done, err := fss.Start(ctx, types.RegistrationData{}, subscriptionUpdates, nil)
+	require.Nil(t, err)
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	require.Equal(t, []models.EventSubscription{
+		{ID: "sub-1", Event: "sh.keptn.event.echo.triggered", Filter: models.EventSubscriptionFilter{Projects: []string{"my-project"}}},
+	}, <-subscriptionUpdates)
+
+	writeSubscriptionFile(t, path, `[{"id": "sub-2", "event": "sh.keptn.event.echo.finished"}, {"id": "sub-3", "event": "sh.keptn.event.test.triggered"}]`)
+	waitForSubscriptions(t, subscriptionUpdates, []models.EventSubscription{
+		{ID: "sub-2", Event: "sh.keptn.event.echo.finished"},
+		{ID: "sub-3", Event: "sh.keptn.event.test.triggered"},
+	})
+}
+
+func TestFileSubscriptionSourceKeepsSubscriptionsOnInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscriptions.yaml")
+	writeSubscriptionFile(t, path, `[{"id": "sub-1", "event": "sh.keptn.event.echo.triggered"}]`)
+	fss := NewFileSubscriptionSource(path)
+	subscriptionUpdates := make(chan []models.EventSubscription)
+	ctx, cancel := context.WithCancel(context.TODO())
+	done, err := fss.Start(ctx, types.RegistrationData{}, subscriptionUpdates, nil)
+	require.Nil(t, err)
+	defer func() {
+		cancel()
+		<-done
+	}()
+	require.Len(t, <-subscriptionUpdates, 1)
+
+	writeSubscriptionFile(t, path, `{not a list`)
+	writeSubscriptionFile(t, path, `[{"id": "sub-2", "event": "sh.keptn.event.echo.triggered"}]`)
+	waitForSubscriptions(t, subscriptionUpdates, []models.EventSubscription{{ID: "sub-2", Event: "sh.keptn.event.echo.triggered"}})
+}
+
+func TestFileSubscriptionSourceMissingFile(t *testing.T) {
+	fss := NewFileSubscriptionSource(filepath.Join(t.TempDir(), "missing.yaml"))
+	done, err := fss.Start(context.TODO(), types.RegistrationData{}, make(chan []models.EventSubscription), nil)
+	require.NotNil(t, err)
+	require.Nil(t, done)
+}
+
+func TestFileSubscriptionSourceRegister(t *testing.T) {
+	id, err := NewFileSubscriptionSource("subscriptions.yaml").Register(models.Integration{})
+	require.Nil(t, err)
+	require.Empty(t, id)
+}
+
+func TestFileSubscriptionSourceStopsWithoutGoroutineLeaks(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+	path := filepath.Join(t.TempDir(), "subscriptions.yaml")
+	writeSubscriptionFile(t, path, `[]`)
+	fss := NewFileSubscriptionSource(path)
+
+	// nobody is receiving from the subscription channel, so the source blocks until it is cancelled
+	ctx, cancel := context.WithCancel(context.TODO())
+	done, err := fss.Start(ctx, types.RegistrationData{}, make(chan []models.EventSubscription), nil)
+	require.Nil(t, err)
+	require.Equal(t, 1, fss.ActiveResources())
+	cancel()
+	<-done
+	require.Equal(t, 0, fss.ActiveResources())
+}
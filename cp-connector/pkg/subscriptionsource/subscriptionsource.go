@@ -2,8 +2,13 @@ package subscriptionsource
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/keptn/keptn/cp-connector/pkg/types"
+	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/benbjohnson/clock"
@@ -12,30 +17,171 @@ import (
 	"github.com/keptn/keptn/cp-connector/pkg/logger"
 )
 
+// SubscriptionSource is anything that can be used
+// to get the current subscriptions of an integration
 type SubscriptionSource interface {
-	Start(context.Context, types.RegistrationData, chan []models.EventSubscription, *sync.WaitGroup) error
+	// Start triggers the execution of the SubscriptionSource.
+	// Errors occurring while fetching the subscriptions are reported on the given error channel, if it is not nil.
+	// Once the given context is cancelled, the SubscriptionSource stops sending on the given channels and
+	// closes the returned channel as soon as all of its goroutines have stopped
+	Start(context.Context, types.RegistrationData, chan []models.EventSubscription, chan error) (<-chan struct{}, error)
+	// Register registers the integration and returns the assigned integration ID
 	Register(integration models.Integration) (string, error)
+	// Unregister removes the registration of the integration with the given integration ID
+	Unregister(integrationID string) error
+	// Fetch fetches the current subscriptions right away, instead of waiting for the next regular update, and sends
+	// them on the channel given to Start. It returns once the subscriptions were sent
+	Fetch(ctx context.Context) error
 }
 
+// defaultAPITimeout is the time the uniform API is given to answer a registration or ping request if no
+// timeout is configured via WithRegistrationTimeout
+const defaultAPITimeout = 30 * time.Second
+
+// ErrAPITimeout is returned if the uniform API did not answer a request within the configured timeout
+var ErrAPITimeout = errors.New("uniform API did not respond in time")
+
+// ErrNotStarted is returned by Fetch if the subscription source is not started
+var ErrNotStarted = errors.New("subscription source is not started")
+
+// ErrEmptyIntegrationID is returned by Register if the uniform API did not assign an integration ID
+var ErrEmptyIntegrationID = errors.New("uniform API returned an empty integration ID")
+
+// ErrAlreadyRegistered is matched by the AlreadyRegisteredError returned by Register if an identical integration
+// is already registered
+var ErrAlreadyRegistered = errors.New("integration is already registered")
+
+// AlreadyRegisteredError is returned by Register if the uniform API reports a conflict with an identical integration
+// that is already registered and the ConflictPolicy is ConflictPolicyFail
+type AlreadyRegisteredError struct {
+	// IntegrationID is the ID of the existing registration
+	IntegrationID string
+	// Err is the error reported by the uniform API
+	Err error
+}
+
+func (e *AlreadyRegisteredError) Error() string {
+	return fmt.Sprintf("integration is already registered with ID %s: %v", e.IntegrationID, e.Err)
+}
+
+func (e *AlreadyRegisteredError) Unwrap() error {
+	return e.Err
+}
+
+func (e *AlreadyRegisteredError) Is(target error) bool {
+	return target == ErrAlreadyRegistered
+}
+
+// ConflictPolicy decides how Register handles a conflict reported by the uniform API because an identical
+// integration is already registered
+type ConflictPolicy int
+
+const (
+	// ConflictPolicyFail makes Register fail with an AlreadyRegisteredError carrying the ID of the existing registration
+	ConflictPolicyFail ConflictPolicy = iota
+	// ConflictPolicyAdopt makes Register return the ID of the existing registration, as if it was just registered
+	ConflictPolicyAdopt
+)
+
 var _ SubscriptionSource = FixedSubscriptionSource{}
 var _ SubscriptionSource = (*UniformSubscriptionSource)(nil)
+var _ types.ResourceReporter = (*UniformSubscriptionSource)(nil)
 
 // UniformSubscriptionSource represents a source for uniform subscriptions
 type UniformSubscriptionSource struct {
-	uniformAPI    api.UniformV1Interface
-	clock         clock.Clock
-	fetchInterval time.Duration
-	logger        logger.Logger
+	uniformAPI     api.UniformV1Interface
+	clock          clock.Clock
+	fetchInterval  time.Duration
+	apiTimeout     time.Duration
+	conflictPolicy ConflictPolicy
+	logger         logger.Logger
+	// activeRoutines is the number of goroutines currently started by the subscription source
+	activeRoutines int32
+	mtx            sync.Mutex
+	// fetchRequests passes the replies of Fetch calls to the goroutine started by Start. It is nil if not started
+	fetchRequests chan chan error
+	stopped       <-chan struct{}
 }
 
 func (s *UniformSubscriptionSource) Register(integration models.Integration) (string, error) {
-	integrationID, err := s.uniformAPI.RegisterIntegration(integration)
+	integrationID, err := callWithTimeout(context.Background(), s.clock, s.apiTimeout, func() (string, error) {
+		return s.uniformAPI.RegisterIntegration(integration)
+	})
+	if isConflict(err) {
+		return s.resolveConflict(integration, err)
+	}
 	if err != nil {
 		return "", err
 	}
+	// without an ID neither the subscriptions can be fetched nor the registration can be removed again
+	if integrationID == "" {
+		return "", ErrEmptyIntegrationID
+	}
 	return integrationID, nil
 }
 
+// isConflict checks whether the registration failed because an identical integration is already registered, which the
+// uniform API reports with the status code 409 (Conflict). As the uniform API client of go-utils only passes on the
+// message of error responses, the message is searched for a conflict if the error does not report a status code
+func isConflict(err error) bool {
+	if err == nil || errors.Is(err, ErrAPITimeout) {
+		return false
+	}
+	if code, ok := statusCode(err); ok {
+		return code == http.StatusConflict
+	}
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "already exists") || strings.Contains(message, "already registered") || strings.Contains(message, "conflict")
+}
+
+// statusCode returns the HTTP status code of the response the uniform API failed with, if the error reports it.
+// Errors report it either by implementing StatusCode() int, e.g. errors of custom uniform API clients, or by the
+// message go-utils uses for error responses without body
+func statusCode(err error) (int, bool) {
+	var coder interface{ StatusCode() int }
+	if errors.As(err, &coder) {
+		return coder.StatusCode(), true
+	}
+	var code int
+	if _, scanErr := fmt.Sscanf(err.Error(), api.ErrWithStatusCode, &code); scanErr == nil {
+		return code, true
+	}
+	return 0, false
+}
+
+// resolveConflict looks up the existing registration of the integration and handles it according to the ConflictPolicy.
+// If the existing registration cannot be found, the error reported by the uniform API is returned
+func (s *UniformSubscriptionSource) resolveConflict(integration models.Integration, conflictErr error) (string, error) {
+	registrations, err := callWithTimeout(context.Background(), s.clock, s.apiTimeout, func() ([]*models.Integration, error) {
+		return s.uniformAPI.GetRegistrations()
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w (could not look up the existing registration: %v)", conflictErr, err)
+	}
+	for _, registration := range registrations {
+		if registration == nil || registration.ID == "" || !sameIntegration(*registration, integration) {
+			continue
+		}
+		if s.conflictPolicy == ConflictPolicyAdopt {
+			s.logger.Infof("Integration %s is already registered, adopting Integration ID %s", integration.Name, registration.ID)
+			return registration.ID, nil
+		}
+		return "", &AlreadyRegisteredError{IntegrationID: registration.ID, Err: conflictErr}
+	}
+	return "", conflictErr
+}
+
+// sameIntegration checks whether both integrations are identified by the same name, namespace and node
+func sameIntegration(a, b models.Integration) bool {
+	return a.Name == b.Name &&
+		a.MetaData.KubernetesMetaData.Namespace == b.MetaData.KubernetesMetaData.Namespace &&
+		a.MetaData.Hostname == b.MetaData.Hostname
+}
+
+func (s *UniformSubscriptionSource) Unregister(integrationID string) error {
+	return s.uniformAPI.UnregisterIntegration(integrationID)
+}
+
 // WithFetchInterval specifies the interval the subscription source should
 // use when polling for new subscriptions
 func WithFetchInterval(interval time.Duration) func(s *UniformSubscriptionSource) {
@@ -44,6 +190,23 @@ func WithFetchInterval(interval time.Duration) func(s *UniformSubscriptionSource
 	}
 }
 
+// WithRegistrationTimeout specifies the time the uniform API is given to answer the registration of the
+// integration and the periodic pings renewing the registration. A timeout <= 0 disables the timeout.
+// By default, the timeout is 30 seconds
+func WithRegistrationTimeout(timeout time.Duration) func(s *UniformSubscriptionSource) {
+	return func(s *UniformSubscriptionSource) {
+		s.apiTimeout = timeout
+	}
+}
+
+// WithConflictPolicy sets how Register handles a conflict reported by the uniform API because an identical integration
+// is already registered. By default, Register fails with an AlreadyRegisteredError
+func WithConflictPolicy(policy ConflictPolicy) func(s *UniformSubscriptionSource) {
+	return func(s *UniformSubscriptionSource) {
+		s.conflictPolicy = policy
+	}
+}
+
 // WithLogger sets the logger to use
 func WithLogger(logger logger.Logger) func(s *UniformSubscriptionSource) {
 	return func(s *UniformSubscriptionSource) {
@@ -51,9 +214,20 @@ func WithLogger(logger logger.Logger) func(s *UniformSubscriptionSource) {
 	}
 }
 
+// NewUniformAPI creates a client for the uniform API of the Keptn control plane at the given base URL
+// that sends all requests using the given HTTP client, e.g. to route them through a proxy or to use custom timeouts.
+// Further options (e.g. the auth token) are applied to the underlying api.APISet
+func NewUniformAPI(baseURL string, httpClient *http.Client, options ...func(*api.APISet)) (api.UniformV1Interface, error) {
+	apiSet, err := api.New(baseURL, append([]func(*api.APISet){api.WithHTTPClient(httpClient)}, options...)...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create uniform API: %w", err)
+	}
+	return apiSet.UniformV1(), nil
+}
+
 // New creates a new UniformSubscriptionSource
 func New(uniformAPI api.UniformV1Interface, options ...func(source *UniformSubscriptionSource)) *UniformSubscriptionSource {
-	s := &UniformSubscriptionSource{uniformAPI: uniformAPI, clock: clock.New(), fetchInterval: time.Second * 5, logger: logger.NewDefaultLogger()}
+	s := &UniformSubscriptionSource{uniformAPI: uniformAPI, clock: clock.New(), fetchInterval: time.Second * 5, apiTimeout: defaultAPITimeout, logger: logger.NewDefaultLogger()}
 	for _, o := range options {
 		o(s)
 	}
@@ -61,33 +235,86 @@ func New(uniformAPI api.UniformV1Interface, options ...func(source *UniformSubsc
 }
 
 // Start triggers the execution of the UniformSubscriptionSource
-func (s *UniformSubscriptionSource) Start(ctx context.Context, registrationData types.RegistrationData, subscriptionChannel chan []models.EventSubscription, wg *sync.WaitGroup) error {
+func (s *UniformSubscriptionSource) Start(ctx context.Context, registrationData types.RegistrationData, subscriptionChannel chan []models.EventSubscription, errC chan error) (<-chan struct{}, error) {
 	s.logger.Debugf("UniformSubscriptionSource: Starting to fetch subscriptions for Integration ID %s", registrationData.ID)
 	ticker := s.clock.Ticker(s.fetchInterval)
+	done := make(chan struct{})
+	fetchRequests := make(chan chan error)
+	s.mtx.Lock()
+	s.fetchRequests, s.stopped = fetchRequests, done
+	s.mtx.Unlock()
+	atomic.AddInt32(&s.activeRoutines, 1)
 	go func() {
-		s.ping(registrationData.ID, subscriptionChannel)
+		defer close(done)
+		defer atomic.AddInt32(&s.activeRoutines, -1)
+		defer ticker.Stop()
+		s.ping(ctx, registrationData.ID, subscriptionChannel, errC)
 		for {
 			select {
 			case <-ctx.Done():
-				wg.Done()
 				return
 			case <-ticker.C:
-				s.ping(registrationData.ID, subscriptionChannel)
+				s.ping(ctx, registrationData.ID, subscriptionChannel, errC)
+			case reply := <-fetchRequests:
+				reply <- s.ping(ctx, registrationData.ID, subscriptionChannel, errC)
 			}
 		}
 	}()
-	return nil
+	return done, nil
 }
 
-func (s *UniformSubscriptionSource) ping(registrationId string, subscriptionChannel chan []models.EventSubscription) {
+// Fetch pings the control plane right away and sends the received subscriptions on the channel given to Start
+func (s *UniformSubscriptionSource) Fetch(ctx context.Context) error {
+	s.mtx.Lock()
+	fetchRequests, stopped := s.fetchRequests, s.stopped
+	s.mtx.Unlock()
+	if fetchRequests == nil {
+		return ErrNotStarted
+	}
+	reply := make(chan error, 1)
+	select {
+	case fetchRequests <- reply:
+	case <-stopped:
+		return ErrNotStarted
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ActiveResources returns the number of goroutines currently owned by the subscription source
+func (s *UniformSubscriptionSource) ActiveResources() int {
+	return int(atomic.LoadInt32(&s.activeRoutines))
+}
+
+// ping renews the registration and sends the received subscriptions. Errors are reported on the error channel
+// and returned, e.g. to the caller of Fetch
+func (s *UniformSubscriptionSource) ping(ctx context.Context, registrationId string, subscriptionChannel chan []models.EventSubscription, errC chan error) error {
 	s.logger.Debugf("UniformSubscriptionSource: Renewing Integration ID %s", registrationId)
-	updatedIntegrationData, err := s.uniformAPI.Ping(registrationId)
+	updatedIntegrationData, err := callWithTimeout(ctx, s.clock, s.apiTimeout, func() (*models.Integration, error) {
+		return s.uniformAPI.Ping(registrationId)
+	})
 	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		s.logger.Errorf("Unable to ping control plane: %v", err)
-		return
+		err = fmt.Errorf("unable to ping control plane: %w", err)
+		reportError(ctx, errC, err)
+		return err
 	}
 	s.logger.Debugf("UniformSubscriptionSource: Ping successful, got %d subscriptions for %s", len(updatedIntegrationData.Subscriptions), registrationId)
-	subscriptionChannel <- updatedIntegrationData.Subscriptions
+	select {
+	case subscriptionChannel <- updatedIntegrationData.Subscriptions:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // FixedSubscriptionSource can be used to use a fixed list of subscriptions rather than
@@ -114,15 +341,69 @@ func NewFixedSubscriptionSource(options ...func(source *FixedSubscriptionSource)
 	return fss
 }
 
-func (s FixedSubscriptionSource) Start(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, wg *sync.WaitGroup) error {
+func (s FixedSubscriptionSource) Start(ctx context.Context, data types.RegistrationData, c chan []models.EventSubscription, errC chan error) (<-chan struct{}, error) {
+	done := make(chan struct{})
 	go func() {
-		c <- s.fixedSubscriptions
+		defer close(done)
+		select {
+		case c <- s.fixedSubscriptions:
+		case <-ctx.Done():
+			return
+		}
 		<-ctx.Done()
-		wg.Done()
 	}()
-	return nil
+	return done, nil
 }
 
 func (s FixedSubscriptionSource) Register(integration models.Integration) (string, error) {
 	return "", nil
 }
+
+func (s FixedSubscriptionSource) Unregister(integrationID string) error {
+	return nil
+}
+
+// Fetch does nothing, as the fixed subscriptions never change
+func (s FixedSubscriptionSource) Fetch(ctx context.Context) error {
+	return nil
+}
+
+// callWithTimeout returns the result of the given call, or ErrAPITimeout if the call did not return within the given
+// timeout. As the uniform API cannot be cancelled, a timed out call keeps running in the background until it returns
+func callWithTimeout[T any](ctx context.Context, clock clock.Clock, timeout time.Duration, call func() (T, error)) (T, error) {
+	if timeout <= 0 {
+		return call()
+	}
+	type result struct {
+		value T
+		err   error
+	}
+	// buffered, so that a timed out call does not block forever
+	results := make(chan result, 1)
+	go func() {
+		value, err := call()
+		results <- result{value: value, err: err}
+	}()
+	timer := clock.Timer(timeout)
+	defer timer.Stop()
+	var zero T
+	select {
+	case r := <-results:
+		return r.value, r.err
+	case <-timer.C:
+		return zero, fmt.Errorf("no response within %s: %w", timeout, ErrAPITimeout)
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// reportError sends the error on the given error channel unless the channel is nil or the context is cancelled
+func reportError(ctx context.Context, errC chan error, err error) {
+	if errC == nil {
+		return
+	}
+	select {
+	case errC <- err:
+	case <-ctx.Done():
+	}
+}
@@ -2,16 +2,22 @@ package subscriptionsource
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/keptn/keptn/cp-connector/pkg/fake"
 	"github.com/keptn/keptn/cp-connector/pkg/types"
-	"sync"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/benbjohnson/clock"
 	"github.com/keptn/go-utils/pkg/api/models"
+	api "github.com/keptn/go-utils/pkg/api/utils"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
 )
 
 func TestSubscriptionSourceCPPingFails(t *testing.T) {
@@ -30,13 +36,31 @@ func TestSubscriptionSourceCPPingFails(t *testing.T) {
 	subscriptionSource := New(uniformInterface)
 	clock := clock.NewMock()
 	subscriptionSource.clock = clock
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	err := subscriptionSource.Start(context.TODO(), initialRegistrationData, subscriptionUpdates, wg)
+	_, err := subscriptionSource.Start(context.TODO(), initialRegistrationData, subscriptionUpdates, nil)
 	require.NoError(t, err)
 	clock.Add(5 * time.Second)
 }
 
+func TestSubscriptionSourceReportsPingErrors(t *testing.T) {
+	uniformInterface := &fake.UniformAPIMock{
+		PingFn: func(s string) (*models.Integration, error) {
+			return nil, fmt.Errorf("error occured")
+		}}
+	subscriptionSource := New(uniformInterface)
+	clock := clock.NewMock()
+	subscriptionSource.clock = clock
+	errC := make(chan error)
+	ctx, cancel := context.WithCancel(context.TODO())
+	done, err := subscriptionSource.Start(ctx, types.RegistrationData{ID: "iID"}, make(chan []models.EventSubscription), errC)
+	require.NoError(t, err)
+	require.ErrorContains(t, <-errC, "error occured")
+	// the source keeps fetching after an error
+	clock.Add(5 * time.Second)
+	require.ErrorContains(t, <-errC, "error occured")
+	cancel()
+	<-done
+}
+
 func TestSubscriptionSourceWithFetchInterval(t *testing.T) {
 	integrationID := "iID"
 	integrationName := "integrationName"
@@ -67,10 +91,8 @@ func TestSubscriptionSourceWithFetchInterval(t *testing.T) {
 	subscriptionSource.clock = clock
 
 	subscriptionUpdates := make(chan []models.EventSubscription)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	err := subscriptionSource.Start(context.TODO(), initialRegistrationData, subscriptionUpdates, wg)
+	_, err := subscriptionSource.Start(context.TODO(), initialRegistrationData, subscriptionUpdates, nil)
 	require.NoError(t, err)
 	for i := 0; i < 100; i++ {
 		clock.Add(10 * time.Second)
@@ -117,9 +139,7 @@ func TestSubscriptionSourceCancel(t *testing.T) {
 	}()
 
 	ctx, cancel := context.WithCancel(context.TODO())
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	err := subscriptionSource.Start(ctx, initialRegistrationData, subscriptionUpdates, wg)
+	done, err := subscriptionSource.Start(ctx, initialRegistrationData, subscriptionUpdates, nil)
 	require.Eventually(t, func() bool { return pingCount == 1 }, 3*time.Second, time.Millisecond*100)
 	require.NoError(t, err)
 	clock.Add(10 * time.Second)
@@ -127,7 +147,7 @@ func TestSubscriptionSourceCancel(t *testing.T) {
 	cancel()
 	clock.Add(10 * time.Second)
 	require.Equal(t, 2, pingCount)
-	wg.Wait()
+	<-done
 }
 
 func TestSubscriptionSource(t *testing.T) {
@@ -159,10 +179,8 @@ func TestSubscriptionSource(t *testing.T) {
 	subscriptionSource.clock = clock
 
 	subscriptionUpdates := make(chan []models.EventSubscription)
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
 
-	err := subscriptionSource.Start(context.TODO(), initialRegistrationData, subscriptionUpdates, wg)
+	_, err := subscriptionSource.Start(context.TODO(), initialRegistrationData, subscriptionUpdates, nil)
 	require.NoError(t, err)
 	clock.Add(5 * time.Second)
 	subs := <-subscriptionUpdates
@@ -175,7 +193,7 @@ func TestSubscriptionSource(t *testing.T) {
 func TestFixedSubscriptionSource_WithSubscriptions(t *testing.T) {
 	fss := NewFixedSubscriptionSource(WithFixedSubscriptions(models.EventSubscription{Event: "some.event"}))
 	subchan := make(chan []models.EventSubscription)
-	err := fss.Start(context.TODO(), types.RegistrationData{}, subchan, &sync.WaitGroup{})
+	_, err := fss.Start(context.TODO(), types.RegistrationData{}, subchan, nil)
 	require.NoError(t, err)
 	updates := <-subchan
 	require.Equal(t, 1, len(updates))
@@ -185,23 +203,31 @@ func TestFixedSubscriptionSource_WithSubscriptions(t *testing.T) {
 func TestFixedSubscriptionSourcer_WithNoSubscriptions(t *testing.T) {
 	fss := NewFixedSubscriptionSource()
 	subchan := make(chan []models.EventSubscription)
-	err := fss.Start(context.TODO(), types.RegistrationData{}, subchan, &sync.WaitGroup{})
+	_, err := fss.Start(context.TODO(), types.RegistrationData{}, subchan, nil)
 	require.NoError(t, err)
 	updates := <-subchan
 	require.Equal(t, 0, len(updates))
 }
 
-func TestFixedSubscriptionSource_CallsWaitGroup(t *testing.T) {
+func TestFixedSubscriptionSource_ClosesDoneChannel(t *testing.T) {
 	fss := NewFixedSubscriptionSource()
 	subchan := make(chan []models.EventSubscription)
 
 	ctx, cancel := context.WithCancel(context.TODO())
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-	fss.Start(ctx, types.RegistrationData{}, subchan, wg)
+	done, err := fss.Start(ctx, types.RegistrationData{}, subchan, nil)
+	require.NoError(t, err)
 	<-subchan
 	cancel()
-	wg.Wait()
+	<-done
+}
+
+func TestFixedSubscriptionSource_StopsWithoutReceiver(t *testing.T) {
+	fss := NewFixedSubscriptionSource()
+	ctx, cancel := context.WithCancel(context.TODO())
+	done, err := fss.Start(ctx, types.RegistrationData{}, make(chan []models.EventSubscription), nil)
+	require.NoError(t, err)
+	cancel()
+	<-done
 }
 
 func TestFixedSubscriptionSourcer_Register(t *testing.T) {
@@ -239,3 +265,260 @@ func TestSubscriptionRegistrationFails(t *testing.T) {
 	require.Error(t, err)
 	require.Equal(t, id, "")
 }
+
+func TestSubscriptionRegistrationReturnsEmptyID(t *testing.T) {
+	uniformInterface := &fake.UniformAPIMock{
+		RegisterIntegrationFn: func(i models.Integration) (string, error) {
+			return "", nil
+		},
+	}
+
+	subscriptionSource := New(uniformInterface)
+	id, err := subscriptionSource.Register(models.Integration(types.RegistrationData{}))
+	require.ErrorIs(t, err, ErrEmptyIntegrationID)
+	require.Equal(t, "", id)
+}
+
+func TestSubscriptionRegistrationConflict(t *testing.T) {
+	integration := models.Integration{
+		Name:     "my-service",
+		MetaData: models.MetaData{Hostname: "node-1", KubernetesMetaData: models.KubernetesMetaData{Namespace: "keptn"}},
+	}
+	uniformInterface := &fake.UniformAPIMock{
+		RegisterIntegrationFn: func(i models.Integration) (string, error) {
+			return "", fmt.Errorf("integration already exists")
+		},
+		GetRegistrationsFn: func() ([]*models.Integration, error) {
+			other := integration
+			other.ID, other.Name = "other-id", "other-service"
+			existing := integration
+			existing.ID = "existing-id"
+			return []*models.Integration{&other, &existing}, nil
+		},
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		id, err := New(uniformInterface).Register(integration)
+		require.ErrorIs(t, err, ErrAlreadyRegistered)
+		var alreadyRegistered *AlreadyRegisteredError
+		require.ErrorAs(t, err, &alreadyRegistered)
+		require.Equal(t, "existing-id", alreadyRegistered.IntegrationID)
+		require.Equal(t, "", id)
+	})
+	t.Run("adopt", func(t *testing.T) {
+		id, err := New(uniformInterface, WithConflictPolicy(ConflictPolicyAdopt)).Register(integration)
+		require.NoError(t, err)
+		require.Equal(t, "existing-id", id)
+	})
+	t.Run("existing registration not found", func(t *testing.T) {
+		unknown := integration
+		unknown.Name = "unknown-service"
+		id, err := New(uniformInterface, WithConflictPolicy(ConflictPolicyAdopt)).Register(unknown)
+		require.EqualError(t, err, "integration already exists")
+		require.NotErrorIs(t, err, ErrAlreadyRegistered)
+		require.Equal(t, "", id)
+	})
+}
+
+type statusError struct {
+	code    int
+	message string
+}
+
+func (e statusError) Error() string {
+	return e.message
+}
+
+func (e statusError) StatusCode() int {
+	return e.code
+}
+
+func TestIsConflict(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "no error", err: nil, want: false},
+		{name: "status conflict", err: statusError{code: http.StatusConflict, message: "duplicate integration"}, want: true},
+		{name: "wrapped status conflict", err: fmt.Errorf("could not register: %w", statusError{code: http.StatusConflict}), want: true},
+		{name: "other status mentioning a conflict", err: statusError{code: http.StatusInternalServerError, message: "database conflict"}, want: false},
+		{name: "status code in go-utils message", err: fmt.Errorf("error with status code 409"), want: true},
+		{name: "other status code in go-utils message", err: fmt.Errorf("error with status code 500"), want: false},
+		{name: "message without status code", err: fmt.Errorf("integration already exists"), want: true},
+		{name: "unrelated message", err: fmt.Errorf("connection refused"), want: false},
+		{name: "timeout", err: ErrAPITimeout, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, isConflict(tt.err))
+		})
+	}
+}
+
+func TestSubscriptionRegistrationTimesOut(t *testing.T) {
+	unblock := make(chan struct{})
+	defer close(unblock)
+	uniformInterface := &fake.UniformAPIMock{
+		RegisterIntegrationFn: func(i models.Integration) (string, error) {
+			<-unblock
+			return "some-id", nil
+		},
+	}
+
+	subscriptionSource := New(uniformInterface, WithRegistrationTimeout(10*time.Millisecond))
+	id, err := subscriptionSource.Register(models.Integration(types.RegistrationData{}))
+	require.ErrorIs(t, err, ErrAPITimeout)
+	require.Equal(t, "", id)
+}
+
+func TestSubscriptionSourcePingTimesOut(t *testing.T) {
+	unblock := make(chan struct{})
+	defer close(unblock)
+	uniformInterface := &fake.UniformAPIMock{
+		PingFn: func(id string) (*models.Integration, error) {
+			<-unblock
+			return &models.Integration{}, nil
+		},
+	}
+
+	subscriptionSource := New(uniformInterface, WithRegistrationTimeout(10*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.TODO())
+	errC := make(chan error)
+	done, err := subscriptionSource.Start(ctx, types.RegistrationData{ID: "some-id"}, make(chan []models.EventSubscription), errC)
+	require.NoError(t, err)
+	require.ErrorIs(t, <-errC, ErrAPITimeout)
+	cancel()
+	<-done
+}
+
+func TestSubscriptionSourceStopsWithoutGoroutineLeaks(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+	pinged := make(chan struct{}, 1)
+	uniformInterface := &fake.UniformAPIMock{
+		PingFn: func(id string) (*models.Integration, error) {
+			pinged <- struct{}{}
+			return &models.Integration{Subscriptions: []models.EventSubscription{{ID: "sID", Event: "keptn.event"}}}, nil
+		},
+	}
+	subscriptionSource := New(uniformInterface)
+	subscriptionSource.clock = clock.NewMock()
+
+	// nobody is receiving from the subscription channel, so the source blocks until it is cancelled
+	ctx, cancel := context.WithCancel(context.TODO())
+	done, err := subscriptionSource.Start(ctx, types.RegistrationData{ID: "iID"}, make(chan []models.EventSubscription), nil)
+	require.NoError(t, err)
+	<-pinged
+	cancel()
+	<-done
+}
+
+func TestFixedSubscriptionSourceStopsWithoutGoroutineLeaks(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+	fss := NewFixedSubscriptionSource(WithFixedSubscriptions(models.EventSubscription{Event: "some.event"}))
+	ctx, cancel := context.WithCancel(context.TODO())
+	done, err := fss.Start(ctx, types.RegistrationData{}, make(chan []models.EventSubscription), nil)
+	require.NoError(t, err)
+	cancel()
+	<-done
+}
+
+func TestSubscriptionSourceActiveResources(t *testing.T) {
+	uniformInterface := &fake.UniformAPIMock{
+		PingFn: func(id string) (*models.Integration, error) { return &models.Integration{}, nil },
+	}
+	subscriptionSource := New(uniformInterface)
+	subscriptionSource.clock = clock.NewMock()
+	require.Equal(t, 0, subscriptionSource.ActiveResources())
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	done, err := subscriptionSource.Start(ctx, types.RegistrationData{ID: "iID"}, make(chan []models.EventSubscription), nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, subscriptionSource.ActiveResources())
+	cancel()
+	<-done
+	require.Equal(t, 0, subscriptionSource.ActiveResources())
+}
+
+func TestSubscriptionUnregister(t *testing.T) {
+	var unregistered string
+	uniformInterface := &fake.UniformAPIMock{
+		UnregisterIntegrationFn: func(id string) error {
+			unregistered = id
+			return nil
+		},
+	}
+	require.Nil(t, New(uniformInterface).Unregister("iID"))
+	require.Equal(t, "iID", unregistered)
+}
+
+func TestNewUniformAPIUsesHTTPClient(t *testing.T) {
+	transport := &fake.RoundTripperMock{
+		RoundTripFn: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(`{"id": "iID"}`)),
+				Request:    req,
+			}, nil
+		},
+	}
+	uniformAPI, err := NewUniformAPI("http://keptn.example.com/api", &http.Client{Transport: transport}, api.WithAuthToken("token"))
+	require.Nil(t, err)
+
+	integrationID, err := New(uniformAPI).Register(models.Integration{Name: "my-integration"})
+	require.Nil(t, err)
+	require.Equal(t, "iID", integrationID)
+	requests := transport.Requests()
+	require.Len(t, requests, 1)
+	require.Equal(t, "keptn.example.com", requests[0].URL.Host)
+	require.Equal(t, http.MethodPost, requests[0].Method)
+	require.Equal(t, "token", requests[0].Header.Get("x-token"))
+}
+
+func TestSubscriptionSourceFetch(t *testing.T) {
+	var pings int32
+	uniformInterface := &fake.UniformAPIMock{
+		PingFn: func(id string) (*models.Integration, error) {
+			atomic.AddInt32(&pings, 1)
+			return &models.Integration{ID: id, Subscriptions: []models.EventSubscription{{ID: "sID", Event: "keptn.event"}}}, nil
+		},
+	}
+	subscriptionSource := New(uniformInterface, WithFetchInterval(time.Hour))
+	require.ErrorIs(t, subscriptionSource.Fetch(context.TODO()), ErrNotStarted)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	subscriptionUpdates := make(chan []models.EventSubscription)
+	done, err := subscriptionSource.Start(ctx, types.RegistrationData{ID: "iID"}, subscriptionUpdates, nil)
+	require.NoError(t, err)
+	<-subscriptionUpdates
+
+	fetched := make(chan error)
+	go func() { fetched <- subscriptionSource.Fetch(context.TODO()) }()
+	require.Equal(t, "sID", (<-subscriptionUpdates)[0].ID)
+	require.NoError(t, <-fetched)
+	require.Equal(t, int32(2), atomic.LoadInt32(&pings))
+
+	cancel()
+	<-done
+	require.ErrorIs(t, subscriptionSource.Fetch(context.TODO()), ErrNotStarted)
+}
+
+func TestSubscriptionSourceFetchReturnsPingError(t *testing.T) {
+	var pings int32
+	uniformInterface := &fake.UniformAPIMock{
+		PingFn: func(id string) (*models.Integration, error) {
+			if atomic.AddInt32(&pings, 1) > 1 {
+				return nil, errors.New("control plane unavailable")
+			}
+			return &models.Integration{ID: id}, nil
+		},
+	}
+	subscriptionSource := New(uniformInterface, WithFetchInterval(time.Hour))
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	subscriptionUpdates := make(chan []models.EventSubscription)
+	_, err := subscriptionSource.Start(ctx, types.RegistrationData{ID: "iID"}, subscriptionUpdates, nil)
+	require.NoError(t, err)
+	<-subscriptionUpdates
+	require.ErrorContains(t, subscriptionSource.Fetch(context.TODO()), "control plane unavailable")
+}
@@ -1,11 +1,42 @@
 package types
 
 import (
+	"context"
+	"os"
+	"time"
+
 	"github.com/keptn/go-utils/pkg/api/models"
 )
 
 type RegistrationData models.Integration
 
+// NewRegistrationData creates the RegistrationData of an integration with the given name that subscribes to the
+// given subjects, using one subscription per subject. The hostname of the integration is set to the hostname
+// reported by the operating system
+func NewRegistrationData(name string, subjects ...string) RegistrationData {
+	hostname, _ := os.Hostname()
+	subscriptions := make([]models.EventSubscription, 0, len(subjects))
+	for _, subject := range subjects {
+		subscriptions = append(subscriptions, models.EventSubscription{Event: subject})
+	}
+	return RegistrationData{
+		Name:          name,
+		MetaData:      models.MetaData{Hostname: hostname},
+		Subscriptions: subscriptions,
+	}
+}
+
+// WithFilter returns a copy of the RegistrationData whose subscriptions all use the given filter
+func (r RegistrationData) WithFilter(filter models.EventSubscriptionFilter) RegistrationData {
+	subscriptions := make([]models.EventSubscription, 0, len(r.Subscriptions))
+	for _, subscription := range r.Subscriptions {
+		subscription.Filter = filter
+		subscriptions = append(subscriptions, subscription)
+	}
+	r.Subscriptions = subscriptions
+	return r
+}
+
 type AdditionalSubscriptionData struct {
 	SubscriptionID string `json:"subscriptionID"`
 }
@@ -13,10 +44,62 @@ type AdditionalSubscriptionData struct {
 type EventUpdate struct {
 	KeptnEvent models.KeptnContextExtendedCE
 	MetaData   EventUpdateMetaData
+	// Acknowledger is used to acknowledge the event after it was handled.
+	// It is nil if the event source does not support acknowledging events
+	Acknowledger Acknowledger
+	// Responder is used to return the result of handling the event to the sender of the event.
+	// It is nil if the event source does not wait for the handling result
+	Responder Responder
+	// Batch holds the events of a message containing multiple events, e.g. a batch message delivered by the broker.
+	// If it is not empty, each event of the batch is handled and acknowledged on its own, and the other fields
+	// of the EventUpdate are ignored
+	Batch []EventUpdate
 }
 
+// EventUpdateMetaData holds the transport metadata of a received event.
+// It is available to integrations via the context passed to OnEvent
 type EventUpdateMetaData struct {
+	// Subject is the broker subject the event was received on
 	Subject string
+	// Timestamp is the time the event was received by the event source
+	Timestamp time.Time
+	// Size is the size of the raw payload of the event in bytes.
+	// It is 0 if the event source does not provide this information
+	Size int
+	// DeliveryCount is the number of times the event was delivered by the broker, including the current delivery.
+	// It is 0 if the event source does not provide this information
+	DeliveryCount uint64
+}
+
+// Acknowledger can be provided by event sources whose broker supports acknowledging received events
+type Acknowledger interface {
+	// Ack tells the broker that the event was handled
+	Ack() error
+	// Nack tells the broker that handling the event failed and the event should be redelivered after the given delay
+	Nack(delay time.Duration) error
+}
+
+// Releaser can be implemented by Acknowledgers that hold a resource of the event source until the event is
+// acknowledged, e.g. a prefetch slot. Release frees the resource without acknowledging the event or requesting its
+// redelivery, e.g. if the event is left unacknowledged for the broker to redeliver it
+type Releaser interface {
+	Release()
+}
+
+// Responder can be provided by event sources of request/response transports, e.g. HTTP, whose sender waits for the
+// result of handling the event instead of just delivering it
+type Responder interface {
+	// Respond is called once with the result of handling the event, including events that are not forwarded
+	// to the integration
+	Respond(HandlingResult)
+}
+
+// HandlingResult is the result of handling a received event
+type HandlingResult struct {
+	// Events holds the events sent while handling the event, e.g. the .started and .finished events of the integration
+	Events []models.KeptnContextExtendedCE
+	// Err is the error of the handling, nil if the event was handled successfully or was not forwarded
+	Err error
 }
 
 type EventSenderKeyType struct{}
@@ -24,3 +107,45 @@ type EventSenderKeyType struct{}
 var EventSenderKey = EventSenderKeyType{}
 
 type EventSender func(ce models.KeptnContextExtendedCE) error
+
+// SenderFromContext returns the sender stored under EventSenderKey in the given context, e.g. the sender the
+// ControlPlane passes to the integration for every event. It returns false if the context holds no sender
+func SenderFromContext(ctx context.Context) (EventSender, bool) {
+	switch sender := ctx.Value(EventSenderKey).(type) {
+	case EventSender:
+		return sender, sender != nil
+	case func(ce models.KeptnContextExtendedCE) error:
+		return sender, sender != nil
+	default:
+		return nil, false
+	}
+}
+
+// ResourceReporter can be implemented by event and subscription sources to report
+// the number of resources (e.g. broker subscriptions or goroutines) they currently own
+type ResourceReporter interface {
+	ActiveResources() int
+}
+
+// OffsetReporter can be implemented by event sources whose broker assigns increasing offsets to the events of a
+// stream, e.g. sequence numbers, to report how far the event source lags behind the stream
+type OffsetReporter interface {
+	// Offsets returns the offset of the latest event received by the event source and the latest offset of the stream
+	Offsets() (current int64, latest int64, err error)
+}
+
+type EventMetaDataKeyType struct{}
+
+var EventMetaDataKey = EventMetaDataKeyType{}
+
+type CorrelationIDKeyType struct{}
+
+var CorrelationIDKey = CorrelationIDKeyType{}
+
+// ConnectionState describes the state of the connection between an event source and the message broker
+type ConnectionState string
+
+const (
+	ConnectionStateDisconnected ConnectionState = "disconnected"
+	ConnectionStateConnected    ConnectionState = "connected"
+)
@@ -0,0 +1,76 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/keptn/go-utils/pkg/api/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRegistrationData(t *testing.T) {
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+
+	data := NewRegistrationData("my-service", "sh.keptn.event.deployment.triggered", "sh.keptn.event.test.triggered")
+	require.Equal(t, RegistrationData{
+		Name:     "my-service",
+		MetaData: models.MetaData{Hostname: hostname},
+		Subscriptions: []models.EventSubscription{
+			{Event: "sh.keptn.event.deployment.triggered"},
+			{Event: "sh.keptn.event.test.triggered"},
+		},
+	}, data)
+}
+
+func TestNewRegistrationDataWithoutSubjects(t *testing.T) {
+	data := NewRegistrationData("my-service")
+	require.Equal(t, "my-service", data.Name)
+	require.Empty(t, data.Subscriptions)
+}
+
+func TestRegistrationDataWithFilter(t *testing.T) {
+	filter := models.EventSubscriptionFilter{Projects: []string{"podtato"}, Stages: []string{"dev"}}
+	data := NewRegistrationData("my-service", "sh.keptn.event.deployment.triggered", "sh.keptn.event.test.triggered")
+
+	filtered := data.WithFilter(filter)
+	require.Equal(t, []models.EventSubscription{
+		{Event: "sh.keptn.event.deployment.triggered", Filter: filter},
+		{Event: "sh.keptn.event.test.triggered", Filter: filter},
+	}, filtered.Subscriptions)
+	require.Empty(t, data.Subscriptions[0].Filter.Projects, "the original registration data must not be changed")
+}
+
+func TestSenderFromContext(t *testing.T) {
+	errSent := errors.New("sent")
+	var sender EventSender = func(ce models.KeptnContextExtendedCE) error { return errSent }
+
+	got, ok := SenderFromContext(context.WithValue(context.TODO(), EventSenderKey, sender))
+	require.True(t, ok)
+	require.ErrorIs(t, got(models.KeptnContextExtendedCE{}), errSent)
+
+	plain := func(ce models.KeptnContextExtendedCE) error { return errSent }
+	got, ok = SenderFromContext(context.WithValue(context.TODO(), EventSenderKey, plain))
+	require.True(t, ok)
+	require.ErrorIs(t, got(models.KeptnContextExtendedCE{}), errSent)
+}
+
+func TestSenderFromContextAbsent(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{name: "no sender", ctx: context.TODO()},
+		{name: "nil sender", ctx: context.WithValue(context.TODO(), EventSenderKey, EventSender(nil))},
+		{name: "other type", ctx: context.WithValue(context.TODO(), EventSenderKey, "not a sender")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender, ok := SenderFromContext(tt.ctx)
+			require.False(t, ok)
+			require.Nil(t, sender)
+		})
+	}
+}